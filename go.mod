@@ -4,9 +4,15 @@ go 1.24.2
 
 require (
 	github.com/andrejsstepanovs/go-litellm v1.2.7
+	github.com/bogem/id3v2/v2 v2.1.4
+	github.com/braheezy/shine-mp3 v0.1.0
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/gosimple/slug v1.15.0
+	github.com/hajimehoshi/go-mp3 v0.3.4
 	github.com/hyacinthus/mp3join v0.0.0-20190710105654-d46eaeeb9552
 	github.com/spf13/cobra v1.10.1
 	github.com/spf13/viper v1.19.0
+	golang.org/x/image v0.25.0
 )
 
 require (
@@ -17,6 +23,7 @@ require (
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-playground/validator/v10 v10.27.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
+	github.com/gosimple/unidecode v1.0.1 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
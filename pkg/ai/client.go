@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/url"
 	"strings"
@@ -11,12 +12,15 @@ import (
 	"github.com/andrejsstepanovs/go-litellm/conf/connections/litellm"
 	"github.com/andrejsstepanovs/go-litellm/models"
 	"github.com/andrejsstepanovs/go-litellm/request"
+	"github.com/andrejsstepanovs/storygen/pkg/story"
 	"github.com/spf13/viper"
 )
 
 type AI struct {
 	client   *client.Litellm
 	ctx      context.Context
+	host     url.URL
+	apiKey   string
 	audience string
 	model    string
 }
@@ -96,18 +100,26 @@ func NewAI(audience string) (*AI, error) {
 	return &AI{
 		client:   litellmClient,
 		ctx:      context.Background(),
+		host:     *baseURL,
+		apiKey:   apiKey,
 		audience: audience,
 		model:    model,
 	}, nil
 }
 
-// TextToSpeech converts text to speech using the configured TTS model
-// Returns the path to the generated audio file
-func (a *AI) TextToSpeech(text, voice, instructions string, speed float64) (string, error) {
+// TTSModel returns the configured TTS model.
+func TTSModel() string {
 	ttsModel := viper.GetString("STORYGEN_TTS_MODEL")
 	if ttsModel == "" {
 		ttsModel = "tts-openai"
 	}
+	return ttsModel
+}
+
+// TextToSpeech converts text to speech using the configured TTS model
+// Returns the path to the generated audio file
+func (a *AI) TextToSpeech(text, voice, instructions string, speed float64) (string, error) {
+	ttsModel := TTSModel()
 
 	speechRequest := request.Speech{
 		Model: models.ModelID(ttsModel),
@@ -115,11 +127,15 @@ func (a *AI) TextToSpeech(text, voice, instructions string, speed float64) (stri
 		Voice: voice,
 	}
 
-	if strings.Contains(ttsModel, "openai") {
+	if TTSSupportsInstructions(ttsModel) {
 		speechRequest.Instructions = instructions
 		speechRequest.Speed = speed
 		speechRequest.ResponseFormat = "mp3"
 	}
+	// Lossless chunks avoid encoding the story twice, the returned format is detected either way
+	if format := viper.GetString("STORYGEN_TTS_RESPONSE_FORMAT"); format != "" {
+		speechRequest.ResponseFormat = strings.ToLower(format)
+	}
 
 	resp, err := a.client.TextToSpeech(a.ctx, speechRequest)
 	if err != nil {
@@ -128,3 +144,38 @@ func (a *AI) TextToSpeech(text, voice, instructions string, speed float64) (stri
 
 	return resp.Full, nil
 }
+
+// TTSSupportsInstructions reports whether the TTS model takes voice instructions and speed.
+func TTSSupportsInstructions(ttsModel string) bool {
+	return strings.Contains(ttsModel, "openai")
+}
+
+// SpeechToText transcribes an audio file using the configured transcription model
+func (a *AI) SpeechToText(file string) (story.Transcript, error) {
+	sttModel := viper.GetString("STORYGEN_STT_MODEL")
+	if sttModel == "" {
+		sttModel = "whisper-1"
+	}
+
+	model, err := a.client.Model(a.ctx, models.ModelID(sttModel))
+	if err != nil {
+		return story.Transcript{}, fmt.Errorf("failed to get model: %w", err)
+	}
+
+	// The client asks for plain text only, subtitles need the word timestamps
+	resp, err := transcribe(a.ctx, a.host.JoinPath("audio", "transcriptions").String(), a.apiKey, string(model.ModelId), file)
+	if err != nil {
+		return story.Transcript{}, err
+	}
+
+	transcript := story.Transcript{Text: resp.Text}
+	for _, w := range resp.Words {
+		transcript.Words = append(transcript.Words, story.TranscriptWord{
+			Word:  w.Word,
+			Start: float64(w.Start),
+			End:   float64(w.End),
+		})
+	}
+
+	return transcript, nil
+}
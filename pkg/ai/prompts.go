@@ -1094,6 +1094,94 @@ func (a *AI) TranslateText(englishText, toLanguage string) string {
 	return templateResponse
 }
 
+// AnnotateChunks asks how each chunk of a chapter should be performed.
+// Unlike story building, failures are returned so narration can fall back to the global voice settings.
+func (a *AI) AnnotateChunks(chapter string, chunks []string) ([]story.Mood, error) {
+	systemPrompt := "You are a voice director preparing a story book for an audio book narrator."
+
+	numbered := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		numbered = append(numbered, fmt.Sprintf("## Chunk %d\n%s", i+1, chunk))
+	}
+
+	userPrompt := fmt.Sprintf("This is a chapter of a %s story:\n```\n%s\n```\n\n"+
+		"The narrator will read it in these %d chunks:\n%s\n\n"+
+		"For every chunk pick how it should be performed:\n"+
+		"- `mood`: one or two words, e.g. tense, cheerful, mysterious, sad, sleepy, triumphant\n"+
+		"- `intensity`: 1 (calm) to 5 (most intense)\n"+
+		"- `pacing`: slow, steady or fast\n"+
+		"Follow what happens in the chunk, a chase scene is fast and intense, a bedtime ending is slow and calm.\n"+
+		"%s %s\n"+
+		"Answer with a JSON array with exactly %d objects, one per chunk in the same order.\n"+
+		"Example: [{\"mood\": \"mysterious\", \"intensity\": 2, \"pacing\": \"slow\"}]",
+		a.audience,
+		chapter,
+		len(chunks),
+		strings.Join(numbered, "\n\n"),
+		GeneralInstruction, ForceJson,
+		len(chunks))
+
+	schema := request.JSONSchema{
+		Name: "chunk_moods",
+		Schema: map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"mood":      map[string]interface{}{"type": "string"},
+					"intensity": map[string]interface{}{"type": "integer"},
+					"pacing":    map[string]interface{}{"type": "string"},
+				},
+				"required":             []string{"mood", "intensity", "pacing"},
+				"additionalProperties": false,
+			},
+			"description": "Performance of every chunk, in chunk order",
+		},
+		Strict: true,
+	}
+
+	model, err := a.client.Model(a.ctx, models.ModelID(a.model))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model: %w", err)
+	}
+
+	messages := request.Messages{
+		request.SystemMessageSimple(systemPrompt),
+		request.UserMessageSimple(userPrompt),
+	}
+
+	req := request.NewCompletionRequest(model, messages, nil, nil, 0.7)
+	req.SetJSONSchema(schema)
+
+	resp, err := a.client.Completion(a.ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("completion failed: %w", err)
+	}
+
+	respStr := cleanResponse(resp.String())
+	startIdx := strings.Index(respStr, "[")
+	if startIdx != -1 {
+		endIdx := strings.LastIndex(respStr, "]")
+		if endIdx != -1 && endIdx > startIdx {
+			respStr = respStr[startIdx : endIdx+1]
+		}
+	}
+
+	var moods []story.Mood
+	err = json.Unmarshal([]byte(respStr), &moods)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chunk moods: %w", err)
+	}
+	if len(moods) != len(chunks) {
+		return nil, fmt.Errorf("got %d chunk moods for %d chunks", len(moods), len(chunks))
+	}
+	for i := range moods {
+		moods[i].Intensity = min(max(moods[i].Intensity, 1), 5)
+	}
+
+	return moods, nil
+}
+
 func cleanResponse(response string) string {
 	response = removeThinking(response)
 	response = strings.Replace(response, "\u201c", "\"", -1)
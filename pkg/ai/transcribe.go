@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/andrejsstepanovs/go-litellm/audio"
+)
+
+// transcribe posts the audio file to an OpenAI compatible transcription endpoint and asks for
+// the verbose response with word timestamps.
+func transcribe(ctx context.Context, endpoint, apiKey, model, file string) (audio.AudioResponse, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to open %q: %w", file, err)
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"model", model},
+		{"response_format", "verbose_json"},
+		{"timestamp_granularities[]", "word"},
+		{"timestamp_granularities[]", "segment"},
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return audio.AudioResponse{}, fmt.Errorf("failed to write %s field: %w", field[0], err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", filepath.Base(file))
+	if err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to copy %q: %w", file, err)
+	}
+	if err := writer.Close(); err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to close form: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return audio.AudioResponse{}, fmt.Errorf("transcription failed with %s: %s", resp.Status, msg)
+	}
+
+	var transcription audio.AudioResponse
+	if err := json.Unmarshal(msg, &transcription); err != nil {
+		return audio.AudioResponse{}, fmt.Errorf("failed to parse transcription: %w", err)
+	}
+	return transcription, nil
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTranscribeAsksForWordTimestamps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		if got := r.FormValue("model"); got != "whisper-1" {
+			t.Errorf("model = %q, want whisper-1", got)
+		}
+		if got := r.FormValue("response_format"); got != "verbose_json" {
+			t.Errorf("response_format = %q, want verbose_json", got)
+		}
+		if got := r.MultipartForm.Value["timestamp_granularities[]"]; !reflect.DeepEqual(got, []string{"word", "segment"}) {
+			t.Errorf("timestamp_granularities[] = %q, want word and segment", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if _, header, err := r.FormFile("file"); err != nil || header.Filename != "chunk.mp3" {
+			t.Errorf("file = %v, %v", header, err)
+		}
+		_, _ = w.Write([]byte(`{"text":"Once upon","words":[{"word":"Once","start":0.1,"end":0.4},{"word":"upon","start":0.5,"end":0.8}]}`))
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "chunk.mp3")
+	if err := os.WriteFile(file, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := transcribe(context.Background(), server.URL, "sk-test", "whisper-1", file)
+	if err != nil {
+		t.Fatalf("transcribe() error = %v", err)
+	}
+	if resp.Text != "Once upon" || len(resp.Words) != 2 || resp.Words[1].Word != "upon" || resp.Words[1].Start != 0.5 {
+		t.Errorf("transcribe() = %+v", resp)
+	}
+}
+
+func TestTranscribeReturnsServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown model", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "chunk.mp3")
+	if err := os.WriteFile(file, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := transcribe(context.Background(), server.URL, "sk-test", "whisper-1", file); err == nil {
+		t.Error("transcribe() error = nil, want the server error")
+	}
+}
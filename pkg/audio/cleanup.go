@@ -0,0 +1,118 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+const analysisWindow = 10 * time.Millisecond
+
+// windowPeaks returns the peak level in dBFS of each analysis window.
+func windowPeaks(p *PCM, window time.Duration) ([]float64, int) {
+	size := max(1, DurationToFrames(window, p.SampleRate))
+	count := (p.Frames() + size - 1) / size
+	peaks := make([]float64, count)
+	for w := 0; w < count; w++ {
+		start := w * size * p.Channels
+		end := min(start+size*p.Channels, len(p.Samples))
+		peak := 0.0
+		for _, s := range p.Samples[start:end] {
+			peak = math.Max(peak, math.Abs(s))
+		}
+		peaks[w] = LinearToDB(peak)
+	}
+	return peaks, size
+}
+
+// TrimSilences shortens every run of audio quieter than thresholdDB that lasts longer than
+// maxSilence down to keep, keep longer than maxSilence is cut to maxSilence.
+// Returns the trimmed audio and how much was removed.
+func TrimSilences(p *PCM, thresholdDB float64, maxSilence, keep time.Duration) (*PCM, time.Duration) {
+	peaks, size := windowPeaks(p, analysisWindow)
+	maxWindows := DurationToFrames(maxSilence, p.SampleRate) / size
+	keepFrames := min(DurationToFrames(keep, p.SampleRate), DurationToFrames(maxSilence, p.SampleRate))
+
+	out := New(p.SampleRate, p.Channels)
+	out.Samples = make([]float64, 0, len(p.Samples))
+	removed := 0
+
+	w := 0
+	for w < len(peaks) {
+		if peaks[w] >= thresholdDB {
+			out.Samples = append(out.Samples, p.frameRange(w*size, (w+1)*size)...)
+			w++
+			continue
+		}
+
+		runStart := w
+		for w < len(peaks) && peaks[w] < thresholdDB {
+			w++
+		}
+		startFrame, endFrame := runStart*size, min(w*size, p.Frames())
+
+		if w-runStart <= maxWindows {
+			out.Samples = append(out.Samples, p.frameRange(startFrame, endFrame)...)
+			continue
+		}
+
+		// Keep half of the allowed silence on each side of the cut so speech is not clipped.
+		half := keepFrames / 2
+		out.Samples = append(out.Samples, p.frameRange(startFrame, startFrame+half)...)
+		out.Samples = append(out.Samples, p.frameRange(endFrame-(keepFrames-half), endFrame)...)
+		removed += endFrame - startFrame - keepFrames
+	}
+
+	return out, FramesToDuration(removed, p.SampleRate)
+}
+
+// SuppressSpikes attenuates sudden loud bursts. Windows peaking above kneeDB are pulled down
+// progressively and anything reaching thresholdDB is muted, similar to the ffmpeg compand
+// curve -80/-80|-6/-6|-2/-80. Returns how many windows were attenuated.
+func SuppressSpikes(p *PCM, kneeDB, thresholdDB float64) int {
+	const floorDB = -80.0
+
+	peaks, size := windowPeaks(p, 5*time.Millisecond)
+	gains := make([]float64, len(peaks))
+	spikes := 0
+	for w, peak := range peaks {
+		if peak <= kneeDB {
+			gains[w] = 1
+			continue
+		}
+		spikes++
+		outDB := floorDB
+		if peak < thresholdDB {
+			outDB = kneeDB + (peak-kneeDB)*(floorDB-kneeDB)/(thresholdDB-kneeDB)
+		}
+		gains[w] = DBToLinear(outDB - peak)
+	}
+	if spikes == 0 {
+		return 0
+	}
+
+	// Spread each reduction over its neighbours and interpolate per sample to avoid new clicks.
+	smoothed := make([]float64, len(gains))
+	for w := range gains {
+		smoothed[w] = gains[w]
+		if w > 0 {
+			smoothed[w] = math.Min(smoothed[w], gains[w-1])
+		}
+		if w < len(gains)-1 {
+			smoothed[w] = math.Min(smoothed[w], gains[w+1])
+		}
+	}
+
+	for frame := 0; frame < p.Frames(); frame++ {
+		pos := float64(frame)/float64(size) - 0.5
+		w := int(math.Floor(pos))
+		frac := pos - float64(w)
+		a := smoothed[max(0, min(w, len(smoothed)-1))]
+		b := smoothed[max(0, min(w+1, len(smoothed)-1))]
+		gain := a + (b-a)*frac
+		for c := 0; c < p.Channels; c++ {
+			p.Samples[frame*p.Channels+c] *= gain
+		}
+	}
+
+	return spikes
+}
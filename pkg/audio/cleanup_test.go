@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTrimSilencesKeepLongerThanMaxSilence(t *testing.T) {
+	const rate = 24000
+	p := New(rate, 1)
+	tone := func(d time.Duration) {
+		for i := 0; i < DurationToFrames(d, rate); i++ {
+			p.Samples = append(p.Samples, 0.5*math.Sin(2*math.Pi*440*float64(i)/rate))
+		}
+	}
+	tone(time.Second)
+	p.Samples = append(p.Samples, make([]float64, DurationToFrames(600*time.Millisecond, rate))...)
+	tone(time.Second)
+
+	out, removed := TrimSilences(p, -60, 500*time.Millisecond, 700*time.Millisecond)
+	if removed < 0 {
+		t.Errorf("removed %v, want not negative", removed)
+	}
+	if out.Duration() > p.Duration() {
+		t.Errorf("trimmed audio is %v, longer than the %v input", out.Duration(), p.Duration())
+	}
+	if got, want := p.Duration()-out.Duration(), removed; got != want {
+		t.Errorf("audio got %v shorter, removed reports %v", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// decodeFFmpeg converts formats without a Go decoder (Opus, AAC, FLAC) to WAV through ffmpeg.
+func decodeFFmpeg(file string) (*PCM, error) {
+	cmd := exec.Command("ffmpeg", "-v", "error", "-i", file, "-f", "wav", "-c:a", "pcm_s16le", "-")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to decode %q with ffmpeg (is it installed?): %v\nOutput: %s", file, err, stderr.String())
+	}
+	return DecodeWAV(&stdout)
+}
+
+// encodeFFmpeg pipes p as WAV into ffmpeg, args select the codec and container.
+func encodeFFmpeg(file string, p *PCM, args ...string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	var wav bytes.Buffer
+	if err := EncodeWAV(&wav, p); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("ffmpeg", append(append([]string{"-v", "error", "-y", "-f", "wav", "-i", "-"}, args...), file)...)
+	var stderr bytes.Buffer
+	cmd.Stdin, cmd.Stderr = &wav, &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to encode %q with ffmpeg (is it installed?): %v\nOutput: %s", file, err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is an audio container and encoding.
+type Format string
+
+const (
+	FormatMP3  Format = "mp3"
+	FormatWAV  Format = "wav"
+	FormatOpus Format = "opus" // Opus in an Ogg container
+	FormatAAC  Format = "aac"  // ADTS stream or MP4/M4A container
+	FormatFLAC Format = "flac"
+	FormatPCM  Format = "pcm" // Headerless 16-bit little-endian mono at RawSampleRate
+)
+
+// RawSampleRate is the sample rate TTS providers use for headerless PCM.
+const RawSampleRate = 24000
+
+// Extension returns the file extension with the leading dot.
+func (f Format) Extension() string {
+	if f == FormatAAC {
+		return ".m4a"
+	}
+	return "." + string(f)
+}
+
+// MimeType returns the media type browsers play the format as.
+func (f Format) MimeType() string {
+	switch f {
+	case FormatMP3:
+		return "audio/mpeg"
+	case FormatWAV:
+		return "audio/wav"
+	case FormatOpus:
+		return "audio/ogg"
+	case FormatAAC:
+		return "audio/mp4"
+	case FormatFLAC:
+		return "audio/flac"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// ParseFormat returns the output format for a name like "mp3", "ogg" or "wav".
+func ParseFormat(name string) (Format, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), ".") {
+	case "", "mp3":
+		return FormatMP3, nil
+	case "wav", "wave":
+		return FormatWAV, nil
+	case "opus", "ogg":
+		return FormatOpus, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q, use mp3, opus or wav", name)
+	}
+}
+
+// DetectFormat recognizes the format from the first bytes of a file. MP3 without an ID3 tag
+// is only recognized when its first frame header is valid and the next frame follows it,
+// headerless PCM can start with the same sync bits.
+func DetectFormat(header []byte) (Format, bool) {
+	switch {
+	case len(header) >= 12 && bytes.Equal(header[:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return FormatWAV, true
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return FormatOpus, true
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return FormatFLAC, true
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return FormatAAC, true
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return FormatMP3, true
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xF6 == 0xF0:
+		// ADTS sync word with layer 0, MP3 frames have a non-zero layer
+		return FormatAAC, true
+	}
+	if size, ok := mp3FrameSize(header); ok {
+		// A file holding a single frame ends where the next one would start
+		if len(header) == size {
+			return FormatMP3, true
+		}
+		if _, ok := mp3FrameSize(header[min(size, len(header)):]); ok {
+			return FormatMP3, true
+		}
+	}
+	return "", false
+}
+
+// detectLength is how much of a file DetectFile reads, enough for the largest MP3 frame and the next header.
+const detectLength = 4096
+
+// mp3Bitrates are the bitrates in kbit/s by bitrate index, for MPEG-1 layers I, II, III and MPEG-2 and 2.5 layers I, II and III.
+var mp3Bitrates = [5][15]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// mp3SampleRates are the sample rates by sample rate index for MPEG-1, 2 and 2.5.
+var mp3SampleRates = [3][3]int{{44100, 48000, 32000}, {22050, 24000, 16000}, {11025, 12000, 8000}}
+
+// mp3FrameSize returns the length in bytes of the MP3 frame starting at header, when its header is valid.
+// Free format frames are rejected, their length cannot be told from the header.
+func mp3FrameSize(header []byte) (int, bool) {
+	if len(header) < 4 || header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return 0, false
+	}
+	version := (header[1] >> 3) & 0x03    // 0 MPEG-2.5, 1 reserved, 2 MPEG-2, 3 MPEG-1
+	layer := 4 - int((header[1]>>1)&0x03) // 1, 2 or 3, 4 is reserved
+	bitrateIndex := int(header[2] >> 4)
+	sampleRateIndex := int((header[2] >> 2) & 0x03)
+	padding := int((header[2] >> 1) & 0x01)
+	if version == 1 || layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return 0, false
+	}
+
+	mpeg1 := version == 3
+	table := 3 + min(layer-1, 1)
+	rates := mp3SampleRates[1]
+	if mpeg1 {
+		table, rates = layer-1, mp3SampleRates[0]
+	} else if version == 0 {
+		rates = mp3SampleRates[2]
+	}
+	bitrate := mp3Bitrates[table][bitrateIndex] * 1000
+	sampleRate := rates[sampleRateIndex]
+
+	switch {
+	case layer == 1:
+		return (12*bitrate/sampleRate + padding) * 4, true
+	case layer == 3 && !mpeg1:
+		return 72*bitrate/sampleRate + padding, true
+	default:
+		return 144*bitrate/sampleRate + padding, true
+	}
+}
+
+// DetectFile recognizes the format of file. Headerless PCM is told by a .pcm or .raw extension,
+// which TTS responses get from the requested format, every other format by its content.
+func DetectFile(file string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".pcm", ".raw":
+		return FormatPCM, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, detectLength)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read %q: %w", file, err)
+	}
+	if format, ok := DetectFormat(header[:n]); ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown audio format of %q", file)
+}
+
+// DecodeFile decodes an audio file of any detected format. MP3, WAV and PCM are decoded natively,
+// other formats need ffmpeg.
+func DecodeFile(file string) (*PCM, error) {
+	format, err := DetectFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatMP3:
+		return DecodeMP3File(file)
+	case FormatWAV:
+		return DecodeWAVFile(file)
+	case FormatPCM:
+		return DecodeRawFile(file, RawSampleRate, 1)
+	default:
+		return decodeFFmpeg(file)
+	}
+}
+
+// EncodeFile encodes p into file. MP3 and WAV are encoded natively, Opus needs ffmpeg.
+func EncodeFile(file string, p *PCM, format Format) error {
+	switch format {
+	case FormatMP3:
+		return EncodeMP3File(file, p)
+	case FormatWAV:
+		return EncodeWAVFile(file, p)
+	case FormatOpus:
+		return encodeFFmpeg(file, p, "-c:a", "libopus", "-b:a", "64k", "-f", "ogg")
+	default:
+		return fmt.Errorf("cannot encode %s audio", format)
+	}
+}
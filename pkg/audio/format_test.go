@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tone := New(24000, 1)
+	for i := 0; i < 24000; i++ {
+		tone.Samples = append(tone.Samples, 0.5*math.Sin(2*math.Pi*440*float64(i)/24000))
+	}
+	mp3 := &bytes.Buffer{}
+	if err := EncodeMP3(mp3, tone); err != nil {
+		t.Fatal(err)
+	}
+	// Headerless PCM whose first sample is -1 starts with the MP3 sync bits
+	pcm := append([]byte{0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFF, 0x10, 0x00}, make([]byte, 2000)...)
+	pcmValidHeader := append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 2000)...)
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   Format
+		ok     bool
+	}{
+		{name: "wav", header: []byte("RIFF\x24\x00\x00\x00WAVEfmt "), want: FormatWAV, ok: true},
+		{name: "ogg", header: []byte("OggS\x00\x02\x00\x00"), want: FormatOpus, ok: true},
+		{name: "flac", header: []byte("fLaC\x00\x00\x00\x22"), want: FormatFLAC, ok: true},
+		{name: "mp4", header: []byte("\x00\x00\x00\x20ftypM4A "), want: FormatAAC, ok: true},
+		{name: "adts", header: []byte{0xFF, 0xF1, 0x50, 0x80, 0x02, 0x1F, 0xFC}, want: FormatAAC, ok: true},
+		{name: "id3", header: []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), want: FormatMP3, ok: true},
+		{name: "bare mp3", header: mp3.Bytes()[:min(detectLength, mp3.Len())], want: FormatMP3, ok: true},
+		{name: "pcm starting with 0xFFFF", header: pcm},
+		{name: "pcm with a valid frame header but no next frame", header: pcmValidHeader},
+		{name: "too short", header: []byte{0xFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DetectFormat(tt.header)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("DetectFormat() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDetectFileTrustsPCMExtension(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"chunk.pcm", "chunk.raw"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte{0xFF, 0xFF, 0x00, 0x00, 0xFF, 0xFB, 0x90, 0x00}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := DetectFile(file)
+		if err != nil || got != FormatPCM {
+			t.Errorf("DetectFile(%s) = %q, %v, want %q", name, got, err, FormatPCM)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Loudness is an EBU R128 measurement.
+type Loudness struct {
+	Integrated float64 // LUFS
+	TruePeak   float64 // dBTP
+}
+
+const (
+	absoluteGate = -70.0
+	relativeGate = -10.0
+	silentLUFS   = -100.0
+)
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the two BS.1770 pre-filters (high shelf and RLB high-pass) for a sample rate.
+func kWeighting(sampleRate int) (biquad, biquad) {
+	rate := float64(sampleRate)
+
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highPass
+}
+
+// Measure returns the integrated loudness and true peak of p.
+func Measure(p *PCM) Loudness {
+	return Loudness{
+		Integrated: IntegratedLoudness(p),
+		TruePeak:   TruePeak(p),
+	}
+}
+
+// IntegratedLoudness implements the gated ITU-R BS.1770-4 / EBU R128 integrated loudness.
+func IntegratedLoudness(p *PCM) float64 {
+	frames := p.Frames()
+	if frames == 0 || p.SampleRate == 0 {
+		return silentLUFS
+	}
+
+	// K-weighted energy per frame, summed over channels (all channel weights are 1 for mono/stereo).
+	energy := make([]float64, frames)
+	for c := 0; c < p.Channels; c++ {
+		shelf, highPass := kWeighting(p.SampleRate)
+		for i := 0; i < frames; i++ {
+			y := highPass.process(shelf.process(p.Samples[i*p.Channels+c]))
+			energy[i] += y * y
+		}
+	}
+
+	// 400 ms blocks with 75% overlap
+	blockSize := DurationToFrames(400*time.Millisecond, p.SampleRate)
+	step := blockSize / 4
+	if frames < blockSize {
+		blockSize, step = frames, frames
+	}
+
+	prefix := make([]float64, frames+1)
+	for i, e := range energy {
+		prefix[i+1] = prefix[i] + e
+	}
+
+	blocks := make([]float64, 0, frames/step+1)
+	for start := 0; start+blockSize <= frames; start += step {
+		blocks = append(blocks, (prefix[start+blockSize]-prefix[start])/float64(blockSize))
+	}
+
+	gated := gateBlocks(blocks, absoluteGate)
+	if len(gated) == 0 {
+		return silentLUFS
+	}
+	relative := blockLoudness(mean(gated)) + relativeGate
+	gated = gateBlocks(gated, relative)
+	if len(gated) == 0 {
+		return silentLUFS
+	}
+
+	return blockLoudness(mean(gated))
+}
+
+func blockLoudness(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return silentLUFS
+	}
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+func gateBlocks(blocks []float64, gate float64) []float64 {
+	kept := make([]float64, 0, len(blocks))
+	for _, b := range blocks {
+		if blockLoudness(b) > gate {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// truePeakTaps is the windowed-sinc interpolation filter used for 4x oversampling, one row per phase.
+var truePeakTaps = func() [4][12]float64 {
+	var taps [4][12]float64
+	for phase := 0; phase < 4; phase++ {
+		for n := 0; n < 12; n++ {
+			x := float64(n-6) + float64(phase)/4
+			sinc := 1.0
+			if x != 0 {
+				sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+			}
+			window := 0.5 * (1 + math.Cos(math.Pi*x/6.5))
+			taps[phase][n] = sinc * window
+		}
+	}
+	return taps
+}()
+
+// TruePeak estimates the inter-sample peak in dBTP using 4x oversampling.
+func TruePeak(p *PCM) float64 {
+	frames := p.Frames()
+	peak := 0.0
+	for c := 0; c < p.Channels; c++ {
+		for i := 0; i < frames; i++ {
+			peak = math.Max(peak, math.Abs(p.Samples[i*p.Channels+c]))
+			for phase := 1; phase < 4; phase++ {
+				v := 0.0
+				for n, tap := range truePeakTaps[phase] {
+					j := i + 6 - n
+					if j >= 0 && j < frames {
+						v += tap * p.Samples[j*p.Channels+c]
+					}
+				}
+				peak = math.Max(peak, math.Abs(v))
+			}
+		}
+	}
+	return LinearToDB(peak)
+}
+
+// Normalize applies gain so p reaches targetLUFS, then limits peaks to peakLimit dBTP.
+// Silent audio is left untouched. Returns the loudness after processing.
+func Normalize(p *PCM, targetLUFS, peakLimit float64) Loudness {
+	integrated := IntegratedLoudness(p)
+	if integrated <= silentLUFS {
+		return Measure(p)
+	}
+
+	p.Gain(targetLUFS - integrated)
+	Limit(p, peakLimit)
+
+	measured := Measure(p)
+	if measured.TruePeak > peakLimit {
+		// The limiter works on sample peaks, pull down whatever inter-sample overshoot is left.
+		p.Gain(peakLimit - measured.TruePeak)
+		measured = Measure(p)
+	}
+	return measured
+}
+
+// Limit is a look-ahead peak limiter keeping samples below ceilingDB.
+func Limit(p *PCM, ceilingDB float64) {
+	ceiling := DBToLinear(ceilingDB)
+	frames := p.Frames()
+	lookahead := max(1, DurationToFrames(5*time.Millisecond, p.SampleRate))
+	release := math.Exp(-1 / (0.05 * float64(p.SampleRate)))
+
+	// Gain needed per frame so that the loudest channel stays under the ceiling.
+	required := make([]float64, frames)
+	for i := 0; i < frames; i++ {
+		peak := 0.0
+		for c := 0; c < p.Channels; c++ {
+			peak = math.Max(peak, math.Abs(p.Samples[i*p.Channels+c]))
+		}
+		required[i] = 1
+		if peak > ceiling {
+			required[i] = ceiling / peak
+		}
+	}
+
+	// Ramp down ahead of each peak so the gain change is not a click.
+	for i := frames - 1; i >= 0; i-- {
+		if required[i] >= 1 {
+			continue
+		}
+		for j := max(0, i-lookahead); j < i; j++ {
+			ramp := 1 - (1-required[i])*float64(j-(i-lookahead))/float64(lookahead)
+			required[j] = math.Min(required[j], ramp)
+		}
+	}
+
+	gain := 1.0
+	for i := 0; i < frames; i++ {
+		if required[i] < gain {
+			gain = required[i]
+		} else {
+			gain = required[i] + (gain-required[i])*release
+		}
+		for c := 0; c < p.Channels; c++ {
+			p.Samples[i*p.Channels+c] *= gain
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FadeIn ramps the first d of p up from silence.
+func FadeIn(p *PCM, d time.Duration) {
+	frames := min(DurationToFrames(d, p.SampleRate), p.Frames())
+	for i := 0; i < frames; i++ {
+		gain := float64(i) / float64(frames)
+		for c := 0; c < p.Channels; c++ {
+			p.Samples[i*p.Channels+c] *= gain
+		}
+	}
+}
+
+// FadeOut ramps the last d of p down to silence.
+func FadeOut(p *PCM, d time.Duration) {
+	total := p.Frames()
+	frames := min(DurationToFrames(d, p.SampleRate), total)
+	for i := 0; i < frames; i++ {
+		gain := float64(i) / float64(frames)
+		frame := total - 1 - i
+		for c := 0; c < p.Channels; c++ {
+			p.Samples[frame*p.Channels+c] *= gain
+		}
+	}
+}
+
+// Loop returns bed repeated until it is exactly frames long.
+func Loop(bed *PCM, frames int) *PCM {
+	out := &PCM{SampleRate: bed.SampleRate, Channels: bed.Channels, Samples: make([]float64, 0, frames*bed.Channels)}
+	if bed.Frames() == 0 {
+		out.Samples = make([]float64, frames*bed.Channels)
+		return out
+	}
+	for out.Frames() < frames {
+		out.Samples = append(out.Samples, bed.frameRange(0, frames-out.Frames())...)
+	}
+	return out
+}
+
+// Ducking describes how a background bed is mixed under a voice.
+type Ducking struct {
+	Volume  float64       // Bed level in dB while the voice is silent
+	Depth   float64       // Extra attenuation in dB while the voice is active
+	Attack  time.Duration // How fast the bed ducks when the voice starts
+	Release time.Duration // How fast the bed comes back when the voice stops
+}
+
+// MixBed loops bed under voice frames [start, end), ducking it whenever the voice is active.
+// Both must share sample rate and channel count.
+func MixBed(voice, bed *PCM, start, end int, ducking Ducking) error {
+	if voice.SampleRate != bed.SampleRate || voice.Channels != bed.Channels {
+		return fmt.Errorf("bed format %d Hz/%d ch does not match voice %d Hz/%d ch",
+			bed.SampleRate, bed.Channels, voice.SampleRate, voice.Channels)
+	}
+	start = max(0, start)
+	end = min(end, voice.Frames())
+	if end <= start {
+		return nil
+	}
+
+	looped := Loop(bed, end-start)
+	FadeIn(looped, time.Second)
+	FadeOut(looped, 2*time.Second)
+
+	const activeThreshold = -45.0 // dBFS voice level treated as speech
+	attack := math.Exp(-1 / (ducking.Attack.Seconds()*float64(voice.SampleRate) + 1))
+	release := math.Exp(-1 / (ducking.Release.Seconds()*float64(voice.SampleRate) + 1))
+	active := DBToLinear(activeThreshold)
+
+	envelope := 0.0
+	duck := 0.0
+	for i := 0; i < end-start; i++ {
+		frame := start + i
+		level := 0.0
+		for c := 0; c < voice.Channels; c++ {
+			level = math.Max(level, math.Abs(voice.Samples[frame*voice.Channels+c]))
+		}
+		// Peak envelope of the voice, then a smoothed 0..1 ducking amount.
+		envelope = math.Max(level, envelope*release)
+		target := 0.0
+		if envelope > active {
+			target = 1
+		}
+		if target > duck {
+			duck = target + (duck-target)*attack
+		} else {
+			duck = target + (duck-target)*release
+		}
+
+		gain := DBToLinear(ducking.Volume - ducking.Depth*duck)
+		for c := 0; c < voice.Channels; c++ {
+			voice.Samples[frame*voice.Channels+c] += looped.Samples[i*voice.Channels+c] * gain
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/braheezy/shine-mp3/pkg/mp3"
+	gomp3 "github.com/hajimehoshi/go-mp3"
+)
+
+// mp3FrameSamples is the number of interleaved stereo samples the encoder consumes per MPEG-1 frame.
+// MPEG-2 frames are half of it, so padding to this size suits both.
+const mp3FrameSamples = 1152 * 2
+
+// encoderGain compensates the constant 9/8 amplitude gain shine-mp3 adds to every encoded frame.
+const encoderGain = 8.0 / 9.0
+
+// DecodeMP3 decodes an MP3 stream into stereo PCM.
+func DecodeMP3(r io.Reader) (*PCM, error) {
+	decoder, err := gomp3.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mp3: %w", err)
+	}
+
+	raw, err := io.ReadAll(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mp3 samples: %w", err)
+	}
+
+	// go-mp3 always produces 16-bit little-endian stereo
+	samples := make([]float64, len(raw)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(raw[i*2:]))) / math.MaxInt16
+	}
+
+	return &PCM{
+		SampleRate: decoder.SampleRate(),
+		Channels:   2,
+		Samples:    samples,
+	}, nil
+}
+
+func DecodeMP3File(file string) (*PCM, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeMP3(bufio.NewReader(f))
+}
+
+// EncodeMP3 encodes p as a 128 kbit/s stereo MP3.
+func EncodeMP3(w io.Writer, p *PCM) error {
+	if p.Channels < 1 || p.Channels > 2 {
+		return fmt.Errorf("mp3 encoder supports mono or stereo, got %d channels", p.Channels)
+	}
+	// MPEG-2.5 rates below 16 kHz encode, but go-mp3 cannot read them back.
+	if p.SampleRate < 16000 || mp3.CheckConfig(p.SampleRate, 128) < 0 {
+		return fmt.Errorf("mp3 encoder does not support %d Hz sample rate", p.SampleRate)
+	}
+
+	// The encoder reads whole frames of interleaved stereo, so upmix mono and pad the tail.
+	frames := p.Frames()
+	size := frames * 2
+	if rest := size % mp3FrameSamples; rest != 0 || size == 0 {
+		size += mp3FrameSamples - rest
+	}
+	// shine-mp3 leaves an unsafe pointer just past the samples it read, the spare frame keeps
+	// it inside the allocation so the garbage collector never sees a dangling pointer.
+	data := make([]int16, size, size+mp3FrameSamples)
+	for i := 0; i < frames; i++ {
+		left := p.Samples[i*p.Channels]
+		right := left
+		if p.Channels == 2 {
+			right = p.Samples[i*2+1]
+		}
+		data[i*2] = toInt16(left * encoderGain)
+		data[i*2+1] = toInt16(right * encoderGain)
+	}
+
+	encoder := mp3.NewEncoder(p.SampleRate, 2)
+	return encoder.Write(w, data)
+}
+
+func EncodeMP3File(file string, p *PCM) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := EncodeMP3(w, p); err != nil {
+		return fmt.Errorf("failed to encode %q: %w", file, err)
+	}
+	return w.Flush()
+}
+
+func toInt16(v float64) int16 {
+	v = math.Max(-1, math.Min(1, v))
+	return int16(math.Round(v * math.MaxInt16))
+}
@@ -0,0 +1,110 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PCM is decoded audio kept in memory as interleaved samples in the range [-1, 1].
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []float64
+}
+
+func New(sampleRate, channels int) *PCM {
+	return &PCM{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Samples:    make([]float64, 0),
+	}
+}
+
+// Frames returns the number of sample frames (one sample per channel).
+func (p *PCM) Frames() int {
+	if p.Channels == 0 {
+		return 0
+	}
+	return len(p.Samples) / p.Channels
+}
+
+func (p *PCM) Duration() time.Duration {
+	return FramesToDuration(p.Frames(), p.SampleRate)
+}
+
+func (p *PCM) Seconds() float64 {
+	if p.SampleRate == 0 {
+		return 0
+	}
+	return float64(p.Frames()) / float64(p.SampleRate)
+}
+
+// Append adds other to the end of p. Both must share sample rate and channel count.
+func (p *PCM) Append(other *PCM) error {
+	if other.SampleRate != p.SampleRate || other.Channels != p.Channels {
+		return fmt.Errorf("cannot append %d Hz/%d ch audio to %d Hz/%d ch audio",
+			other.SampleRate, other.Channels, p.SampleRate, p.Channels)
+	}
+	p.Samples = append(p.Samples, other.Samples...)
+	return nil
+}
+
+func (p *PCM) AppendSilence(d time.Duration) {
+	frames := DurationToFrames(d, p.SampleRate)
+	p.Samples = append(p.Samples, make([]float64, frames*p.Channels)...)
+}
+
+// Slice returns a copy of the frames in [start, end).
+func (p *PCM) Slice(start, end int) *PCM {
+	frames := p.frameRange(start, end)
+	samples := make([]float64, len(frames))
+	copy(samples, frames)
+	return &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: samples}
+}
+
+// frameRange returns the samples of frames [start, end) without copying.
+func (p *PCM) frameRange(start, end int) []float64 {
+	start = max(0, min(start, p.Frames()))
+	end = max(start, min(end, p.Frames()))
+	return p.Samples[start*p.Channels : end*p.Channels]
+}
+
+// Gain multiplies every sample by the given amount of decibels.
+func (p *PCM) Gain(db float64) {
+	factor := DBToLinear(db)
+	for i := range p.Samples {
+		p.Samples[i] *= factor
+	}
+}
+
+// Peak returns the absolute sample peak in dBFS.
+func (p *PCM) Peak() float64 {
+	peak := 0.0
+	for _, s := range p.Samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	return LinearToDB(peak)
+}
+
+func DBToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func LinearToDB(v float64) float64 {
+	if v <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(v)
+}
+
+func DurationToFrames(d time.Duration, sampleRate int) int {
+	return int(d.Seconds() * float64(sampleRate))
+}
+
+func FramesToDuration(frames, sampleRate int) time.Duration {
+	if sampleRate == 0 {
+		return 0
+	}
+	return time.Duration(float64(frames) / float64(sampleRate) * float64(time.Second))
+}
@@ -0,0 +1,71 @@
+package audio
+
+import "math"
+
+const resampleTaps = 16
+
+// Resample converts p to the given sample rate using windowed-sinc interpolation.
+func Resample(p *PCM, sampleRate int) *PCM {
+	if p.SampleRate == sampleRate || p.Frames() == 0 {
+		return p
+	}
+
+	ratio := float64(sampleRate) / float64(p.SampleRate)
+	// Lower the cut-off when downsampling so content above the new Nyquist frequency does not alias.
+	cutoff := math.Min(1, ratio)
+	frames := int(math.Floor(float64(p.Frames()) * ratio))
+	out := &PCM{SampleRate: sampleRate, Channels: p.Channels, Samples: make([]float64, frames*p.Channels)}
+
+	for i := 0; i < frames; i++ {
+		pos := float64(i) / ratio
+		center := int(math.Floor(pos))
+		for c := 0; c < p.Channels; c++ {
+			sum, weight := 0.0, 0.0
+			for j := center - resampleTaps + 1; j <= center+resampleTaps; j++ {
+				if j < 0 || j >= p.Frames() {
+					continue
+				}
+				x := (pos - float64(j)) * cutoff
+				w := sinc(x) * (0.5 + 0.5*math.Cos(math.Pi*(pos-float64(j))/resampleTaps))
+				sum += p.Samples[j*p.Channels+c] * w
+				weight += w
+			}
+			if weight != 0 {
+				out.Samples[i*p.Channels+c] = sum / weight
+			}
+		}
+	}
+
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Convert returns p with the given sample rate and channel count.
+// Mono is duplicated into both stereo channels, stereo is averaged into mono.
+func Convert(p *PCM, sampleRate, channels int) *PCM {
+	out := p
+	if p.Channels != channels {
+		out = &PCM{SampleRate: p.SampleRate, Channels: channels, Samples: make([]float64, p.Frames()*channels)}
+		for i := 0; i < p.Frames(); i++ {
+			sum := 0.0
+			for c := 0; c < p.Channels; c++ {
+				sum += p.Samples[i*p.Channels+c]
+			}
+			for c := 0; c < channels; c++ {
+				if p.Channels == 1 {
+					out.Samples[i*channels+c] = p.Samples[i]
+				} else {
+					out.Samples[i*channels+c] = sum / float64(p.Channels)
+				}
+			}
+		}
+	}
+
+	return Resample(out, sampleRate)
+}
@@ -0,0 +1,130 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// WSOLA parameters: overlapping windows are taken from the input at the stretched rate,
+// each one shifted within the tolerance to best continue the previous window so the
+// pitch and the waveform stay intact.
+const (
+	stretchWindow    = 40 * time.Millisecond
+	stretchTolerance = 10 * time.Millisecond
+	// stretchDecimation thins the similarity search, speech is well above this resolution
+	stretchDecimation = 4
+)
+
+// TimeStretch changes the tempo of p by speed without changing its pitch, speed 1.25
+// makes the audio 25% faster and so 20% shorter. It uses WSOLA (waveform similarity overlap-add).
+func TimeStretch(p *PCM, speed float64) *PCM {
+	if speed <= 0 || speed == 1 || p.Frames() == 0 {
+		return &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: append([]float64(nil), p.Samples...)}
+	}
+
+	window := max(DurationToFrames(stretchWindow, p.SampleRate)&^1, 4)
+	synthesisHop := window / 2
+	tolerance := DurationToFrames(stretchTolerance, p.SampleRate)
+	frames := p.Frames()
+	length := int(math.Round(float64(frames) / speed))
+	if frames < window || length < window {
+		return resampleNearest(p, length)
+	}
+	mono := mixDown(p)
+
+	// Offset by half a sample so no window weight is zero, overlapping windows still sum to one
+	hann := make([]float64, window)
+	for i := range hann {
+		hann[i] = 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(window))
+	}
+
+	out := make([]float64, length*p.Channels)
+	weights := make([]float64, length)
+	add := func(position, synthesis int, weight func(i int) float64) {
+		for i := 0; i < window; i++ {
+			w := weight(i)
+			weights[synthesis+i] += w
+			for c := 0; c < p.Channels; c++ {
+				out[(synthesis+i)*p.Channels+c] += w * p.Samples[(position+i)*p.Channels+c]
+			}
+		}
+	}
+
+	// The first window keeps the start of the audio at full level
+	add(0, 0, func(i int) float64 {
+		if i < window/2 {
+			return 1
+		}
+		return hann[i]
+	})
+	// Windows stop before the second half of the last one, that half is the end of the input as it is
+	previous := 0
+	for k := 1; ; k++ {
+		synthesis := k * synthesisHop
+		if synthesis+window > length-window/2 {
+			break
+		}
+		nominal := min(int(math.Round(float64(synthesis)*speed)), frames-window)
+		position := bestOverlap(mono, previous+synthesisHop, nominal, tolerance, window)
+		add(position, synthesis, func(i int) float64 { return hann[i] })
+		previous = position
+	}
+	// The last window puts the end of the input at the end of the output
+	add(frames-window, length-window, func(i int) float64 {
+		if i >= window/2 {
+			return 1
+		}
+		return hann[i]
+	})
+
+	for i := range weights {
+		for c := 0; c < p.Channels; c++ {
+			out[i*p.Channels+c] /= weights[i]
+		}
+	}
+	return &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: out}
+}
+
+// resampleNearest stretches audio shorter than a WSOLA window to length frames by repeating or dropping frames.
+func resampleNearest(p *PCM, length int) *PCM {
+	out := &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: make([]float64, 0, length*p.Channels)}
+	for i := 0; i < length; i++ {
+		frame := min(i*p.Frames()/max(length, 1), p.Frames()-1)
+		out.Samples = append(out.Samples, p.Samples[frame*p.Channels:(frame+1)*p.Channels]...)
+	}
+	return out
+}
+
+// bestOverlap returns the position near nominal whose window is most similar to the natural
+// continuation of the previous window at target.
+func bestOverlap(mono []float64, target, nominal, tolerance, window int) int {
+	best, bestScore := nominal, math.Inf(-1)
+	start, end := max(nominal-tolerance, 0), min(nominal+tolerance, len(mono)-window)
+	if target+window > len(mono) || start > end {
+		return max(min(nominal, len(mono)-window), 0)
+	}
+
+	for position := start; position <= end; position += stretchDecimation {
+		score := 0.0
+		for i := 0; i < window; i += stretchDecimation {
+			score += mono[position+i] * mono[target+i]
+		}
+		if score > bestScore {
+			best, bestScore = position, score
+		}
+	}
+	return best
+}
+
+func mixDown(p *PCM) []float64 {
+	if p.Channels == 1 {
+		return p.Samples
+	}
+	mono := make([]float64, p.Frames())
+	for i := range mono {
+		for c := 0; c < p.Channels; c++ {
+			mono[i] += p.Samples[i*p.Channels+c]
+		}
+	}
+	return mono
+}
@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func stretchTone(rate, channels int, d time.Duration) *PCM {
+	p := New(rate, channels)
+	for i := 0; i < DurationToFrames(d, rate); i++ {
+		v := 0.5 * math.Sin(2*math.Pi*220*float64(i)/float64(rate))
+		for c := 0; c < channels; c++ {
+			p.Samples = append(p.Samples, v)
+		}
+	}
+	return p
+}
+
+func rms(samples []float64) float64 {
+	sum := 0.0
+	for _, s := range samples {
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(max(len(samples), 1)))
+}
+
+func TestTimeStretchLength(t *testing.T) {
+	for _, channels := range []int{1, 2} {
+		in := stretchTone(24000, channels, 3*time.Second)
+		for _, speed := range []float64{0.5, 0.8, 1, 1.1, 1.25, 2} {
+			out := TimeStretch(in, speed)
+			want := int(math.Round(float64(in.Frames()) / speed))
+			if out.Frames() != want || out.Channels != channels || out.SampleRate != in.SampleRate {
+				t.Errorf("TimeStretch(%d channels, %.2f) = %d frames %d channels at %d Hz, want %d frames", channels, speed, out.Frames(), out.Channels, out.SampleRate, want)
+			}
+		}
+	}
+}
+
+func TestTimeStretchKeepsTheEnds(t *testing.T) {
+	in := stretchTone(24000, 1, 2*time.Second)
+	edge := DurationToFrames(50*time.Millisecond, 24000)
+	for _, speed := range []float64{0.8, 1.1, 1.25, 1.5} {
+		out := TimeStretch(in, speed)
+		if level := rms(out.Samples[:edge]); level < 0.3 {
+			t.Errorf("speed %.2f: first 50ms RMS %.3f, want the tone at about 0.35", speed, level)
+		}
+		if level := rms(out.Samples[len(out.Samples)-edge:]); level < 0.3 {
+			t.Errorf("speed %.2f: last 50ms RMS %.3f, want the tone at about 0.35", speed, level)
+		}
+		for i, s := range out.Samples {
+			if math.Abs(s) > 0.6 {
+				t.Fatalf("speed %.2f: sample %d is %.3f, louder than the input", speed, i, s)
+			}
+		}
+	}
+}
+
+func TestTimeStretchEndsWithTheInput(t *testing.T) {
+	// The input ends with a click the output has to end with too
+	in := stretchTone(24000, 2, 1013*time.Millisecond)
+	in.Samples[len(in.Samples)-2], in.Samples[len(in.Samples)-1] = 0.9, -0.9
+	half := DurationToFrames(stretchWindow, 24000) / 2
+	for _, speed := range []float64{0.8, 1.1, 1.25, 1.5} {
+		out := TimeStretch(in, speed)
+		for i := 1; i <= half*in.Channels; i++ {
+			got, want := out.Samples[len(out.Samples)-i], in.Samples[len(in.Samples)-i]
+			if math.Abs(got-want) > 1e-9 {
+				t.Fatalf("speed %.2f: sample %d from the end is %.9f, want the input %.9f", speed, i, got, want)
+			}
+		}
+		for i := 0; i < half*in.Channels; i++ {
+			if math.Abs(out.Samples[i]-in.Samples[i]) > 1e-9 {
+				t.Fatalf("speed %.2f: sample %d is %.4f, want the input %.4f", speed, i, out.Samples[i], in.Samples[i])
+			}
+		}
+	}
+}
+
+func TestTimeStretchShortInput(t *testing.T) {
+	in := stretchTone(24000, 1, 10*time.Millisecond)
+	out := TimeStretch(in, 1.25)
+	if want := int(math.Round(float64(in.Frames()) / 1.25)); out.Frames() != want {
+		t.Errorf("TimeStretch() = %d frames, want %d", out.Frames(), want)
+	}
+	if same := TimeStretch(in, 1); &same.Samples[0] == &in.Samples[0] || same.Frames() != in.Frames() {
+		t.Error("TimeStretch(1) must return a copy of the input")
+	}
+}
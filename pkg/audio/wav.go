@@ -0,0 +1,185 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// DecodeWAV decodes 8, 16, 24 or 32-bit integer and 32 or 64-bit float WAV audio.
+func DecodeWAV(r io.Reader) (*PCM, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read wav header: %w", err)
+	}
+	if !bytes.Equal(header[:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WAVE")) {
+		return nil, fmt.Errorf("not a wav file")
+	}
+
+	var format, channels, bits int
+	var sampleRate int
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("wav file has no data chunk: %w", err)
+		}
+		id, size := string(chunk[:4]), binary.LittleEndian.Uint32(chunk[4:])
+
+		switch id {
+		case "fmt ":
+			data := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, data); err != nil || size < 16 {
+				return nil, fmt.Errorf("failed to read wav format: %w", err)
+			}
+			format = int(binary.LittleEndian.Uint16(data[0:]))
+			channels = int(binary.LittleEndian.Uint16(data[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(data[4:]))
+			bits = int(binary.LittleEndian.Uint16(data[14:]))
+			if format == wavFormatExtensible && size >= 26 {
+				format = int(binary.LittleEndian.Uint16(data[24:]))
+			}
+		case "data":
+			if channels == 0 || bits == 0 {
+				return nil, fmt.Errorf("wav data chunk before format chunk")
+			}
+			// Streamed WAV files often leave the size unset, read to the end then
+			var data []byte
+			var err error
+			if size == 0 || size == math.MaxUint32 {
+				data, err = io.ReadAll(r)
+			} else {
+				data = make([]byte, size)
+				var n int
+				n, err = io.ReadFull(r, data)
+				if err == io.ErrUnexpectedEOF {
+					data, err = data[:n], nil
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read wav data: %w", err)
+			}
+			samples, err := wavSamples(data, format, bits)
+			if err != nil {
+				return nil, err
+			}
+			return &PCM{SampleRate: sampleRate, Channels: channels, Samples: samples}, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return nil, fmt.Errorf("failed to skip wav %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+func wavSamples(data []byte, format, bits int) ([]float64, error) {
+	width := bits / 8
+	if width == 0 {
+		return nil, fmt.Errorf("unsupported wav sample size %d bits", bits)
+	}
+	samples := make([]float64, len(data)/width)
+	for i := range samples {
+		b := data[i*width:]
+		switch {
+		case format == wavFormatPCM && bits == 8:
+			samples[i] = (float64(b[0]) - 128) / 128
+		case format == wavFormatPCM && bits == 16:
+			samples[i] = float64(int16(binary.LittleEndian.Uint16(b))) / math.MaxInt16
+		case format == wavFormatPCM && bits == 24:
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			samples[i] = float64(v) / (1 << 23)
+		case format == wavFormatPCM && bits == 32:
+			samples[i] = float64(int32(binary.LittleEndian.Uint32(b))) / math.MaxInt32
+		case format == wavFormatFloat && bits == 32:
+			samples[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case format == wavFormatFloat && bits == 64:
+			samples[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		default:
+			return nil, fmt.Errorf("unsupported wav encoding %d with %d bits", format, bits)
+		}
+	}
+	return samples, nil
+}
+
+func DecodeWAVFile(file string) (*PCM, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return DecodeWAV(bufio.NewReader(f))
+}
+
+// DecodeRawFile decodes headerless 16-bit little-endian PCM.
+func DecodeRawFile(file string, sampleRate, channels int) (*PCM, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := wavSamples(data, wavFormatPCM, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &PCM{SampleRate: sampleRate, Channels: channels, Samples: samples}, nil
+}
+
+// EncodeWAV writes p as 16-bit PCM WAV.
+func EncodeWAV(w io.Writer, p *PCM) error {
+	size := len(p.Samples) * 2
+	if size > math.MaxUint32-36 {
+		return fmt.Errorf("audio is too long for a wav file")
+	}
+
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+size))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:], uint16(p.Channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(p.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(p.SampleRate*p.Channels*2))
+	binary.LittleEndian.PutUint16(header[32:], uint16(p.Channels*2))
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(size))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	data := make([]byte, size)
+	for i, s := range p.Samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(toInt16(s)))
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func EncodeWAVFile(file string, p *PCM) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := EncodeWAV(w, p); err != nil {
+		return fmt.Errorf("failed to encode %q: %w", file, err)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,230 @@
+package bundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Extension of bundle files
+	Extension = ".storygen"
+	// FormatName identifies bundle manifests
+	FormatName = "storygen-bundle"
+	// FormatVersion is the manifest layout this version writes, newer bundles are rejected
+	FormatVersion = 1
+
+	ManifestFile = "manifest.json"
+	StoryFile    = "story.json"
+)
+
+// File roles in the manifest.
+const (
+	RoleStory        = "story"
+	RoleTranslation  = "translation"
+	RoleAudio        = "audio"
+	RoleChapterAudio = "chapter-audio"
+	RoleSubtitles    = "subtitles"
+	RoleTimings      = "timings"
+	RoleChapters     = "chapters"
+	RoleTranscript   = "transcript"
+	RoleUsage        = "usage"
+	RoleReadAlong    = "read-along"
+	RoleImage        = "image"
+)
+
+// Manifest describes a .storygen bundle: a zip holding a story with its translations, audio,
+// subtitles and images, every file with a checksum. It is the first file in the zip.
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Created   time.Time `json:"created"`
+	Generator string    `json:"generator"`
+	Title     string    `json:"title"`
+	Files     []File    `json:"files"`
+}
+
+// File is a bundled file with its checksum.
+type File struct {
+	Path     string `json:"path"`
+	Role     string `json:"role"`
+	Language string `json:"language,omitempty"`
+	Chapter  int    `json:"chapter,omitempty"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// Entry is a file to pack, read from Source unless Data is set.
+type Entry struct {
+	File
+	Source string
+	Data   []byte
+}
+
+// Find returns the first file with the role.
+func (m Manifest) Find(role string) (File, bool) {
+	for _, f := range m.Files {
+		if f.Role == role {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Pack writes the entries and their manifest into file.
+func Pack(file, title string, entries []Entry) (Manifest, error) {
+	manifest := Manifest{Format: FormatName, Version: FormatVersion, Created: time.Now().UTC().Truncate(time.Second), Generator: "storygen", Title: title}
+	seen := make(map[string]bool)
+	for i := range entries {
+		e := &entries[i]
+		if err := checkPath(e.Path); err != nil {
+			return manifest, err
+		}
+		if seen[e.Path] {
+			return manifest, fmt.Errorf("bundle path %q is used twice", e.Path)
+		}
+		seen[e.Path] = true
+		if e.Data == nil {
+			data, err := os.ReadFile(e.Source)
+			if err != nil {
+				return manifest, err
+			}
+			e.Data = data
+		}
+		sum := sha256.Sum256(e.Data)
+		e.Size, e.SHA256 = int64(len(e.Data)), hex.EncodeToString(sum[:])
+		manifest.Files = append(manifest.Files, e.File)
+	}
+	sort.SliceStable(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	f, err := os.Create(file)
+	if err != nil {
+		return manifest, err
+	}
+	defer f.Close()
+	z := zip.NewWriter(f)
+	write := func(name string, data []byte) error {
+		w, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: manifest.Created})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := write(ManifestFile, data); err != nil {
+		return manifest, err
+	}
+	for _, e := range entries {
+		if err := write(e.Path, e.Data); err != nil {
+			return manifest, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, f.Close()
+}
+
+// Bundle is an opened, verified bundle.
+type Bundle struct {
+	Manifest Manifest
+	zip      *zip.ReadCloser
+	files    map[string]*zip.File
+}
+
+// Open reads the manifest and checks that every listed file is present with its checksum.
+func Open(file string) (*Bundle, error) {
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a bundle: %w", file, err)
+	}
+	b := &Bundle{zip: z, files: make(map[string]*zip.File)}
+	for _, f := range z.File {
+		b.files[f.Name] = f
+	}
+
+	data, err := b.read(ManifestFile)
+	if err != nil {
+		z.Close()
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err := json.Unmarshal(data, &b.Manifest); err != nil {
+		z.Close()
+		return nil, fmt.Errorf("%s: invalid manifest: %w", file, err)
+	}
+	if err := b.verify(); err != nil {
+		z.Close()
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return b, nil
+}
+
+func (b *Bundle) verify() error {
+	m := b.Manifest
+	if m.Format != FormatName {
+		return fmt.Errorf("manifest format is %q, expected %q", m.Format, FormatName)
+	}
+	if m.Version < 1 || m.Version > FormatVersion {
+		return fmt.Errorf("bundle version %d is not supported, this storygen reads versions 1 to %d", m.Version, FormatVersion)
+	}
+	if _, ok := m.Find(RoleStory); !ok {
+		return fmt.Errorf("manifest has no %s file", RoleStory)
+	}
+	for _, f := range m.Files {
+		if err := checkPath(f.Path); err != nil {
+			return err
+		}
+		data, err := b.read(f.Path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s, the bundle is damaged", f.Path)
+		}
+	}
+	return nil
+}
+
+// ReadFile returns the content of a bundled file.
+func (b *Bundle) ReadFile(name string) ([]byte, error) {
+	return b.read(name)
+}
+
+func (b *Bundle) read(name string) ([]byte, error) {
+	f, ok := b.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing from the bundle", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (b *Bundle) Close() error {
+	return b.zip.Close()
+}
+
+// checkPath rejects paths that would leave the directory the bundle is unpacked to.
+func checkPath(name string) error {
+	if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) || path.Clean(name) != name || strings.HasPrefix(name, "../") || name == ".." {
+		return fmt.Errorf("invalid bundle path %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package bundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rewriteZip replaces file with a zip of the same entries, changed by edit.
+func rewriteZip(t *testing.T, file string, edit func(name string, data []byte) []byte) {
+	t.Helper()
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	contents := make(map[string][]byte)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		contents[f.Name] = data
+	}
+	z.Close()
+	writeZip(t, file, names, func(name string) []byte { return edit(name, contents[name]) })
+}
+
+func writeZip(t *testing.T, file string, names []string, content func(name string) []byte) {
+	t.Helper()
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write(content(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEntries() []Entry {
+	return []Entry{
+		{File: File{Path: StoryFile, Role: RoleStory}, Data: []byte(`{"title":"The Fox"}`)},
+		{File: File{Path: "audio/the-fox.srt", Role: RoleSubtitles}, Data: []byte("1\n00:00:00,000 --> 00:00:01,000\nOnce.\n")},
+		{File: File{Path: "audio/the-fox.mp3", Role: RoleAudio}, Data: []byte("ID3 audio")},
+	}
+}
+
+func TestPackOpen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "the-fox"+Extension)
+	manifest, err := Pack(file, "The Fox", testEntries())
+	if err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+
+	b, err := Open(file)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer b.Close()
+
+	if !reflect.DeepEqual(b.Manifest, manifest) {
+		t.Errorf("Open() manifest = %+v, want %+v", b.Manifest, manifest)
+	}
+	paths := make([]string, 0)
+	for _, f := range b.Manifest.Files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"audio/the-fox.mp3", "audio/the-fox.srt", StoryFile}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("manifest paths = %q, want sorted %q", paths, want)
+	}
+	sum := sha256.Sum256([]byte("ID3 audio"))
+	if f, _ := b.Manifest.Find(RoleAudio); f.Size != 9 || f.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("audio file = %+v, want its size and sha256", f)
+	}
+	for _, e := range testEntries() {
+		data, err := b.ReadFile(e.Path)
+		if err != nil || string(data) != string(e.Data) {
+			t.Errorf("ReadFile(%q) = %q, %v, want %q", e.Path, data, err, e.Data)
+		}
+	}
+
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	if z.File[0].Name != ManifestFile {
+		t.Errorf("first file is %s, want the manifest", z.File[0].Name)
+	}
+}
+
+func TestPackRejectsPaths(t *testing.T) {
+	for _, name := range []string{"", "../story.json", "/etc/passwd", `audio\the-fox.mp3`, "audio/../../x", "audio//x", ".."} {
+		entries := append(testEntries(), Entry{File: File{Path: name, Role: RoleAudio}, Data: []byte("x")})
+		if _, err := Pack(filepath.Join(t.TempDir(), "b"+Extension), "The Fox", entries); err == nil {
+			t.Errorf("Pack() with path %q error = nil", name)
+		}
+	}
+
+	entries := append(testEntries(), testEntries()[1])
+	if _, err := Pack(filepath.Join(t.TempDir(), "b"+Extension), "The Fox", entries); err == nil || !strings.Contains(err.Error(), "used twice") {
+		t.Errorf("Pack() with a path used twice error = %v", err)
+	}
+}
+
+func TestOpenChecksumMismatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "the-fox"+Extension)
+	if _, err := Pack(file, "The Fox", testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	rewriteZip(t, file, func(name string, data []byte) []byte {
+		if name == "audio/the-fox.mp3" {
+			return []byte("ID3 AUDIO")
+		}
+		return data
+	})
+
+	if _, err := Open(file); err == nil || !strings.Contains(err.Error(), "checksum mismatch for audio/the-fox.mp3") {
+		t.Errorf("Open() error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestOpenRejectsBadManifests(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(m *Manifest)
+		files []string
+		want  string
+	}{
+		{
+			name: "path traversal",
+			edit: func(m *Manifest) {
+				m.Files = append(m.Files, File{Path: "../../.bashrc", Role: RoleAudio})
+			},
+			files: []string{"../../.bashrc"},
+			want:  `invalid bundle path "../../.bashrc"`,
+		},
+		{
+			name: "missing file",
+			edit: func(m *Manifest) {
+				m.Files = append(m.Files, File{Path: "audio/gone.mp3", Role: RoleAudio})
+			},
+			want: "audio/gone.mp3 is missing from the bundle",
+		},
+		{
+			name: "newer version",
+			edit: func(m *Manifest) { m.Version = FormatVersion + 1 },
+			want: "is not supported",
+		},
+		{
+			name: "other format",
+			edit: func(m *Manifest) { m.Format = "zip" },
+			want: "manifest format",
+		},
+		{
+			name: "no story",
+			edit: func(m *Manifest) { m.Files = m.Files[:2] },
+			want: "manifest has no story file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "the-fox"+Extension)
+			manifest, err := Pack(file, "The Fox", testEntries())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(&manifest)
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{ManifestFile}
+			for _, e := range testEntries() {
+				names = append(names, e.Path)
+			}
+			contents := make(map[string][]byte)
+			for _, e := range testEntries() {
+				contents[e.Path] = e.Data
+			}
+			contents[ManifestFile] = data
+			for _, name := range tt.files {
+				names = append(names, name)
+				contents[name] = []byte("x")
+			}
+			writeZip(t, file, names, func(name string) []byte { return contents[name] })
+
+			if _, err := Open(file); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Open() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Sources tells Collect where the files around a story are.
+type Sources struct {
+	// Translations are the translated story files by language name
+	Translations  map[string]string
+	Cover         string
+	Illustrations map[int]string
+	// ChapterAudio cuts the narration into one file per chapter
+	ChapterAudio bool
+}
+
+// sidecarRoles maps file name endings next to a narration to their role.
+var sidecarRoles = []struct{ suffix, role string }{
+	{".timings.json", RoleTimings},
+	{".chapters.json", RoleChapters},
+	{".transcript.json", RoleTranscript},
+	{".transcript.txt", RoleTranscript},
+	{".usage.json", RoleUsage},
+	{".srt", RoleSubtitles},
+	{".vtt", RoleSubtitles},
+	{".html", RoleReadAlong},
+}
+
+// Collect gathers the story, its translations, their narrations with the files written next
+// to them, and the images into bundle entries. Narration paths in the bundled JSONs point into
+// the bundle.
+func Collect(storyFile string, s story.Story, src Sources) ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	narration, err := collectNarration(&s, "", src.ChapterAudio)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, Entry{File: File{Path: StoryFile, Role: RoleStory}, Data: []byte(s.ToJson())})
+	entries = append(entries, narration...)
+
+	absStory, _ := filepath.Abs(storyFile)
+	languages := make([]string, 0, len(src.Translations))
+	for language := range src.Translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		file := src.Translations[language]
+		if abs, _ := filepath.Abs(file); abs == absStory {
+			continue
+		}
+		translation, err := story.Load(file)
+		if err != nil {
+			return nil, fmt.Errorf("translation: %w", err)
+		}
+		l := utils.FindLanguage(language)
+		narration, err := collectNarration(&translation, l.ISO1, src.ChapterAudio)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{File: File{Path: "translations/" + language + ".json", Role: RoleTranslation, Language: l.ISO1}, Data: []byte(translation.ToJson())})
+		entries = append(entries, narration...)
+	}
+
+	if src.Cover != "" {
+		entries = append(entries, Entry{File: File{Path: "images/cover" + strings.ToLower(filepath.Ext(src.Cover)), Role: RoleImage}, Source: src.Cover})
+	}
+	for chapter, image := range src.Illustrations {
+		entries = append(entries, Entry{File: File{Path: fmt.Sprintf("images/chapter_%d%s", chapter, strings.ToLower(filepath.Ext(image))), Role: RoleImage, Chapter: chapter}, Source: image})
+	}
+	return entries, nil
+}
+
+// collectNarration adds the narration audio, the files sharing its name and optionally the
+// chapter audio, and points the story narration at the bundled audio.
+func collectNarration(s *story.Story, language string, chapterAudio bool) ([]Entry, error) {
+	if s.Narration == nil || s.Narration.File == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(s.Narration.File); err != nil {
+		fmt.Printf("Warning: narration %s not found, bundling the story without it\n", s.Narration.File)
+		return nil, nil
+	}
+
+	file := s.Narration.File
+	name := filepath.Base(file)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	entries := []Entry{{File: File{Path: "audio/" + name, Role: RoleAudio, Language: language}, Source: file}}
+
+	siblings, err := os.ReadDir(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	for _, sibling := range siblings {
+		if sibling.IsDir() || !strings.HasPrefix(sibling.Name(), stem+".") || sibling.Name() == name {
+			continue
+		}
+		for _, r := range sidecarRoles {
+			if strings.HasSuffix(sibling.Name(), r.suffix) {
+				entries = append(entries, Entry{
+					File:   File{Path: "audio/" + sibling.Name(), Role: r.role, Language: language},
+					Source: filepath.Join(filepath.Dir(file), sibling.Name()),
+				})
+				break
+			}
+		}
+	}
+
+	if chapterAudio && s.Narration.HasTimings() {
+		chapters, err := cutChapters(file, s.Narration.ChapterMarkers(), language)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cut chapter audio: %w", err)
+		}
+		entries = append(entries, chapters...)
+	}
+
+	s.Narration.File = "audio/" + name
+	return entries, nil
+}
+
+// cutChapters splits the narration at the chapter markers.
+func cutChapters(file string, markers []story.ChapterMarker, language string) ([]Entry, error) {
+	format, err := audio.ParseFormat(filepath.Ext(file))
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := audio.DecodeFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "storygen-chapters")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	stem := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	entries := make([]Entry, 0, len(markers))
+	for _, m := range markers {
+		start := min(audio.DurationToFrames(time.Duration(m.Start*float64(time.Second)), pcm.SampleRate), pcm.Frames())
+		end := min(audio.DurationToFrames(time.Duration(m.End*float64(time.Second)), pcm.SampleRate), pcm.Frames())
+		chapter := &audio.PCM{SampleRate: pcm.SampleRate, Channels: pcm.Channels, Samples: pcm.Samples[start*pcm.Channels : end*pcm.Channels]}
+
+		name := fmt.Sprintf("%s_chapter_%02d%s", stem, m.Chapter, format.Extension())
+		if err := audio.EncodeFile(filepath.Join(tmp, name), chapter, format); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(tmp, name))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{File: File{Path: path.Join("audio", "chapters", name), Role: RoleChapterAudio, Language: language, Chapter: m.Chapter}, Data: data})
+	}
+	return entries, nil
+}
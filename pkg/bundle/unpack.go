@@ -0,0 +1,162 @@
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+	"github.com/andrejsstepanovs/storygen/pkg/workspace"
+)
+
+// Library is where unpacked stories go: the workspaces directory and the directory for audio,
+// like STORYGEN_STORIES_DIR and STORYGEN_TARGET_DIR.
+type Library struct {
+	StoriesDir string
+	AudioDir   string
+}
+
+// Unpack imports a bundle into the library: the story and its translations go into the workspace
+// of the story ID, audio and subtitles to the audio directory, and the narration paths are pointed
+// at them. Nothing is written when a file would overwrite a different one. It returns the written files.
+func Unpack(file string, lib Library) ([]string, error) {
+	b, err := Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+
+	data, err := b.ReadFile(StoryFile)
+	if err != nil {
+		return nil, err
+	}
+	s, err := story.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid %s: %w", file, StoryFile, err)
+	}
+	dir := filepath.Join(lib.StoriesDir, s.ID)
+
+	stories := make(map[string]story.Story) // By language, "" is the original
+	targets := make(map[string][]byte)
+	order := make([]string, 0, len(b.Manifest.Files))
+	sources := make(map[string]string) // Target -> bundle path
+	place := func(target, name string, data []byte) error {
+		if other, ok := sources[target]; ok {
+			return fmt.Errorf("%s: %s and %s would both be unpacked to %s", file, other, name, target)
+		}
+		sources[target] = name
+		order = append(order, target)
+		targets[target] = data
+		return nil
+	}
+
+	for _, f := range b.Manifest.Files {
+		data, err := b.ReadFile(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Role {
+		case RoleStory, RoleTranslation:
+			language, target := "", filepath.Join(dir, workspace.StoryFile)
+			if f.Role == RoleTranslation {
+				language = strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path))
+				if !knownLanguage(language) {
+					return nil, fmt.Errorf("%s: %s is a translation to unknown language %q", file, f.Path, language)
+				}
+				target = filepath.Join(dir, workspace.TranslationsDir, language+".json")
+			}
+			translated, err := story.Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid %s: %w", file, f.Path, err)
+			}
+			translated.ID = s.ID
+			if translated.Narration != nil && strings.HasPrefix(translated.Narration.File, audioPrefix) {
+				if err := checkPath(translated.Narration.File); err != nil {
+					return nil, fmt.Errorf("%s: narration of %s: %w", file, f.Path, err)
+				}
+				translated.Narration.File = libraryPath(lib.AudioDir, audioPrefix, translated.Narration.File)
+			}
+			if existing, err := story.Load(target); err == nil {
+				if existing.ToJson() != translated.ToJson() {
+					return nil, fmt.Errorf("%s already exists with different content, move it away to import %s", target, file)
+				}
+				continue // Imported before
+			}
+			stories[language] = translated
+		case RoleImage:
+			err = place(libraryPath(filepath.Join(dir, workspace.ImagesDir), imagesPrefix, f.Path), f.Path, data)
+		default:
+			err = place(libraryPath(lib.AudioDir, audioPrefix, f.Path), f.Path, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, target := range order {
+		existing, err := os.ReadFile(target)
+		if err == nil && !bytes.Equal(existing, targets[target]) {
+			return nil, fmt.Errorf("%s already exists with different content, move it away to import %s", target, file)
+		}
+	}
+
+	written := make([]string, 0, len(order)+len(stories))
+	for _, target := range order {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, targets[target], 0644); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+
+	ws, err := workspace.Open(lib.StoriesDir, s.ID)
+	if err != nil {
+		return written, err
+	}
+	languages := make([]string, 0, len(stories))
+	for language := range stories {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		saved, err := ws.SaveNarration(language, stories[language])
+		if err != nil {
+			return written, err
+		}
+		written = append(written, saved)
+	}
+	if len(stories) == 0 {
+		return written, nil
+	}
+	ws.State.Stage = workspace.StageImported
+	return written, ws.Save()
+}
+
+// Bundle directories whose content is unpacked into the library directories.
+const (
+	audioPrefix  = "audio/"
+	imagesPrefix = "images/"
+)
+
+// libraryPath places a bundle path under dir, keeping its path below the bundle directory
+// so chapter audio and files sharing a name do not overwrite each other.
+func libraryPath(dir, prefix, name string) string {
+	return filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, prefix)))
+}
+
+// knownLanguage reports whether a translation is named after a language storygen knows.
+func knownLanguage(name string) bool {
+	for _, l := range utils.Languages() {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/workspace"
+)
+
+func testStory(narration string) story.Story {
+	s := story.Story{
+		ID:       "1a2b3c4d5e6f",
+		Title:    "The Fox",
+		Chapters: story.Chapters{{Number: 1, Text: "Once upon a time."}},
+	}
+	if narration != "" {
+		s.Narration = &story.Narration{File: narration, Voice: "nova", Speed: 1}
+	}
+	return s
+}
+
+func storyJSON(narration string) []byte {
+	s := testStory(narration)
+	return []byte(s.ToJson())
+}
+
+func writeFile(t *testing.T, file, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func testLibrary(t *testing.T) Library {
+	dir := t.TempDir()
+	return Library{StoriesDir: filepath.Join(dir, "stories"), AudioDir: filepath.Join(dir, "audio")}
+}
+
+func readFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	audio := writeFile(t, filepath.Join(src, "out", "the-fox.mp3"), "ID3 fox")
+	writeFile(t, filepath.Join(src, "out", "the-fox.srt"), "1\n00:00:00,000 --> 00:00:01,000\nOnce.\n")
+	writeFile(t, filepath.Join(src, "out", "the-fox.unrelated"), "not bundled")
+	translationAudio := writeFile(t, filepath.Join(src, "out", "latvian_the-fox.mp3"), "ID3 lapsa")
+	translation := testStory(translationAudio)
+	translation.Title = "Lapsa"
+	translationFile := writeFile(t, filepath.Join(src, "latvian.json"), translation.ToJson())
+	cover := writeFile(t, filepath.Join(src, "cover.png"), "png")
+	storyFile := filepath.Join(src, "story.json")
+
+	entries, err := Collect(storyFile, testStory(audio), Sources{Translations: map[string]string{"latvian": translationFile}, Cover: cover})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "the-fox"+Extension)
+	if _, err := Pack(file, "The Fox", entries); err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+
+	lib := testLibrary(t)
+	written, err := Unpack(file, lib)
+	if err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	dir := filepath.Join(lib.StoriesDir, "1a2b3c4d5e6f")
+	want := []string{
+		filepath.Join(lib.AudioDir, "latvian_the-fox.mp3"),
+		filepath.Join(lib.AudioDir, "the-fox.mp3"),
+		filepath.Join(lib.AudioDir, "the-fox.srt"),
+		filepath.Join(dir, workspace.ImagesDir, "cover.png"),
+		filepath.Join(dir, workspace.StoryFile),
+		filepath.Join(dir, workspace.TranslationsDir, "latvian.json"),
+	}
+	got := append([]string(nil), written...)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unpack() wrote\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	s, err := story.Load(filepath.Join(dir, workspace.StoryFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Narration.File != filepath.Join(lib.AudioDir, "the-fox.mp3") || readFile(t, s.Narration.File) != "ID3 fox" {
+		t.Errorf("narration = %s, want the unpacked audio", s.Narration.File)
+	}
+	lv, err := story.Load(filepath.Join(dir, workspace.TranslationsDir, "latvian.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lv.Title != "Lapsa" || lv.Narration.File != filepath.Join(lib.AudioDir, "latvian_the-fox.mp3") || readFile(t, lv.Narration.File) != "ID3 lapsa" {
+		t.Errorf("translation = %q narrated in %s", lv.Title, lv.Narration.File)
+	}
+
+	ws, err := workspace.Open(lib.StoriesDir, "1a2b3c4d5e6f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.State.Stage != workspace.StageImported || !reflect.DeepEqual(ws.State.Translations, []string{"latvian"}) {
+		t.Errorf("state = %+v", ws.State)
+	}
+
+	// Imported before, nothing changes
+	if _, err := Unpack(file, lib); err != nil {
+		t.Errorf("Unpack() again error = %v", err)
+	}
+}
+
+// packEntries packs a story narrated from audio/the-fox.mp3 with the extra entries.
+func packEntries(t *testing.T, extra ...Entry) string {
+	t.Helper()
+	entries := append([]Entry{
+		{File: File{Path: StoryFile, Role: RoleStory}, Data: storyJSON("audio/the-fox.mp3")},
+		{File: File{Path: "audio/the-fox.mp3", Role: RoleAudio}, Data: []byte("ID3 fox")},
+	}, extra...)
+	file := filepath.Join(t.TempDir(), "the-fox"+Extension)
+	if _, err := Pack(file, "The Fox", entries); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestUnpackKeepsBundlePaths(t *testing.T) {
+	file := packEntries(t,
+		Entry{File: File{Path: "audio/chapters/the-fox.mp3", Role: RoleChapterAudio, Chapter: 1}, Data: []byte("ID3 chapter one")},
+		Entry{File: File{Path: "images/chapter_1.png", Role: RoleImage, Chapter: 1}, Data: []byte("png")},
+	)
+	lib := testLibrary(t)
+	if _, err := Unpack(file, lib); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(lib.AudioDir, "the-fox.mp3")); got != "ID3 fox" {
+		t.Errorf("narration = %q, overwritten by the chapter audio", got)
+	}
+	if got := readFile(t, filepath.Join(lib.AudioDir, "chapters", "the-fox.mp3")); got != "ID3 chapter one" {
+		t.Errorf("chapter audio = %q", got)
+	}
+	if got := readFile(t, filepath.Join(lib.StoriesDir, "1a2b3c4d5e6f", workspace.ImagesDir, "chapter_1.png")); got != "png" {
+		t.Errorf("illustration = %q", got)
+	}
+}
+
+func TestUnpackRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra []Entry
+		want  string
+	}{
+		{
+			name: "files unpacked to the same path",
+			extra: []Entry{
+				{File: File{Path: "audio/the-fox.srt", Role: RoleSubtitles}, Data: []byte("one")},
+				{File: File{Path: "the-fox.srt", Role: RoleSubtitles}, Data: []byte("two")},
+			},
+			want: "would both be unpacked to",
+		},
+		{
+			name:  "unknown translation language",
+			extra: []Entry{{File: File{Path: "translations/klingon.json", Role: RoleTranslation}, Data: storyJSON("")}},
+			want:  `unknown language "klingon"`,
+		},
+		{
+			name:  "translation named by its code",
+			extra: []Entry{{File: File{Path: "translations/lv.json", Role: RoleTranslation}, Data: storyJSON("")}},
+			want:  `unknown language "lv"`,
+		},
+		{
+			name:  "narration outside the bundle",
+			extra: []Entry{{File: File{Path: "translations/latvian.json", Role: RoleTranslation}, Data: storyJSON("audio/../../../.bashrc")}},
+			want:  `invalid bundle path "audio/../../../.bashrc"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := testLibrary(t)
+			written, err := Unpack(packEntries(t, tt.extra...), lib)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Unpack() error = %v, want %q", err, tt.want)
+			}
+			if len(written) > 0 {
+				t.Errorf("Unpack() wrote %q", written)
+			}
+			if _, err := os.Stat(lib.AudioDir); err == nil {
+				t.Errorf("Unpack() created %s", lib.AudioDir)
+			}
+		})
+	}
+}
+
+func TestUnpackKeepsDifferentFiles(t *testing.T) {
+	lib := testLibrary(t)
+	existing := writeFile(t, filepath.Join(lib.AudioDir, "the-fox.mp3"), "ID3 another fox")
+
+	written, err := Unpack(packEntries(t), lib)
+	if err == nil || !strings.Contains(err.Error(), "already exists with different content") {
+		t.Errorf("Unpack() error = %v, want the existing file kept", err)
+	}
+	if len(written) > 0 || readFile(t, existing) != "ID3 another fox" {
+		t.Errorf("Unpack() wrote %q over %s", written, existing)
+	}
+}
+
+func TestUnpackDamagedBundle(t *testing.T) {
+	file := packEntries(t)
+	rewriteZip(t, file, func(name string, data []byte) []byte {
+		if name == "audio/the-fox.mp3" {
+			return []byte("ID3 wolf")
+		}
+		return data
+	})
+
+	lib := testLibrary(t)
+	if _, err := Unpack(file, lib); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Unpack() error = %v, want a checksum mismatch", err)
+	}
+	if _, err := os.Stat(lib.StoriesDir); err == nil {
+		t.Errorf("Unpack() of a damaged bundle created %s", lib.StoriesDir)
+	}
+}
@@ -2,18 +2,27 @@ package pkg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/andrejsstepanovs/storygen/pkg/ai"
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+	"github.com/andrejsstepanovs/storygen/pkg/bundle"
+	"github.com/andrejsstepanovs/storygen/pkg/export"
 	"github.com/andrejsstepanovs/storygen/pkg/story"
 	"github.com/andrejsstepanovs/storygen/pkg/tts"
 	"github.com/andrejsstepanovs/storygen/pkg/utils"
+	"github.com/andrejsstepanovs/storygen/pkg/workspace"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -24,10 +33,7 @@ func NewCommand() (*cobra.Command, error) {
 		Short: "Generate Story",
 	}
 
-	audience := viper.GetString("STORYGEN_AUDIENCE")
-	if audience == "" {
-		audience = "Children"
-	}
+	audience := getAudience()
 	llm, err := ai.NewAI(audience)
 	if err != nil {
 		return nil, err
@@ -37,6 +43,16 @@ func NewCommand() (*cobra.Command, error) {
 		newWorkCommand(llm),
 		newTranslateCommand(llm),
 		newReadCommand(llm),
+		newRetimeCommand(),
+		newAuditionCommand(llm),
+		newReadAlongCommand(),
+		newExportCommand(),
+		newFeedCommand(),
+		newPackCommand(),
+		newUnpackCommand(),
+		newValidateCommand(),
+		newMigrateCommand(),
+		newListCommand(),
 		newWriteCommand(llm),
 		newGroomCommand(llm),
 		newStoryIdeasCommand(llm, audience),
@@ -141,7 +157,7 @@ func newStoryCompetitionCommand(llm *ai.AI) *cobra.Command {
 			log.Println("JSON saved")
 			log.Println(file)
 
-			ToVoice(llm, stories[0], file, stories[0].BuildContent(story.TextChapter, story.TextTheEnd))
+			ToVoice(llm, stories[0], file, story.TextChapter, story.TextTheEnd)
 
 			return nil
 		},
@@ -156,10 +172,10 @@ func newGroomCommand(llm *ai.AI) *cobra.Command {
 			file := args[0]
 			log.Printf("Loading story from file: %s", file)
 
-			x := &story.Story{}
-			json.Unmarshal(utils.LoadTextFromFile(file), x)
-
-			s := *x
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
 			file, s = refineStory(llm, s, 0)
 
 			log.Println("Done")
@@ -171,11 +187,11 @@ func newGroomCommand(llm *ai.AI) *cobra.Command {
 }
 
 func refineStory(llm *ai.AI, s story.Story, preReadLoops int) (string, story.Story) {
-	tmpDir := viper.GetString("STORYGEN_TMP_DIR")
+	ws := storyWorkspace(s)
 
 	preReadLoops = viper.GetInt("STORYGEN_PREREAD_LOOPS")
 	if preReadLoops == 0 {
-		file, err := utils.SaveTextToFile(tmpDir, "final_"+s.Title, "json", s.ToJson())
+		file, err := ws.SaveStory(workspace.StageFinal, s)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -267,11 +283,11 @@ func refineStory(llm *ai.AI, s story.Story, preReadLoops int) (string, story.Sto
 			}
 		}
 
-		utils.SaveTextToFile(tmpDir, strconv.Itoa(i)+"_groomed_"+s.Title, "json", s.ToJson())
+		ws.SaveStory(workspace.StageGroomed, s)
 		allAddressedSuggestions = append(allAddressedSuggestions, allSuggestions...)
 	}
 
-	file, err := utils.SaveTextToFile(tmpDir, "final_groomed_"+s.Title, "json", s.ToJson())
+	file, err := ws.SaveStory(workspace.StageFinal, s)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -287,20 +303,21 @@ func newTranslateCommand(llm *ai.AI) *cobra.Command {
 			file := args[0]
 			log.Printf("Loading story from file: %s", file)
 
-			s := &story.Story{}
-			json.Unmarshal(utils.LoadTextFromFile(file), s)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
 
-			translated := *s
+			translated := s
 			chapter := story.TextChapter
 			theEnd := story.TextTheEnd
-			toLang := strings.ToLower(viper.GetString("STORYGEN_LANGUAGE"))
+			//toLang := strings.ToLower(viper.GetString("STORYGEN_LANGUAGE"))
 			//if toLang != "english" {
 			//	log.Printf("Translating to: %s", toLang)
 			//	translated, chapter, theEnd = translate(llm, *s, toLang)
 			//}
 
-			soundFile := file[:len(file)-4] + "mp3"
-			ToVoice(llm, translated, toLang+"_"+soundFile, translated.BuildContent(chapter, theEnd))
+			ToVoice(llm, translated, file, chapter, theEnd)
 
 			return nil
 		},
@@ -315,10 +332,12 @@ func newReadCommand(llm *ai.AI) *cobra.Command {
 			file := args[0]
 			log.Printf("Loading story from file: %s", file)
 
-			s := &story.Story{}
-			json.Unmarshal(utils.LoadTextFromFile(file), s)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
 
-			translated := *s
+			translated := s
 
 			text := translated.BuildContent(story.TextChapter, story.TextTheEnd)
 			fmt.Println(text)
@@ -327,6 +346,643 @@ func newReadCommand(llm *ai.AI) *cobra.Command {
 	}
 }
 
+func newRetimeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "retime",
+		Short: "Change the speed of a narrated Story JSON (first arg) by a factor (second arg, 1.1 is 10% faster) without calling TTS",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return fmt.Errorf("usage: story retime <story.json> <speed factor>")
+			}
+			file := args[0]
+			speed, err := strconv.ParseFloat(args[1], 64)
+			if err != nil || speed <= 0 {
+				return fmt.Errorf("speed factor must be a positive number, got %q", args[1])
+			}
+
+			log.Printf("Loading story from file: %s", file)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
+			if s.Narration == nil || s.Narration.File == "" {
+				return fmt.Errorf("%s has no narration, voice the story first", file)
+			}
+
+			narration := *s.Narration
+			narration.Chunks = append([]story.NarrationChunk(nil), s.Narration.Chunks...)
+			ext := filepath.Ext(narration.File)
+			output := fmt.Sprintf("%s_x%s%s", strings.TrimSuffix(narration.File, ext), strconv.FormatFloat(speed, 'f', -1, 64), ext)
+
+			duration, err := tts.Retime(narration.File, output, speed)
+			if err != nil {
+				return err
+			}
+			narration.Retime(speed)
+			narration.File = output
+			if narration.Duration > 0 {
+				narration.Duration = roundSeconds(duration)
+			}
+			s.Narration = &narration
+
+			label := "retimed_x" + strconv.FormatFloat(speed, 'f', -1, 64)
+			if language := workspace.Language(file); language != "" {
+				label = language + "_" + label
+			}
+			saved, err := storyWorkspace(s).WriteRevision(label, s)
+			if err != nil {
+				return fmt.Errorf("failed to save narration metadata: %w", err)
+			}
+			log.Printf("Saved %s\n", saved)
+			if strings.EqualFold(ext, ".mp3") {
+				writeTags(s, output)
+			}
+			writeSubtitles(s, output)
+
+			log.Printf("Retimed narration: %s (%.2fx, speed %.2f)\n", output, speed, narration.Speed)
+			return nil
+		},
+	}
+}
+
+func newAuditionCommand(llm *ai.AI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "audition",
+		Short: "Render the first sentences of a Story JSON (first arg, optional sentence count second arg) with a grid of voices, speeds and instruction presets",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: story audition <story.json> [sentences]")
+			}
+			file := args[0]
+			sentences := 3
+			if len(args) > 1 {
+				var err error
+				sentences, err = strconv.Atoi(args[1])
+				if err != nil || sentences < 1 {
+					return fmt.Errorf("sentence count must be a positive number, got %q", args[1])
+				}
+			}
+
+			log.Printf("Loading story from file: %s", file)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
+			text := auditionText(s, sentences)
+			if text == "" {
+				return fmt.Errorf("%s has no text to audition", file)
+			}
+
+			voice := newVoice(s)
+			audition, err := getAudition(s, voice)
+			if err != nil {
+				return err
+			}
+
+			name := outputName(s, file)
+			dir := filepath.Join(strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR")), "audition_"+name)
+			log.Printf("Rendering %d samples into %s\n", len(audition.Voices)*len(audition.Speeds)*len(audition.Presets), dir)
+
+			samples, err := audition.Run(dir, text, voice, newConverter(llm))
+			// Whatever was rendered before a failure is still worth listening to
+			if indexErr := tts.WriteAuditionIndex(filepath.Join(dir, "index.md"), text, samples); indexErr != nil {
+				log.Printf("Warning: failed to write audition index: %v\n", indexErr)
+			}
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Audition ready: %s\n", filepath.Join(dir, "index.md"))
+			return nil
+		},
+	}
+}
+
+func newReadAlongCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "readalong",
+		Short: "Write a self-contained read-along HTML page for a narrated Story JSON (first arg) that highlights the sentence being read",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: story readalong <story.json>")
+			}
+			file := args[0]
+
+			log.Printf("Loading story from file: %s", file)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
+			if s.Narration == nil || s.Narration.File == "" {
+				return fmt.Errorf("%s has no narration, voice the story first", file)
+			}
+			if !s.Narration.HasTimings() {
+				return fmt.Errorf("%s narration has no chunk timings, voice it again with the native post-processing backend", file)
+			}
+
+			page, err := writeReadAlong(s, s.Narration.File)
+			if err != nil {
+				return err
+			}
+			log.Printf("Read-along page: %s\n", page)
+			return nil
+		},
+	}
+}
+
+func newExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a Story JSON (first arg) as a book: --format epub, markdown, html or pdf",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: story export <story.json> --format epub|markdown|html|pdf [--appendix] [--layout a4|letter|booklet] [--dyslexic]")
+			}
+			file := args[0]
+			format, _ := cmd.Flags().GetString("format")
+			format = strings.ToLower(strings.TrimSpace(format))
+			if format == "md" {
+				format = "markdown"
+			}
+
+			log.Printf("Loading story from file: %s", file)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
+			book := newBook(s)
+			book.Appendix, _ = cmd.Flags().GetBool("appendix")
+
+			extensions := map[string]string{"epub": ".epub", "markdown": ".md", "html": ".html", "pdf": ".pdf"}
+			if _, ok := extensions[format]; !ok {
+				return fmt.Errorf("unsupported export format %q, use epub, markdown, html or pdf", format)
+			}
+			name := outputName(s, file)
+			target := filepath.Join(strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR")), name+extensions[format])
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+
+			switch format {
+			case "epub":
+				err = export.WriteEPUB(target, book)
+			case "markdown":
+				err = os.WriteFile(target, []byte(export.Markdown(book, filepath.Dir(target))), 0644)
+			case "html":
+				var page []byte
+				if page, err = export.HTML(book); err == nil {
+					err = os.WriteFile(target, page, 0644)
+				}
+			case "pdf":
+				opts := export.PDFOptions{}
+				layout, _ := cmd.Flags().GetString("layout")
+				if opts.Layout, err = export.FindPageLayout(layout); err != nil {
+					return err
+				}
+				opts.Dyslexic, _ = cmd.Flags().GetBool("dyslexic")
+				err = export.WritePDF(target, book, opts)
+			}
+			if err != nil {
+				return err
+			}
+			log.Printf("Exported %s\n", target)
+			return nil
+		},
+	}
+	cmd.Flags().String("format", "epub", "book format: epub, markdown, html or pdf")
+	cmd.Flags().Bool("appendix", false, `add an "about this story" appendix with structure, time period, protagonists and villain`)
+	cmd.Flags().String("layout", "a4", "pdf page layout: a4, letter, a5, half-letter or booklet (a5)")
+	cmd.Flags().Bool("dyslexic", false, "pdf with large, dyslexia-friendly typography")
+	return cmd
+}
+
+func newFeedCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "feed",
+		Short: "Write or update a podcast RSS feed (STORYGEN_TARGET_DIR/feed.xml) from the narrated stories",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			targetDir := strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR"))
+			channel := export.FeedChannel{
+				Title:       viper.GetString("STORYGEN_FEED_TITLE"),
+				Description: viper.GetString("STORYGEN_FEED_DESCRIPTION"),
+				Author:      viper.GetString("STORYGEN_FEED_AUTHOR"),
+				Image:       viper.GetString("STORYGEN_FEED_IMAGE"),
+				Category:    viper.GetString("STORYGEN_FEED_CATEGORY"),
+				Language:    utils.FindLanguage(getLanguage()),
+				BaseURL:     viper.GetString("STORYGEN_FEED_BASE_URL"),
+			}
+			if channel.Title == "" {
+				channel.Title = "Bedtime stories"
+			}
+			if channel.Description == "" {
+				channel.Description = fmt.Sprintf("Stories for %s, written and narrated by storygen.", strings.ToLower(getAudience()))
+			}
+
+			episodes, err := feedEpisodes(targetDir, channel.Language)
+			if err != nil {
+				return err
+			}
+			file := filepath.Join(targetDir, "feed.xml")
+			added, err := export.UpdateFeed(file, channel, episodes)
+			if err != nil {
+				return err
+			}
+			log.Printf("Feed %s: %d episodes, %d new\n", file, len(episodes), added)
+			return nil
+		},
+	}
+}
+
+func newPackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack",
+		Short: "Pack a Story JSON (first arg) with its translations, audio, subtitles and images into a .storygen bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: story pack <story.json> [--chapter-audio]")
+			}
+			file := args[0]
+			log.Printf("Loading story from file: %s", file)
+			s, err := loadStory(file)
+			if err != nil {
+				return err
+			}
+
+			src := bundle.Sources{
+				Cover:         viper.GetString("STORYGEN_COVER_IMAGE"),
+				Illustrations: export.FindIllustrations(viper.GetString("STORYGEN_ILLUSTRATIONS_DIR")),
+			}
+			src.ChapterAudio, _ = cmd.Flags().GetBool("chapter-audio")
+			if ws, ok := workspace.For(file); ok && workspace.Language(file) == "" {
+				src.Translations = ws.Translations()
+			}
+			entries, err := bundle.Collect(file, s, src)
+			if err != nil {
+				return err
+			}
+
+			name := outputName(s, file)
+			target := filepath.Join(strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR")), name+bundle.Extension)
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			manifest, err := bundle.Pack(target, s.Title, entries)
+			if err != nil {
+				return err
+			}
+			log.Printf("Packed %d files into %s\n", len(manifest.Files), target)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("chapter-audio", false, "also bundle one audio file per chapter")
+	return cmd
+}
+
+func newUnpackCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unpack",
+		Short: "Import a .storygen bundle (first arg) into a story workspace in STORYGEN_STORIES_DIR, audio into STORYGEN_TARGET_DIR",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: story unpack <file.storygen>")
+			}
+			written, err := bundle.Unpack(args[0], bundle.Library{
+				StoriesDir: getStoriesDir(),
+				AudioDir:   strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR")),
+			})
+			for _, f := range written {
+				log.Printf("Unpacked %s\n", f)
+			}
+			return err
+		},
+	}
+}
+
+func newValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check Story JSON files (args) against the story schema, --migrate rewrites older versions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if printSchema, _ := cmd.Flags().GetBool("schema"); printSchema {
+				fmt.Print(string(story.JSONSchema))
+				return nil
+			}
+			if len(args) < 1 {
+				return fmt.Errorf("usage: story validate <story.json>... [--migrate] | story validate --schema")
+			}
+			migrate, _ := cmd.Flags().GetBool("migrate")
+
+			invalid := 0
+			for _, file := range args {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				_, version, err := story.Migrate(data)
+				var s story.Story
+				if err == nil {
+					s, err = story.Parse(data)
+				}
+				if err != nil {
+					invalid++
+					var problems *story.ValidationError
+					if errors.As(err, &problems) {
+						log.Printf("%s: invalid story\n", file)
+						for _, p := range problems.Problems {
+							log.Printf("  - %s\n", p)
+						}
+						continue
+					}
+					log.Printf("%s: %v\n", file, err)
+					continue
+				}
+				if version == story.SchemaVersion {
+					log.Printf("%s: valid, version %d\n", file, version)
+					continue
+				}
+				if !migrate {
+					log.Printf("%s: valid, version %d, run with --migrate to upgrade it to version %d\n", file, version, story.SchemaVersion)
+					continue
+				}
+				if id, ok := workspace.LegacyOriginalID(file); ok {
+					s.ID = id
+				}
+				if err := os.WriteFile(file, []byte(s.ToJson()), 0644); err != nil {
+					return err
+				}
+				log.Printf("%s: migrated from version %d to %d\n", file, version, story.SchemaVersion)
+			}
+			if invalid > 0 {
+				return fmt.Errorf("%d of %d files are invalid", invalid, len(args))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool("migrate", false, "rewrite valid files of older versions in the current version")
+	cmd.Flags().Bool("schema", false, "print the story JSON Schema")
+	return cmd
+}
+
+func newMigrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Move story JSONs named by title in STORYGEN_TMP_DIR, and chunk audio left in STORYGEN_TARGET_DIR, into story workspaces",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			migrations, problems := workspace.MigrateLegacy(
+				getStoriesDir(),
+				viper.GetString("STORYGEN_TMP_DIR"),
+				strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR")),
+			)
+			for _, m := range migrations {
+				log.Printf("%s %q: %d files moved\n", m.Workspace.Dir, m.Workspace.State.Title, len(m.Files))
+				for _, f := range m.Files {
+					log.Printf("  - %s\n", f)
+				}
+			}
+			for _, err := range problems {
+				log.Printf("Not migrated: %v\n", err)
+			}
+			if len(migrations) == 0 && len(problems) == 0 {
+				log.Println("Nothing to migrate")
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%d files or stories were not migrated, fix them and run migrate again", len(problems))
+			}
+			return nil
+		},
+	}
+}
+
+func newListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the story workspaces in STORYGEN_STORIES_DIR",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			workspaces, err := workspace.List(getStoriesDir())
+			if err != nil {
+				return err
+			}
+			for _, ws := range workspaces {
+				translations := ""
+				if len(ws.State.Translations) > 0 {
+					translations = " [" + strings.Join(ws.State.Translations, ", ") + "]"
+				}
+				fmt.Printf("%s  %-10s  %s  %s%s\n", ws.State.ID, ws.State.Stage, ws.State.Updated.Local().Format("2006-01-02 15:04"), ws.State.Title, translations)
+			}
+			return nil
+		},
+	}
+}
+
+// feedEpisodes finds the narrated stories and translations in the story workspaces whose audio
+// is in the feed directory and writes their chapter files.
+func feedEpisodes(feedDir string, language utils.Language) ([]export.FeedEpisode, error) {
+	workspaces, err := workspace.List(getStoriesDir())
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, ws := range workspaces {
+		files = append(files, ws.StoryFiles()...)
+	}
+	absDir, err := filepath.Abs(feedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make([]export.FeedEpisode, 0)
+	seen := make(map[string]bool)
+	for _, file := range files {
+		s, err := loadStory(file)
+		if err != nil {
+			log.Printf("Warning: skipping %v\n", err)
+			continue
+		}
+		if s.Narration == nil || s.Narration.File == "" {
+			continue
+		}
+		info, err := os.Stat(s.Narration.File)
+		if err != nil {
+			continue
+		}
+		absAudio, err := filepath.Abs(s.Narration.File)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absDir, absAudio)
+		if err != nil || strings.HasPrefix(rel, "..") || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		episode := export.FeedEpisode{
+			Story:    s,
+			Audio:    rel,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			Duration: time.Duration(s.Narration.Duration * float64(time.Second)),
+			Language: language,
+		}
+		if l, known := utils.LookupLanguage(workspace.Language(file)); known {
+			episode.Language = l
+		}
+		if episode.Duration == 0 {
+			if pcm, err := audio.DecodeFile(s.Narration.File); err == nil {
+				episode.Duration = pcm.Duration()
+			}
+		}
+
+		base := strings.TrimSuffix(rel, filepath.Ext(rel))
+		if s.Narration.HasTimings() {
+			if err := export.WriteChapters(filepath.Join(feedDir, base+".chapters.json"), s); err != nil {
+				log.Printf("Warning: failed to write chapters for %s: %v\n", rel, err)
+			} else {
+				episode.Chapters = base + ".chapters.json"
+			}
+		}
+		for _, ext := range []string{".vtt", ".srt"} {
+			if _, err := os.Stat(filepath.Join(feedDir, base+ext)); err == nil {
+				episode.Transcripts = append(episode.Transcripts, base+ext)
+			}
+		}
+		episodes = append(episodes, episode)
+	}
+	return episodes, nil
+}
+
+// newBook collects the cover, illustrations and narration timings the book exporters use.
+func newBook(s story.Story) export.Book {
+	language := utils.FindLanguage(getLanguage())
+	book := export.Book{
+		Story:         s,
+		Language:      language,
+		Cover:         viper.GetString("STORYGEN_COVER_IMAGE"),
+		Illustrations: export.FindIllustrations(viper.GetString("STORYGEN_ILLUSTRATIONS_DIR")),
+	}
+	if s.Narration == nil || !s.Narration.HasTimings() {
+		return book
+	}
+	if _, err := os.Stat(s.Narration.File); err != nil {
+		log.Printf("Warning: narration %s not found, exporting without audio\n", s.Narration.File)
+		return book
+	}
+
+	segmenter := tts.NewSegmenter(language)
+	timed := export.AlignStory(s, narrationTimings(s, segmenter), segmenter)
+	timed.Language = language.ISO1
+	book.Timed, book.Audio = &timed, s.Narration.File
+	return book
+}
+
+// narrationTimings prefers word timings written with the subtitles, they may come from a transcription.
+func narrationTimings(s story.Story, segmenter *tts.Segmenter) story.Timings {
+	base := strings.TrimSuffix(s.Narration.File, filepath.Ext(s.Narration.File))
+	var timings story.Timings
+	if data, err := os.ReadFile(base + ".timings.json"); err == nil && json.Unmarshal(data, &timings) == nil && len(timings.Chunks) > 0 {
+		log.Printf("Using word timings from %s.timings.json\n", base)
+		return timings
+	}
+	return tts.BuildTimings(s.Narration, segmenter)
+}
+
+// writeReadAlong writes <audio>.html next to the audio file.
+func writeReadAlong(s story.Story, audioFile string) (string, error) {
+	base := strings.TrimSuffix(audioFile, filepath.Ext(audioFile))
+	language := utils.FindLanguage(getLanguage())
+	segmenter := tts.NewSegmenter(language)
+
+	timed := export.AlignStory(s, narrationTimings(s, segmenter), segmenter)
+	timed.Language = language.ISO1
+	page, err := export.ReadAlong(timed, audioFile)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".html", page, 0644); err != nil {
+		return "", fmt.Errorf("failed to write read-along page: %w", err)
+	}
+	return base + ".html", nil
+}
+
+// auditionText returns the first sentences of the story, normalized like the narration.
+func auditionText(s story.Story, count int) string {
+	segmenter := tts.NewSegmenter(utils.FindLanguage(getLanguage()))
+	sentences := make([]string, 0, count)
+	for _, c := range s.Chapters {
+		for _, sentence := range segmenter.Sentences(c.Body()) {
+			if len(sentences) == count {
+				break
+			}
+			sentences = append(sentences, sentence)
+		}
+	}
+	return getNormalizer().Normalize(strings.Join(sentences, " "))
+}
+
+// getAudition builds the grid from STORYGEN_AUDITION_VOICES, STORYGEN_AUDITION_SPEEDS and STORYGEN_AUDITION_PRESETS.
+// Without voices the narrator and two more catalog voices are auditioned.
+func getAudition(s story.Story, voice story.Voice) (tts.Audition, error) {
+	audition := tts.Audition{
+		Voices: splitList(viper.GetString("STORYGEN_AUDITION_VOICES")),
+		Speeds: []float64{0.8, voice.Provider.Speed, 1},
+	}
+
+	if len(audition.Voices) == 0 {
+		catalog, provider := getCatalog()
+		audition.Voices = append(audition.Voices, castStory(s).Narrator)
+		for _, v := range tts.ProviderVoices(catalog, provider) {
+			if len(audition.Voices) == 3 {
+				break
+			}
+			if v.Name != audition.Voices[0] {
+				audition.Voices = append(audition.Voices, v.Name)
+			}
+		}
+	}
+
+	if speeds := splitList(viper.GetString("STORYGEN_AUDITION_SPEEDS")); len(speeds) > 0 {
+		audition.Speeds = make([]float64, 0, len(speeds))
+		for _, speed := range speeds {
+			value, err := strconv.ParseFloat(speed, 64)
+			if err != nil || value <= 0 {
+				return audition, fmt.Errorf("invalid STORYGEN_AUDITION_SPEEDS value %q", speed)
+			}
+			audition.Speeds = append(audition.Speeds, value)
+		}
+	}
+	sort.Float64s(audition.Speeds)
+	audition.Speeds = slices.Compact(audition.Speeds)
+
+	presets := splitList(viper.GetString("STORYGEN_AUDITION_PRESETS"))
+	if len(presets) == 0 {
+		presets = []string{"current", "calm", "lively"}
+	}
+	var err error
+	audition.Presets, err = tts.FindPresets(presets, voice.Instruction)
+	if err != nil {
+		return audition, err
+	}
+
+	// Models without instructions ignore speed and presets, stretch the speeds locally instead
+	if !ai.TTSSupportsInstructions(ai.TTSModel()) {
+		log.Printf("%s does not take voice instructions, auditioning only the current preset\n", ai.TTSModel())
+		audition.Presets, _ = tts.FindPresets([]string{"current"}, voice.Instruction)
+		audition.Stretch = true
+	}
+
+	return audition, nil
+}
+
+// splitList splits a comma separated setting, skipping empty values.
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func newWriteCommand(llm *ai.AI) *cobra.Command {
 	return &cobra.Command{
 		Use:   "write",
@@ -337,8 +993,7 @@ func newWriteCommand(llm *ai.AI) *cobra.Command {
 			suggestion := strings.Join(args, " ")
 			s := buildStory(llm, suggestion)
 
-			tmpDir := viper.GetString("STORYGEN_TMP_DIR")
-			file, err := utils.SaveTextToFile(tmpDir, s.Title, "json", s.ToJson())
+			file, err := storyWorkspace(s).SaveStory(workspace.StageWritten, s)
 			if err != nil {
 				return err
 			}
@@ -359,55 +1014,137 @@ func newWorkCommand(llm *ai.AI) *cobra.Command {
 			suggestion := strings.Join(args, " ")
 			s := buildStory(llm, suggestion)
 
-			tmpDir := viper.GetString("STORYGEN_TMP_DIR")
-			file, err := utils.SaveTextToFile(tmpDir, s.Title, "json", s.ToJson())
+			_, err := storyWorkspace(s).SaveStory(workspace.StageWritten, s)
 			if err != nil {
 				return err
 			}
 			log.Println("JSON saved")
 
-			file, s = refineStory(llm, s, 0)
+			file, s := refineStory(llm, s, 0)
 
-			toLang := strings.ToLower(viper.GetString("STORYGEN_LANGUAGE"))
-			if toLang == "" {
-				toLang = "english"
-			}
+			toLang := getLanguage()
 
 			//_ = file
 			chapter := story.TextChapter
 			theEnd := story.TextTheEnd
 			if toLang != "english" {
-				title := s.Title
 				s, chapter, theEnd = translate(llm, s, toLang)
-				_, err = utils.SaveTextToFile(tmpDir, toLang+"_"+title, "json", s.ToJson())
+				file, err = storyWorkspace(s).SaveTranslation(toLang, s)
 				if err != nil {
 					return err
 				}
 				log.Println(toLang, " JSON saved")
-				file = toLang + "_" + file
 			}
-			ToVoice(llm, s, file, s.BuildContent(chapter, theEnd))
+			ToVoice(llm, s, file, chapter, theEnd)
 
 			return err
 		},
 	}
 }
 
-func ToVoice(llm *ai.AI, s story.Story, file, content string) {
-	soundFile := file + ".mp3"
-	lastDot := strings.LastIndex(file, ".")
-	if lastDot >= 0 {
-		soundFile = file[:lastDot] + ".mp3"
+// ToVoice narrates the story with the chapter headers and ending in its language.
+func ToVoice(llm *ai.AI, s story.Story, file, chapter, theEnd string) {
+	format, err := audio.ParseFormat(viper.GetString("STORYGEN_AUDIO_FORMAT"))
+	if err != nil {
+		log.Fatalln(err)
 	}
+	ws, language := storyWorkspace(s), workspace.Language(file)
+	soundFile := outputName(s, file) + format.Extension()
 	targetDir := strings.ToLower(viper.GetString("STORYGEN_TARGET_DIR"))
 	log.Println("Text to Speech...")
 
+	voice := newVoice(s)
+	ttsConverter := newConverter(llm)
+
+	opts := tts.Options{
+		SplitLen:    viper.GetInt("STORYGEN_TTS_SPLITLEN"),
+		PostProcess: viper.GetBool("STORYGEN_TTS_POSTPROCESS"),
+		Backend:     strings.ToLower(viper.GetString("STORYGEN_TTS_POSTPROCESS_BACKEND")),
+		Cleanup:     tts.DefaultCleanup(),
+	}
+	opts.Normalize, opts.LoudnessTarget, opts.TruePeakLimit = getLoudnessTarget()
+	opts.QA = getQA(llm)
+	opts.Sounds = getSounds()
+	opts.Sleep = getSleep()
+	opts.Normalizer = getNormalizer()
+	opts.Segmenter = tts.NewSegmenter(utils.FindLanguage(getLanguage()))
+	opts.Markup = getMarkup()
+	opts.Format = format
+	opts.ChunkDir = ws.Path(workspace.AudioDir)
+	opts.ChapterLabel = chapter
+	if viper.GetBool("STORYGEN_SUBTITLES_TRANSCRIBE") {
+		opts.Transcribe = llm.SpeechToText
+	}
+	if s.Casting == nil {
+		s.Casting = workspaceCasting(ws)
+	}
+	s.Casting = castStory(s)
+	voice.Provider.Voice = s.Casting.Narrator
+	if viper.GetBool("STORYGEN_FULL_CAST") {
+		opts.Cast = tts.NewCast(s.Casting)
+	}
+	if viper.GetBool("STORYGEN_VOICE_MOODS") {
+		if ai.TTSSupportsInstructions(ai.TTSModel()) {
+			opts.Annotate = llm.AnnotateChunks
+		} else {
+			log.Printf("Warning: %s does not take voice instructions, skipping chunk moods\n", ai.TTSModel())
+		}
+	}
+	if threshold := viper.GetFloat64("STORYGEN_SILENCE_THRESHOLD"); threshold != 0 {
+		opts.Cleanup.SilenceThreshold = threshold
+	}
+	if maxSilence := viper.GetDuration("STORYGEN_SILENCE_MAX"); maxSilence > 0 {
+		opts.Cleanup.MaxSilence = maxSilence
+	}
+
+	result, err := tts.TextToSpeech(targetDir, soundFile, s.BuildNarration(chapter, theEnd), voice, opts, ttsConverter)
+	if err != nil {
+		log.Printf("Error during Text to Speech: %v\n", err)
+		log.Fatalln(err)
+	}
+	finalSoundFile := result.File
+
+	s.Narration = newNarration(result, voice)
+	if _, err = ws.SaveNarration(language, s); err != nil {
+		log.Printf("Warning: failed to save narration metadata: %v\n", err)
+	}
+	if transcript, ok := narrationTranscript(s.Narration); ok {
+		if _, err := ws.SaveTranscript(language, transcript); err != nil {
+			log.Printf("Warning: failed to save transcript: %v\n", err)
+		}
+	}
+
+	if format == audio.FormatMP3 {
+		writeTags(s, finalSoundFile)
+	}
+	writeSubtitles(s, finalSoundFile)
+	if viper.GetBool("STORYGEN_READALONG") && s.Narration.HasTimings() {
+		if page, err := writeReadAlong(s, finalSoundFile); err != nil {
+			log.Printf("Warning: failed to write read-along page: %v\n", err)
+		} else {
+			log.Printf("read-along: %s\n", page)
+		}
+	}
+
+	log.Println("Success!")
+	log.Println("")
+	log.Printf("Story: %s\n", s.Title)
+	log.Printf("Summary: %s\n\n", s.Summary)
+	log.Printf("json: %s\n", file)
+	log.Printf("%s: %s\n", format, finalSoundFile)
+	if s.Narration.Loudness != nil {
+		log.Printf("loudness: %.1f LUFS, true peak %.1f dBTP\n", s.Narration.Loudness.IntegratedLUFS, s.Narration.Loudness.TruePeakDBTP)
+	}
+}
+
+// newVoice builds the narrator voice from the STORYGEN_VOICE* settings.
+func newVoice(s story.Story) story.Voice {
 	speed := viper.GetFloat64("STORYGEN_SPEECH_SPEED")
 	if speed == 0 {
 		speed = 0.9
 	}
 
-	voice := story.Voice{
+	return story.Voice{
 		Provider: story.VoiceProvider{
 			Provider: "litellm",
 			Voice:    viper.GetString("STORYGEN_VOICE"),
@@ -422,33 +1159,352 @@ func ToVoice(llm *ai.AI, s story.Story, file, content string) {
 			Story:   s,
 		},
 	}
+}
 
-	// Create TTS converter using AI client
-	ttsConverter := &tts.LiteLLMAdapter{
+// newConverter creates the TTS converter using the AI client.
+func newConverter(llm *ai.AI) *tts.LiteLLMAdapter {
+	return &tts.LiteLLMAdapter{
 		TextToSpeechFunc: llm.TextToSpeech,
 		MaxRetries:       3,
 		RetryDelay:       2 * time.Second,
 		RetryMultiplier:  1.5,
 	}
+}
+
+// writeTags writes ID3 tags with the narration chapter markers into an mp3 story file.
+func writeTags(s story.Story, file string) {
+	tags := tts.Tags{
+		Title:    s.Title,
+		Comment:  s.Summary,
+		Genre:    getAudience(),
+		Language: getLanguage(),
+		Date:     time.Now(),
+		Cover:    viper.GetString("STORYGEN_COVER_IMAGE"),
+	}
+	if s.Narration != nil {
+		tags.Artist = s.Narration.Voice
+		for _, m := range s.Narration.ChapterMarkers() {
+			title := fmt.Sprintf("%s %d", story.TextChapter, m.Chapter)
+			if m.Chapter <= len(s.Chapters) && s.Chapters[m.Chapter-1].Title != "" {
+				title = s.Chapters[m.Chapter-1].Title
+			}
+			tags.Chapters = append(tags.Chapters, tts.ChapterTag{
+				Title: title,
+				Start: time.Duration(m.Start * float64(time.Second)),
+				End:   time.Duration(m.End * float64(time.Second)),
+			})
+		}
+	}
 
-	postProcess := viper.GetBool("STORYGEN_TTS_POSTPROCESS")
-	splitLen := viper.GetInt("STORYGEN_TTS_SPLITLEN")
-	finalSoundFile, err := tts.TextToSpeech(targetDir, soundFile, content, voice, splitLen, postProcess, ttsConverter)
+	err := tts.WriteTags(file, tags)
+	if err != nil {
+		log.Printf("Warning: failed to write ID3 tags: %v\n", err)
+	}
+}
+
+// castStory maps story characters to voices from STORYGEN_VOICE_CATALOG (JSON file, defaults to
+// the built-in catalog) for STORYGEN_VOICE_PROVIDER (guessed from STORYGEN_TTS_MODEL when empty).
+func castStory(s story.Story) *story.Casting {
+	catalog, provider := getCatalog()
+	return tts.CastStory(s, provider, viper.GetString("STORYGEN_VOICE"), catalog)
+}
+
+// workspaceCasting returns the casting the story or one of its translations was voiced with,
+// so every language of a story is read by the same voices.
+func workspaceCasting(ws *workspace.Workspace) *story.Casting {
+	for _, file := range ws.StoryFiles() {
+		if s, err := story.Load(file); err == nil && s.Casting != nil {
+			return s.Casting
+		}
+	}
+	return nil
+}
+
+func getCatalog() ([]tts.CatalogVoice, string) {
+	catalog := tts.DefaultCatalog()
+	if file := viper.GetString("STORYGEN_VOICE_CATALOG"); file != "" {
+		var err error
+		catalog, err = tts.LoadCatalog(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	provider := strings.ToLower(viper.GetString("STORYGEN_VOICE_PROVIDER"))
+	if provider == "" {
+		provider = tts.GuessProvider(ai.TTSModel())
+	}
+
+	return catalog, provider
+}
+
+func newNarration(result *tts.Result, voice story.Voice) *story.Narration {
+	narration := &story.Narration{
+		File:     result.File,
+		Voice:    voice.Provider.Voice,
+		Speed:    voice.Provider.Speed,
+		Loudness: toStoryLoudness(result.Loudness),
+		Duration: roundSeconds(result.Duration),
+		Chunks:   make([]story.NarrationChunk, 0, len(result.Chunks)),
+	}
+	for _, c := range result.Chunks {
+		words := make([]story.TranscriptWord, 0, len(c.Words))
+		for _, w := range c.Words {
+			w.Start = math.Round((w.Start+c.Start.Seconds())*1000) / 1000
+			w.End = math.Round((w.End+c.Start.Seconds())*1000) / 1000
+			words = append(words, w)
+		}
+		narration.Chunks = append(narration.Chunks, story.NarrationChunk{
+			Chapter:  c.Chapter,
+			Index:    c.Index,
+			Speaker:  c.Speaker,
+			Text:     c.Text,
+			Mood:     c.Mood,
+			Loudness: toStoryLoudness(c.Loudness),
+			Attempts: c.Attempts,
+			Problems: c.Problems,
+			Start:    roundSeconds(c.Start),
+			End:      roundSeconds(c.End),
+			Words:    words,
+		})
+	}
+	return narration
+}
+
+// writeSubtitles writes SRT, WebVTT and word timings next to the audio file, unless STORYGEN_SUBTITLES is false.
+func writeSubtitles(s story.Story, audioFile string) {
+	if viper.IsSet("STORYGEN_SUBTITLES") && !viper.GetBool("STORYGEN_SUBTITLES") {
+		return
+	}
+	if s.Narration == nil || !s.Narration.HasTimings() {
+		log.Println("Warning: narration has no chunk timings, skipping subtitles")
+		return
+	}
+
+	timings := tts.BuildTimings(s.Narration, tts.NewSegmenter(utils.FindLanguage(getLanguage())))
+	timings.Audio = filepath.Base(audioFile)
+	files, err := tts.WriteSubtitles(strings.TrimSuffix(audioFile, filepath.Ext(audioFile)), timings)
+	if err != nil {
+		log.Printf("Warning: failed to write subtitles: %v\n", err)
+	}
+	for _, f := range files {
+		log.Printf("subtitles: %s\n", f)
+	}
+}
+
+// roundSeconds keeps millisecond precision for the story json.
+func roundSeconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
+
+func toStoryLoudness(l *audio.Loudness) *story.Loudness {
+	if l == nil {
+		return nil
+	}
+	return &story.Loudness{
+		IntegratedLUFS: math.Round(l.Integrated*10) / 10,
+		TruePeakDBTP:   math.Round(l.TruePeak*10) / 10,
+	}
+}
+
+func getQA(llm *ai.AI) tts.QA {
+	qa := tts.DefaultQA()
+	qa.Enabled = viper.GetBool("STORYGEN_QA")
+	if wpm := viper.GetFloat64("STORYGEN_QA_WPM"); wpm > 0 {
+		qa.WordsPerMinute = wpm
+	}
+	if viper.IsSet("STORYGEN_QA_MAX_REGENERATIONS") {
+		qa.MaxRegenerations = viper.GetInt("STORYGEN_QA_MAX_REGENERATIONS")
+	}
+	if maxWER := viper.GetFloat64("STORYGEN_QA_MAX_WER"); maxWER > 0 {
+		qa.MaxWER = maxWER
+		qa.Transcribe = llm.SpeechToText
+	}
+	return qa
+}
+
+func getSoundDir() string {
+	dir := viper.GetString("STORYGEN_SOUND_DIR")
+	if dir == "" {
+		dir = "sounds"
+	}
+	return dir
+}
+
+func getSounds() tts.Sounds {
+	sounds := tts.NewSounds(
+		getSoundDir(),
+		viper.GetString("STORYGEN_SOUND_INTRO"),
+		viper.GetString("STORYGEN_SOUND_OUTRO"),
+		viper.GetString("STORYGEN_SOUND_TRANSITION"),
+		viper.GetString("STORYGEN_SOUND_BED"),
+	)
+	if viper.IsSet("STORYGEN_SOUND_BED_VOLUME") {
+		sounds.Ducking.Volume = viper.GetFloat64("STORYGEN_SOUND_BED_VOLUME")
+	}
+	if viper.IsSet("STORYGEN_SOUND_BED_DUCKING") {
+		sounds.Ducking.Depth = viper.GetFloat64("STORYGEN_SOUND_BED_DUCKING")
+	}
+	return sounds
+}
+
+// getNormalizer prepares text normalization for STORYGEN_LANGUAGE, on unless STORYGEN_TTS_NORMALIZE is false.
+func getNormalizer() *tts.Normalizer {
+	if viper.IsSet("STORYGEN_TTS_NORMALIZE") && !viper.GetBool("STORYGEN_TTS_NORMALIZE") {
+		return nil
+	}
+
+	lexicon := make(map[string]string)
+	if file := viper.GetString("STORYGEN_LEXICON"); file != "" {
+		var err error
+		lexicon, err = tts.LoadLexicon(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	return tts.NewNormalizer(utils.FindLanguage(getLanguage()), lexicon)
+}
+
+// getMarkup picks how pauses, emphasis and headings reach the TTS model, STORYGEN_TTS_MARKUP overrides the guess.
+func getMarkup() string {
+	switch markup := strings.ToLower(viper.GetString("STORYGEN_TTS_MARKUP")); markup {
+	case tts.MarkupSSML, tts.MarkupInstructions, tts.MarkupSilence:
+		return markup
+	case "":
+	default:
+		log.Printf("Warning: unknown STORYGEN_TTS_MARKUP %q, guessing from the TTS model\n", markup)
+	}
+
+	if ai.TTSSupportsInstructions(ai.TTSModel()) {
+		return tts.MarkupInstructions
+	}
+	return tts.MarkupSilence
+}
+
+func getSleep() tts.Sleep {
+	sleep := tts.DefaultSleep()
+	sleep.Enabled = viper.GetBool("STORYGEN_SLEEP_MODE")
+	if viper.IsSet("STORYGEN_SLEEP_CHAPTERS") {
+		sleep.Chapters = viper.GetInt("STORYGEN_SLEEP_CHAPTERS")
+	}
+	if minSpeed := viper.GetFloat64("STORYGEN_SLEEP_MIN_SPEED"); minSpeed > 0 {
+		sleep.MinSpeed = minSpeed
+	}
+	if viper.IsSet("STORYGEN_SLEEP_FADE_OUT") {
+		sleep.FadeOut = viper.GetDuration("STORYGEN_SLEEP_FADE_OUT")
+	}
+	sleep.Tail = tts.ResolveSound(getSoundDir(), viper.GetString("STORYGEN_SLEEP_TAIL"))
+	if tailLength := viper.GetDuration("STORYGEN_SLEEP_TAIL_LENGTH"); tailLength > 0 {
+		sleep.TailLength = tailLength
+	}
+	if viper.IsSet("STORYGEN_SLEEP_TAIL_VOLUME") {
+		sleep.TailVolume = viper.GetFloat64("STORYGEN_SLEEP_TAIL_VOLUME")
+	}
+	return sleep
+}
+
+// getLoudnessTarget reads STORYGEN_LOUDNESS_TARGET (LUFS, default -16, "off" disables normalization)
+// and STORYGEN_TRUE_PEAK (dBTP, default -1.5).
+func getLoudnessTarget() (bool, float64, float64) {
+	target := -16.0
+	peak := -1.5
+
+	setting := strings.ToLower(strings.TrimSpace(viper.GetString("STORYGEN_LOUDNESS_TARGET")))
+	if setting == "off" || setting == "false" {
+		return false, target, peak
+	}
+	if setting != "" {
+		value, err := strconv.ParseFloat(setting, 64)
+		if err != nil {
+			log.Fatalf("invalid STORYGEN_LOUDNESS_TARGET %q: %v", setting, err)
+		}
+		target = value
+	}
+	if value := viper.GetFloat64("STORYGEN_TRUE_PEAK"); value != 0 {
+		peak = value
+	}
+
+	return true, target, peak
+}
+
+func getAudience() string {
+	audience := viper.GetString("STORYGEN_AUDIENCE")
+	if audience == "" {
+		audience = "Children"
+	}
+	return audience
+}
+
+// getStoriesDir is where the story workspaces are, one directory per story ID.
+func getStoriesDir() string {
+	dir := viper.GetString("STORYGEN_STORIES_DIR")
+	if dir == "" {
+		dir = "stories"
+	}
+	return dir
+}
+
+// loadStory reads a story file. Translations of the flat layout go with the original they were
+// translated from, their own texts would give them an ID of their own.
+func loadStory(file string) (story.Story, error) {
+	s, err := story.Load(file)
+	if err != nil {
+		return s, err
+	}
+	if id, ok := workspace.LegacyOriginalID(file); ok {
+		s.ID = id
+	}
+	return s, nil
+}
+
+// storyWorkspace opens the workspace of the story, creating it for new and imported stories.
+func storyWorkspace(s story.Story) *workspace.Workspace {
+	ws, err := workspace.Open(getStoriesDir(), s.ID)
 	if err != nil {
-		log.Printf("Error during Text to Speech: %v\n", err)
 		log.Fatalln(err)
 	}
+	return ws
+}
 
-	log.Println("Success!")
-	log.Println("")
-	log.Printf("Story: %s\n", s.Title)
-	log.Printf("Summary: %s\n\n", s.Summary)
-	log.Printf("json: %s\n", file)
-	log.Printf("mp3: %s\n", finalSoundFile)
+// outputName names the files made from a story in STORYGEN_TARGET_DIR after the transliterated title,
+// the story ID keeps stories with the same title apart and names titles nothing is left of.
+// Translations are prefixed with their language.
+func outputName(s story.Story, file string) string {
+	language := workspace.Language(file)
+	name := s.ID
+	if title := utils.Slug(s.Title, language, ""); title != "" {
+		name = title + "_" + s.ID
+	}
+	if language != "" {
+		name = language + "_" + name
+	}
+	return name
+}
+
+// narrationTranscript collects the words the narration chunks were transcribed to.
+func narrationTranscript(n *story.Narration) (story.Transcript, bool) {
+	words := make([]story.TranscriptWord, 0)
+	for _, c := range n.Chunks {
+		words = append(words, c.Words...)
+	}
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.Word
+	}
+	return story.Transcript{Text: strings.Join(texts, " "), Words: words}, len(words) > 0
+}
+
+func getLanguage() string {
+	toLang := strings.ToLower(viper.GetString("STORYGEN_LANGUAGE"))
+	if toLang == "" {
+		toLang = "english"
+	}
+	return toLang
 }
 
 func translate(llm *ai.AI, s story.Story, toLang string) (story.Story, string, string) {
-	translated := story.Story{}
+	translated := s.Translation()
 
 	log.Printf("Translating Title %s ...\n", s.Title)
 	translated.Title = llm.TranslateText(s.Title, toLang)
@@ -562,13 +1618,17 @@ func buildStory(llm *ai.AI, suggestion string) story.Story {
 }
 
 func compareStories(llm *ai.AI, storyAFile, storyBFile string) story.Story {
-	storyA := &story.Story{}
-	storyB := &story.Story{}
-	json.Unmarshal(utils.LoadTextFromFile(storyAFile), storyA)
-	json.Unmarshal(utils.LoadTextFromFile(storyBFile), storyB)
+	storyA, err := story.Load(storyAFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	storyB, err := story.Load(storyBFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	log.Printf("StoryA: %q\n", storyA.Title)
 	log.Printf("StoryB: %q\n", storyB.Title)
 
-	return llm.CompareStories(*storyA, *storyB)
+	return llm.CompareStories(storyA, storyB)
 }
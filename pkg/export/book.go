@@ -0,0 +1,136 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Book is a story with everything the book exporters lay out around its text.
+type Book struct {
+	Story    story.Story
+	Language utils.Language
+	// Cover is an optional JPEG, PNG, GIF, WebP or SVG image
+	Cover string
+	// Illustrations maps chapter numbers to images shown at the start of the chapter
+	Illustrations map[int]string
+	// Timed is the story text placed on the narration timeline, nil for stories without narration
+	Timed *TimedStory
+	// Audio is the narration file read aloud with the timed text
+	Audio string
+	// Appendix adds an "about this story" section with the story ingredients
+	Appendix bool
+}
+
+// Fact is a labelled line of the "about this story" appendix.
+type Fact struct {
+	Label string
+	Value string
+}
+
+// Title returns the story title without the "Title:" prefix the LLM sometimes adds.
+func (b Book) Title() string {
+	return strings.TrimSpace(strings.TrimPrefix(b.Story.Title, "Title:"))
+}
+
+// Heading returns the chapter heading, chapters without a title are numbered.
+func (b Book) Heading(c story.Chapter) string {
+	if title := strings.TrimSpace(c.Title); title != "" {
+		return title
+	}
+	return story.TextChapter + " " + strconv.Itoa(c.Number)
+}
+
+// Paragraphs returns the chapter body split into paragraphs.
+func (b Book) Paragraphs(c story.Chapter) []string {
+	paragraphs := make([]string, 0)
+	for _, p := range strings.Split(c.Body(), "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// About lists the structure, time period, protagonists and villain the story was written from.
+func (b Book) About() []Fact {
+	facts := make([]Fact, 0)
+	describe := func(name, description string) string {
+		if description == "" {
+			return name
+		}
+		return name + " - " + description
+	}
+	if b.Story.Structure.Name != "" {
+		facts = append(facts, Fact{"Structure", describe(b.Story.Structure.Name, b.Story.Structure.Description)})
+	}
+	if b.Story.TimePeriod.Name != "" {
+		facts = append(facts, Fact{"Time period", describe(b.Story.TimePeriod.Name, b.Story.TimePeriod.Description)})
+	}
+	if b.Story.Location != "" {
+		facts = append(facts, Fact{"Location", b.Story.Location})
+	}
+	for _, p := range b.Story.Protagonists {
+		traits := make([]string, 0)
+		for _, t := range []string{p.Type, p.Gender, p.Age, p.Size} {
+			if t = strings.TrimSpace(t); t != "" {
+				traits = append(traits, t)
+			}
+		}
+		facts = append(facts, Fact{"Protagonist", describe(p.Name, strings.Join(traits, ", "))})
+	}
+	if b.Story.Villain != "" {
+		facts = append(facts, Fact{"Villain", b.Story.Villain})
+	}
+	return facts
+}
+
+var illustrationNumber = regexp.MustCompile(`(\d+)`)
+
+// FindIllustrations returns the images in dir by chapter number, taken from the first number in
+// the file name: chapter_1.png, 02.jpg and ch3-forest.webp are all found.
+func FindIllustrations(dir string) map[int]string {
+	illustrations := make(map[int]string)
+	if dir == "" {
+		return illustrations
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return illustrations
+	}
+	for _, e := range entries {
+		if e.IsDir() || imageMimeType(e.Name()) == "" {
+			continue
+		}
+		match := illustrationNumber.FindString(e.Name())
+		if number, err := strconv.Atoi(match); err == nil {
+			if _, ok := illustrations[number]; !ok {
+				illustrations[number] = filepath.Join(dir, e.Name())
+			}
+		}
+	}
+	return illustrations
+}
+
+// imageMimeType returns the media type of a supported image file, empty for other files.
+func imageMimeType(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".svg":
+		return "image/svg+xml"
+	default:
+		return ""
+	}
+}
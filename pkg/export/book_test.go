@@ -0,0 +1,89 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// testBook is a two chapter story with a cover and an illustration for the second chapter.
+func testBook(t *testing.T) Book {
+	t.Helper()
+	dir := t.TempDir()
+	return Book{
+		Story: story.Story{
+			ID:           "1a2b3c4d5e6f",
+			Title:        "Title: The Fox & the Moon",
+			Summary:      "A fox looks for the <moon>.",
+			Protagonists: story.Protagonists{{Name: "Max", Type: "fox", Age: "child"}},
+			Villain:      "Grumblesnort, a grumpy troll",
+			Location:     "Dark forest",
+			Chapters: story.Chapters{
+				{Number: 1, Title: "The Night", Text: "The Night\nMax woke up. The moon was gone.\nHe went out."},
+				{Number: 2, Text: "Max found the moon in the river."},
+			},
+		},
+		Language:      utils.FindLanguage("English"),
+		Cover:         testImage(t, filepath.Join(dir, "cover.png"), 40, 30),
+		Illustrations: map[int]string{2: testImage(t, filepath.Join(dir, "chapter_2.png"), 30, 40)},
+	}
+}
+
+func testImage(t *testing.T, file string, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 6), G: 120, B: uint8(y * 8), A: 255})
+		}
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestBookText(t *testing.T) {
+	b := testBook(t)
+
+	if got := b.Title(); got != "The Fox & the Moon" {
+		t.Errorf("Title() = %q", got)
+	}
+	if got := b.Heading(b.Story.Chapters[1]); got != "Chapter 2" {
+		t.Errorf("Heading() of an untitled chapter = %q, want Chapter 2", got)
+	}
+	want := []string{"Max woke up. The moon was gone.", "He went out."}
+	if got := b.Paragraphs(b.Story.Chapters[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paragraphs() = %q, want %q", got, want)
+	}
+}
+
+func TestFindIllustrations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"chapter_1.png", "02.jpg", "ch3-forest.webp", "notes.txt", "cover.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[int]string{
+		1: filepath.Join(dir, "chapter_1.png"),
+		2: filepath.Join(dir, "02.jpg"),
+		3: filepath.Join(dir, "ch3-forest.webp"),
+	}
+	if got := FindIllustrations(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindIllustrations() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,362 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+)
+
+const (
+	epubMimeType = "application/epub+zip"
+	epubRoot     = "OEBPS"
+	// epubActiveClass is the class e-readers set on the sentence being read aloud
+	epubActiveClass = "-epub-media-overlay-active"
+)
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const epubStyle = `body { font-family: Georgia, serif; line-height: 1.6; margin: 0 5%; }
+h1, h2 { text-align: center; }
+h1 { margin-top: 30%; }
+p { text-indent: 1.2em; margin: 0 0 .4em; }
+.summary { font-style: italic; text-indent: 0; text-align: center; }
+.cover, .illustration { text-align: center; text-indent: 0; }
+.cover img { max-width: 100%; max-height: 100%; }
+.illustration img { max-width: 100%; }
+.` + epubActiveClass + ` { background-color: #ffe27a; }
+`
+
+// epubAudioTypes are the audio core media types of EPUB 3.3, other formats get no media overlays.
+var epubAudioTypes = map[audio.Format]string{
+	audio.FormatMP3:  "audio/mpeg",
+	audio.FormatAAC:  "audio/mp4",
+	audio.FormatOpus: "audio/opus",
+}
+
+// epubItem is a file of the publication listed in the package manifest.
+type epubItem struct {
+	ID         string
+	Href       string
+	MediaType  string
+	Properties string
+	Overlay    string // ID of the SMIL item reading this document aloud
+	Duration   float64
+	Data       []byte
+}
+
+// epubPar is one sentence of a media overlay: a text fragment and its audio clip.
+type epubPar struct {
+	Fragment   string
+	Start, End float64
+}
+
+// WriteEPUB writes the book as an EPUB 3 publication: a cover, a title page with the summary,
+// one document per chapter and a table of contents. Timed books with narration in an EPUB
+// audio format get SMIL media overlays so e-readers read aloud and highlight the sentence.
+func WriteEPUB(file string, b Book) error {
+	items := make([]epubItem, 0)
+	spine := make([]string, 0)
+	add := func(item epubItem, inSpine bool) {
+		items = append(items, item)
+		if inSpine {
+			spine = append(spine, item.ID)
+		}
+	}
+
+	overlays := b.Timed != nil && b.Audio != ""
+	audioHref := ""
+	if overlays {
+		format, err := audio.DetectFile(b.Audio)
+		if err != nil {
+			return err
+		}
+		mediaType, ok := epubAudioTypes[format]
+		if !ok {
+			overlays = false
+			fmt.Printf("Warning: %s audio is not an EPUB media type, skipping media overlays\n", format)
+		} else {
+			data, err := os.ReadFile(b.Audio)
+			if err != nil {
+				return err
+			}
+			audioHref = "audio/narration" + format.Extension()
+			add(epubItem{ID: "narration", Href: audioHref, MediaType: mediaType, Data: data}, false)
+		}
+	}
+
+	add(epubItem{ID: "style", Href: "style.css", MediaType: "text/css", Data: []byte(epubStyle)}, false)
+
+	if b.Cover != "" {
+		data, err := os.ReadFile(b.Cover)
+		if err != nil {
+			return fmt.Errorf("failed to read cover image: %w", err)
+		}
+		href := "images/cover" + strings.ToLower(filepath.Ext(b.Cover))
+		add(epubItem{ID: "cover-image", Href: href, MediaType: imageMimeType(b.Cover), Properties: "cover-image", Data: data}, false)
+		body := fmt.Sprintf(`<section epub:type="cover"><p class="cover"><img src="%s" alt="%s"/></p></section>`, href, html.EscapeString(b.Title()))
+		add(epubItem{ID: "cover", Href: "cover.xhtml", MediaType: "application/xhtml+xml", Data: xhtmlDocument(b, b.Title(), body)}, true)
+	}
+
+	// Title page
+	var body strings.Builder
+	var pars []epubPar
+	body.WriteString(`<section epub:type="titlepage">`)
+	if overlays {
+		body.WriteString(timedElement("h1", "title", b.Timed.Title, &pars))
+	} else {
+		body.WriteString("<h1>" + html.EscapeString(b.Title()) + "</h1>")
+	}
+	if b.Story.Summary != "" {
+		body.WriteString(`<p class="summary">` + html.EscapeString(strings.TrimSpace(b.Story.Summary)) + "</p>")
+	}
+	body.WriteString("</section>")
+	addDocument := func(id, title, content string, pars []epubPar) {
+		item := epubItem{ID: id, Href: id + ".xhtml", MediaType: "application/xhtml+xml", Data: xhtmlDocument(b, title, content)}
+		if len(pars) > 0 {
+			smil := epubItem{ID: "smil_" + id, Href: "smil/" + id + ".smil", MediaType: "application/smil+xml"}
+			smil.Data, smil.Duration = smilDocument(item.Href, audioHref, pars)
+			item.Overlay = smil.ID
+			add(smil, false)
+		}
+		add(item, true)
+	}
+	addDocument("titlepage", b.Title(), body.String(), pars)
+
+	// Chapters
+	for i, c := range b.Story.Chapters {
+		id := fmt.Sprintf("chapter_%d", i+1)
+		body.Reset()
+		pars = nil
+		body.WriteString(fmt.Sprintf(`<section epub:type="chapter" id="%s">`, id))
+		if overlays && i < len(b.Timed.Sections) {
+			section := b.Timed.Sections[i]
+			body.WriteString(timedElement("h2", "h", section.Heading, &pars))
+			body.WriteString(illustration(b, c.Number, add))
+			for _, paragraph := range section.Paragraphs {
+				body.WriteString("<p>")
+				for k, sentence := range paragraph {
+					if k > 0 {
+						body.WriteString(" ")
+					}
+					body.WriteString(timedElement("span", "s", sentence, &pars))
+				}
+				body.WriteString("</p>")
+			}
+		} else {
+			body.WriteString("<h2>" + html.EscapeString(b.Heading(c)) + "</h2>")
+			body.WriteString(illustration(b, c.Number, add))
+			for _, paragraph := range b.Paragraphs(c) {
+				body.WriteString("<p>" + html.EscapeString(paragraph) + "</p>")
+			}
+		}
+		body.WriteString("</section>")
+		addDocument(id, b.Heading(c), body.String(), pars)
+	}
+
+	add(epubItem{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav", Data: navDocument(b)}, false)
+
+	if err := writeEPUBFile(file, packageDocument(b, items, spine, overlays), items); err != nil {
+		return err
+	}
+	return ValidateEPUB(file)
+}
+
+// timedElement renders a sentence with an id for the media overlay, untimed sentences get no clip.
+func timedElement(tag, prefix string, s Sentence, pars *[]epubPar) string {
+	if s.End <= s.Start {
+		return fmt.Sprintf("<%s>%s</%s>", tag, html.EscapeString(s.Text), tag)
+	}
+	id := fmt.Sprintf("%s%d", prefix, len(*pars)+1)
+	*pars = append(*pars, epubPar{Fragment: id, Start: s.Start, End: s.End})
+	return fmt.Sprintf(`<%s id="%s">%s</%s>`, tag, id, html.EscapeString(s.Text), tag)
+}
+
+// illustration adds the chapter image to the publication and returns its markup.
+func illustration(b Book, chapter int, add func(epubItem, bool)) string {
+	file, ok := b.Illustrations[chapter]
+	if !ok {
+		return ""
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Warning: failed to read illustration %s: %v\n", file, err)
+		return ""
+	}
+	href := fmt.Sprintf("images/chapter_%d%s", chapter, strings.ToLower(filepath.Ext(file)))
+	add(epubItem{ID: fmt.Sprintf("illustration_%d", chapter), Href: href, MediaType: imageMimeType(file), Data: data}, false)
+	return fmt.Sprintf(`<p class="illustration"><img src="%s" alt=""/></p>`, href)
+}
+
+func xhtmlDocument(b Book, title, body string) []byte {
+	lang := html.EscapeString(b.Language.ISO1)
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%s" lang="%s">
+<head>
+<meta charset="UTF-8"/>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+%s
+</body>
+</html>
+`, lang, lang, html.EscapeString(title), body))
+}
+
+func navDocument(b Book) []byte {
+	var nav strings.Builder
+	nav.WriteString(`<nav epub:type="toc" id="toc"><h1>` + html.EscapeString(b.Title()) + "</h1><ol>")
+	nav.WriteString(`<li><a href="titlepage.xhtml">` + html.EscapeString(b.Title()) + "</a></li>")
+	for i, c := range b.Story.Chapters {
+		nav.WriteString(fmt.Sprintf(`<li><a href="chapter_%d.xhtml">%s</a></li>`, i+1, html.EscapeString(b.Heading(c))))
+	}
+	nav.WriteString("</ol></nav>")
+	nav.WriteString(`<nav epub:type="landmarks" id="landmarks" hidden="hidden"><ol>`)
+	if b.Cover != "" {
+		nav.WriteString(`<li><a epub:type="cover" href="cover.xhtml">Cover</a></li>`)
+	}
+	if len(b.Story.Chapters) > 0 {
+		nav.WriteString(`<li><a epub:type="bodymatter" href="chapter_1.xhtml">` + html.EscapeString(b.Heading(b.Story.Chapters[0])) + "</a></li>")
+	}
+	nav.WriteString("</ol></nav>")
+	return xhtmlDocument(b, b.Title(), nav.String())
+}
+
+func smilDocument(textHref, audioHref string, pars []epubPar) ([]byte, float64) {
+	var smil strings.Builder
+	smil.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<smil xmlns="http://www.w3.org/ns/SMIL" xmlns:epub="http://www.idpf.org/2007/ops" version="3.0">
+<body>
+`)
+	smil.WriteString(fmt.Sprintf(`<seq id="seq1" epub:textref="../%s">`+"\n", textHref))
+	duration := 0.0
+	for i, p := range pars {
+		smil.WriteString(fmt.Sprintf(`<par id="par%d"><text src="../%s#%s"/><audio src="../%s" clipBegin="%.3fs" clipEnd="%.3fs"/></par>`+"\n",
+			i+1, textHref, p.Fragment, audioHref, p.Start, p.End))
+		duration += p.End - p.Start
+	}
+	smil.WriteString("</seq>\n</body>\n</smil>\n")
+	return []byte(smil.String()), duration
+}
+
+// clockValue formats seconds as a SMIL clock value, 0:01:02.345.
+func clockValue(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func packageDocument(b Book, items []epubItem, spine []string, overlays bool) []byte {
+	var opf strings.Builder
+	opf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + html.EscapeString(b.Language.ISO1) + `">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	opf.WriteString(fmt.Sprintf("<dc:identifier id=\"book-id\">urn:uuid:%s</dc:identifier>\n", bookUUID(b)))
+	opf.WriteString(fmt.Sprintf("<dc:title>%s</dc:title>\n", html.EscapeString(b.Title())))
+	opf.WriteString(fmt.Sprintf("<dc:language>%s</dc:language>\n", html.EscapeString(b.Language.ISO1)))
+	opf.WriteString("<dc:creator>storygen</dc:creator>\n")
+	if b.Story.Summary != "" {
+		opf.WriteString(fmt.Sprintf("<dc:description>%s</dc:description>\n", html.EscapeString(strings.TrimSpace(b.Story.Summary))))
+	}
+	opf.WriteString(fmt.Sprintf("<meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z")))
+	if b.Cover != "" {
+		opf.WriteString("<meta name=\"cover\" content=\"cover-image\"/>\n")
+	}
+	if overlays {
+		total := 0.0
+		for _, item := range items {
+			if item.MediaType == "application/smil+xml" {
+				opf.WriteString(fmt.Sprintf("<meta property=\"media:duration\" refines=\"#%s\">%s</meta>\n", item.ID, clockValue(item.Duration)))
+				total += item.Duration
+			}
+		}
+		opf.WriteString(fmt.Sprintf("<meta property=\"media:duration\">%s</meta>\n", clockValue(total)))
+		if b.Story.Narration != nil && b.Story.Narration.Voice != "" {
+			opf.WriteString(fmt.Sprintf("<meta property=\"media:narrator\">%s</meta>\n", html.EscapeString(b.Story.Narration.Voice)))
+		}
+		opf.WriteString(fmt.Sprintf("<meta property=\"media:active-class\">%s</meta>\n", epubActiveClass))
+	}
+	opf.WriteString("</metadata>\n<manifest>\n")
+	for _, item := range items {
+		attributes := ""
+		if item.Properties != "" {
+			attributes += fmt.Sprintf(` properties="%s"`, item.Properties)
+		}
+		if item.Overlay != "" {
+			attributes += fmt.Sprintf(` media-overlay="%s"`, item.Overlay)
+		}
+		opf.WriteString(fmt.Sprintf("<item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", item.ID, item.Href, item.MediaType, attributes))
+	}
+	opf.WriteString("</manifest>\n<spine>\n")
+	for _, id := range spine {
+		opf.WriteString(fmt.Sprintf("<itemref idref=\"%s\"/>\n", id))
+	}
+	opf.WriteString("</spine>\n</package>\n")
+	return []byte(opf.String())
+}
+
+// bookUUID derives a stable name based UUID from the story, so exporting again keeps the identifier.
+func bookUUID(b Book) string {
+	sum := sha1.Sum([]byte(b.Title() + "\n" + b.Story.Summary + "\n" + b.Language.ISO1))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// writeEPUBFile writes the OCF zip container, the uncompressed mimetype entry must come first.
+func writeEPUBFile(file string, opf []byte, items []epubItem) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	modified := time.Now()
+	z := zip.NewWriter(f)
+	entry := func(name string, method uint16, data []byte) error {
+		w, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: modified})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	if err := entry("mimetype", zip.Store, []byte(epubMimeType)); err != nil {
+		return err
+	}
+	if err := entry("META-INF/container.xml", zip.Deflate, []byte(epubContainer)); err != nil {
+		return err
+	}
+	if err := entry(path.Join(epubRoot, "content.opf"), zip.Deflate, opf); err != nil {
+		return err
+	}
+	for _, item := range items {
+		method := zip.Deflate
+		if strings.HasPrefix(item.MediaType, "audio/") || strings.HasPrefix(item.MediaType, "image/") && item.MediaType != "image/svg+xml" {
+			method = zip.Store // Already compressed
+		}
+		if err := entry(path.Join(epubRoot, item.Href), method, item.Data); err != nil {
+			return err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
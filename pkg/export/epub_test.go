@@ -0,0 +1,287 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readEPUB returns the container entries in order and their contents.
+func readEPUB(t *testing.T, file string) ([]*zip.File, map[string]string) {
+	t.Helper()
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { z.Close() })
+
+	contents := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = string(data)
+	}
+	return z.File, contents
+}
+
+func testTimedBook(t *testing.T) Book {
+	t.Helper()
+	b := testBook(t)
+	b.Audio = filepath.Join(t.TempDir(), "story.mp3")
+	if err := os.WriteFile(b.Audio, append([]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), make([]byte, 64)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b.Timed = &TimedStory{
+		Title: Sentence{Text: "The Fox & the Moon", Start: 0, End: 1.5},
+		Sections: []Section{
+			{Number: 1, Heading: Sentence{Text: "The Night", Start: 1.5, End: 2.5}, Paragraphs: [][]Sentence{
+				{{Text: "Max woke up.", Start: 2.5, End: 4}, {Text: "The moon was gone.", Start: 4, End: 6}},
+				{{Text: "He went out.", Start: 6, End: 7.25}},
+			}},
+			// Not narrated, the chapter has no overlay
+			{Number: 2, Heading: Sentence{Text: "Chapter 2"}, Paragraphs: [][]Sentence{
+				{{Text: "Max found the moon in the river."}},
+			}},
+		},
+	}
+	return b
+}
+
+func TestWriteEPUB(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "story.epub")
+	if err := WriteEPUB(file, testBook(t)); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+
+	entries, contents := readEPUB(t, file)
+	if entries[0].Name != "mimetype" || entries[0].Method != zip.Store || contents["mimetype"] != epubMimeType {
+		t.Errorf("first entry = %s (method %d), want the stored mimetype", entries[0].Name, entries[0].Method)
+	}
+
+	var opf opfPackage
+	if err := xml.Unmarshal([]byte(contents["OEBPS/content.opf"]), &opf); err != nil {
+		t.Fatal(err)
+	}
+	spine := make([]string, 0)
+	for _, ref := range opf.Spine {
+		spine = append(spine, ref.IDRef)
+	}
+	if got := strings.Join(spine, " "); got != "cover titlepage chapter_1 chapter_2" {
+		t.Errorf("spine = %s", got)
+	}
+	if opf.Metadata.Titles[0] != "The Fox & the Moon" || opf.Metadata.Languages[0] != "en" {
+		t.Errorf("metadata = %q, %q", opf.Metadata.Titles, opf.Metadata.Languages)
+	}
+	for _, item := range opf.Items {
+		if item.MediaType == "application/smil+xml" || item.MediaOverlay != "" {
+			t.Errorf("untimed book has a media overlay %+v", item)
+		}
+	}
+
+	chapter := contents["OEBPS/chapter_1.xhtml"]
+	for _, want := range []string{"<h2>The Night</h2>", "<p>Max woke up. The moon was gone.</p>", "<p>He went out.</p>"} {
+		if !strings.Contains(chapter, want) {
+			t.Errorf("chapter_1.xhtml has no %s:\n%s", want, chapter)
+		}
+	}
+	if !strings.Contains(contents["OEBPS/chapter_2.xhtml"], `<img src="images/chapter_2.png"`) {
+		t.Errorf("chapter_2.xhtml has no illustration")
+	}
+	if !strings.Contains(contents["OEBPS/titlepage.xhtml"], "A fox looks for the &lt;moon&gt;.") {
+		t.Errorf("title page has no escaped summary")
+	}
+}
+
+func TestWriteEPUBKeepsTheIdentifier(t *testing.T) {
+	identifier := func(b Book) string {
+		file := filepath.Join(t.TempDir(), "story.epub")
+		if err := WriteEPUB(file, b); err != nil {
+			t.Fatal(err)
+		}
+		_, contents := readEPUB(t, file)
+		var opf opfPackage
+		if err := xml.Unmarshal([]byte(contents["OEBPS/content.opf"]), &opf); err != nil {
+			t.Fatal(err)
+		}
+		return opf.Metadata.Identifiers[0].Value
+	}
+
+	b := testBook(t)
+	first := identifier(b)
+	if again := identifier(b); again != first {
+		t.Errorf("identifier changed from %s to %s", first, again)
+	}
+	b.Story.Title = "Another story"
+	if other := identifier(b); other == first {
+		t.Errorf("another story has the same identifier %s", other)
+	}
+}
+
+func TestWriteEPUBMediaOverlays(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "story.epub")
+	if err := WriteEPUB(file, testTimedBook(t)); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+
+	entries, contents := readEPUB(t, file)
+	for _, e := range entries {
+		if e.Name == "OEBPS/audio/narration.mp3" && e.Method != zip.Store {
+			t.Errorf("narration is compressed")
+		}
+	}
+	if _, ok := contents["OEBPS/audio/narration.mp3"]; !ok {
+		t.Fatal("narration is not in the container")
+	}
+
+	opf := contents["OEBPS/content.opf"]
+	for _, want := range []string{
+		`<item id="chapter_1" href="chapter_1.xhtml" media-type="application/xhtml+xml" media-overlay="smil_chapter_1"/>`,
+		`<item id="titlepage" href="titlepage.xhtml" media-type="application/xhtml+xml" media-overlay="smil_titlepage"/>`,
+		`<item id="chapter_2" href="chapter_2.xhtml" media-type="application/xhtml+xml"/>`,
+		`<meta property="media:duration" refines="#smil_titlepage">0:00:01.500</meta>`,
+		`<meta property="media:duration" refines="#smil_chapter_1">0:00:05.750</meta>`,
+		`<meta property="media:duration">0:00:07.250</meta>`,
+		`<meta property="media:active-class">` + epubActiveClass + `</meta>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf has no %s:\n%s", want, opf)
+		}
+	}
+
+	smil := contents["OEBPS/smil/chapter_1.smil"]
+	for _, want := range []string{
+		`<seq id="seq1" epub:textref="../chapter_1.xhtml">`,
+		`<par id="par1"><text src="../chapter_1.xhtml#h1"/><audio src="../audio/narration.mp3" clipBegin="1.500s" clipEnd="2.500s"/></par>`,
+		`<par id="par4"><text src="../chapter_1.xhtml#s4"/><audio src="../audio/narration.mp3" clipBegin="6.000s" clipEnd="7.250s"/></par>`,
+	} {
+		if !strings.Contains(smil, want) {
+			t.Errorf("chapter_1.smil has no %s:\n%s", want, smil)
+		}
+	}
+	if !strings.Contains(contents["OEBPS/chapter_1.xhtml"], `<p><span id="s2">Max woke up.</span> <span id="s3">The moon was gone.</span></p>`) {
+		t.Errorf("chapter_1.xhtml sentences have no overlay ids:\n%s", contents["OEBPS/chapter_1.xhtml"])
+	}
+	if !strings.Contains(contents["OEBPS/chapter_2.xhtml"], "<span>Max found the moon in the river.</span>") {
+		t.Errorf("untimed sentence got an overlay id:\n%s", contents["OEBPS/chapter_2.xhtml"])
+	}
+}
+
+func TestWriteEPUBSkipsOverlaysForOtherAudio(t *testing.T) {
+	b := testTimedBook(t)
+	if err := os.WriteFile(b.Audio, append([]byte("fLaC"), make([]byte, 64)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "story.epub")
+	if err := WriteEPUB(file, b); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+	_, contents := readEPUB(t, file)
+	if strings.Contains(contents["OEBPS/content.opf"], "media-overlay") {
+		t.Errorf("FLAC narration got media overlays")
+	}
+}
+
+func TestValidateEPUB(t *testing.T) {
+	b := testBook(t)
+	items := []epubItem{
+		{ID: "style", Href: "style.css", MediaType: "text/css", Data: []byte(epubStyle)},
+		{ID: "chapter_1", Href: "chapter_1.xhtml", MediaType: "application/xhtml+xml", Data: xhtmlDocument(b, "One", `<p><a href="chapter_1.xhtml#top">Up</a></p>`)},
+		{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav", Data: navDocument(b)},
+	}
+	valid := packageDocument(b, items, []string{"chapter_1"}, false)
+
+	tests := []struct {
+		name  string
+		opf   string
+		items []epubItem
+		want  []string
+	}{
+		{
+			name:  "missing fragment and chapter",
+			opf:   string(valid),
+			items: items,
+			want:  []string{"references missing fragment chapter_1.xhtml#top", "nav.xhtml references chapter_2.xhtml which is not in the manifest"},
+		},
+		{
+			name:  "no title, file missing from the container",
+			opf:   strings.Replace(string(valid), "<dc:title>The Fox &amp; the Moon</dc:title>", "", 1),
+			items: items[:2],
+			want:  []string{"dc:title is required", `manifest item "nav.xhtml" is missing from the container`},
+		},
+		{
+			name: "not well-formed",
+			opf:  string(valid),
+			items: []epubItem{items[0], {ID: "chapter_1", Href: "chapter_1.xhtml", MediaType: "application/xhtml+xml", Data: []byte("<html><p>open</html>")},
+				items[2]},
+			want: []string{"chapter_1.xhtml is not well-formed"},
+		},
+		{
+			name:  "overlay without a duration",
+			opf:   strings.Replace(string(valid), `<item id="chapter_1" href="chapter_1.xhtml" media-type="application/xhtml+xml"`, `<item id="chapter_1" href="chapter_1.xhtml" media-type="application/xhtml+xml" media-overlay="style"`, 1),
+			items: items,
+			want:  []string{`media-overlay of "chapter_1" must reference a SMIL item`, `media overlay "style" needs a media:duration`, "need a total media:duration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "story.epub")
+			if err := writeEPUBFile(file, []byte(tt.opf), tt.items); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateEPUB(file)
+			if err == nil {
+				t.Fatal("ValidateEPUB() error = nil")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateEPUB() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateEPUBMimetype(t *testing.T) {
+	valid := filepath.Join(t.TempDir(), "valid.epub")
+	if err := WriteEPUB(valid, testBook(t)); err != nil {
+		t.Fatal(err)
+	}
+	entries, contents := readEPUB(t, valid)
+
+	// Same files with a compressed mimetype at the end
+	file := filepath.Join(t.TempDir(), "story.epub")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for _, e := range append(entries[1:], entries[0]) {
+		w, err := z.CreateHeader(&zip.FileHeader{Name: e.Name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents[e.Name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := ValidateEPUB(file); err == nil || !strings.Contains(err.Error(), "mimetype must be the first file") {
+		t.Errorf("ValidateEPUB() error = %v, want the mimetype first", err)
+	}
+}
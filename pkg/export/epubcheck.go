@@ -0,0 +1,305 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type opfPackage struct {
+	Version          string `xml:"version,attr"`
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Identifiers []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+		Titles    []string `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Languages []string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Meta      []struct {
+			Property string `xml:"property,attr"`
+			Refines  string `xml:"refines,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Items []struct {
+		ID           string `xml:"id,attr"`
+		Href         string `xml:"href,attr"`
+		MediaType    string `xml:"media-type,attr"`
+		Properties   string `xml:"properties,attr"`
+		MediaOverlay string `xml:"media-overlay,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+var epubModified = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+
+// ValidateEPUB checks the EPUB 3 structure rules the exporter has to follow: the OCF container
+// layout, required package metadata, a complete manifest and spine, a navigation document,
+// well-formed content documents, media overlays and that every reference resolves.
+func ValidateEPUB(file string) error {
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("epub %s: %w", file, err)
+	}
+	defer z.Close()
+
+	problems := make([]string, 0)
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing", name)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+
+	// OCF container
+	if len(z.File) == 0 || z.File[0].Name != "mimetype" {
+		fail("mimetype must be the first file in the container")
+	} else if z.File[0].Method != zip.Store {
+		fail("mimetype must not be compressed")
+	} else if data, _ := read("mimetype"); string(data) != epubMimeType {
+		fail("mimetype must contain %q", epubMimeType)
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	data, err := read("META-INF/container.xml")
+	if err == nil {
+		err = xml.Unmarshal(data, &container)
+	}
+	if err != nil || len(container.Rootfiles) == 0 {
+		return fmt.Errorf("epub %s: invalid META-INF/container.xml: %v", file, err)
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	if container.Rootfiles[0].MediaType != "application/oebps-package+xml" {
+		fail("container rootfile must have media-type application/oebps-package+xml")
+	}
+
+	// Package document
+	var opf opfPackage
+	data, err = read(opfPath)
+	if err == nil {
+		err = xml.Unmarshal(data, &opf)
+	}
+	if err != nil {
+		return fmt.Errorf("epub %s: invalid package document %s: %v", file, opfPath, err)
+	}
+	base := path.Dir(opfPath)
+
+	if opf.Version != "3.0" {
+		fail("package version must be 3.0, got %q", opf.Version)
+	}
+	identified := false
+	for _, id := range opf.Metadata.Identifiers {
+		if id.ID == opf.UniqueIdentifier && strings.TrimSpace(id.Value) != "" {
+			identified = true
+		}
+	}
+	if !identified {
+		fail("unique-identifier %q must reference a non-empty dc:identifier", opf.UniqueIdentifier)
+	}
+	if len(opf.Metadata.Titles) == 0 || strings.TrimSpace(opf.Metadata.Titles[0]) == "" {
+		fail("dc:title is required")
+	}
+	if len(opf.Metadata.Languages) == 0 || strings.TrimSpace(opf.Metadata.Languages[0]) == "" {
+		fail("dc:language is required")
+	}
+	modified, totalDuration, durations := 0, false, make(map[string]bool)
+	for _, m := range opf.Metadata.Meta {
+		switch {
+		case m.Property == "dcterms:modified" && m.Refines == "":
+			modified++
+			if !epubModified.MatchString(strings.TrimSpace(m.Value)) {
+				fail("dcterms:modified must be CCYY-MM-DDThh:mm:ssZ, got %q", m.Value)
+			}
+		case m.Property == "media:duration" && m.Refines == "":
+			totalDuration = true
+		case m.Property == "media:duration":
+			durations[strings.TrimPrefix(m.Refines, "#")] = true
+		}
+	}
+	if modified != 1 {
+		fail("exactly one dcterms:modified is required, found %d", modified)
+	}
+
+	// Manifest
+	ids := make(map[string]string) // id -> media type
+	hrefs := make(map[string]bool)
+	navs, covers, overlays := 0, 0, 0
+	for _, item := range opf.Items {
+		if item.ID == "" || item.Href == "" || item.MediaType == "" {
+			fail("manifest item %q needs id, href and media-type", item.ID)
+			continue
+		}
+		if _, ok := ids[item.ID]; ok {
+			fail("duplicate manifest id %q", item.ID)
+		}
+		ids[item.ID] = item.MediaType
+		full := path.Join(base, item.Href)
+		if hrefs[full] {
+			fail("duplicate manifest href %q", item.Href)
+		}
+		hrefs[full] = true
+		if _, ok := files[full]; !ok {
+			fail("manifest item %q is missing from the container", item.Href)
+		}
+		for _, p := range strings.Fields(item.Properties) {
+			switch p {
+			case "nav":
+				navs++
+			case "cover-image":
+				covers++
+			}
+		}
+	}
+	if navs != 1 {
+		fail("exactly one navigation document is required, found %d", navs)
+	}
+	if covers > 1 {
+		fail("at most one cover-image is allowed, found %d", covers)
+	}
+	for _, item := range opf.Items {
+		if item.MediaOverlay == "" {
+			continue
+		}
+		overlays++
+		if ids[item.MediaOverlay] != "application/smil+xml" {
+			fail("media-overlay of %q must reference a SMIL item", item.ID)
+		}
+		if !durations[item.MediaOverlay] {
+			fail("media overlay %q needs a media:duration", item.MediaOverlay)
+		}
+	}
+	if overlays > 0 && !totalDuration {
+		fail("publications with media overlays need a total media:duration")
+	}
+	for name := range files {
+		if name != "mimetype" && !strings.HasPrefix(name, "META-INF/") && name != opfPath && !hrefs[name] {
+			fail("%s is not listed in the manifest", name)
+		}
+	}
+
+	// Spine
+	if len(opf.Spine) == 0 {
+		fail("spine must not be empty")
+	}
+	for _, ref := range opf.Spine {
+		mediaType, ok := ids[ref.IDRef]
+		if !ok {
+			fail("spine itemref %q is not in the manifest", ref.IDRef)
+		} else if mediaType != "application/xhtml+xml" && mediaType != "image/svg+xml" {
+			fail("spine item %q must be a content document, got %s", ref.IDRef, mediaType)
+		}
+	}
+
+	// Content documents and overlays must be well-formed and their references must resolve
+	for _, item := range opf.Items {
+		if item.MediaType != "application/xhtml+xml" && item.MediaType != "application/smil+xml" {
+			continue
+		}
+		full := path.Join(base, item.Href)
+		data, err := read(full)
+		if err != nil {
+			continue
+		}
+		refs, ids, err := xmlReferences(data)
+		if err != nil {
+			fail("%s is not well-formed: %v", item.Href, err)
+			continue
+		}
+		if strings.Contains(item.Properties, "nav") && !bytes.Contains(data, []byte(`epub:type="toc"`)) {
+			fail("navigation document %s needs a toc nav", item.Href)
+		}
+		for _, ref := range refs {
+			u, err := url.Parse(ref)
+			if err != nil || u.Scheme != "" {
+				continue
+			}
+			target := full
+			if u.Path != "" {
+				target = path.Join(path.Dir(full), u.Path)
+				if !hrefs[target] {
+					fail("%s references %s which is not in the manifest", item.Href, ref)
+					continue
+				}
+			}
+			if u.Fragment == "" {
+				continue
+			}
+			targetIDs := ids
+			if target != full {
+				targetData, err := read(target)
+				if err != nil {
+					continue
+				}
+				if _, targetIDs, err = xmlReferences(targetData); err != nil {
+					continue
+				}
+			}
+			if !targetIDs[u.Fragment] {
+				fail("%s references missing fragment %s", item.Href, ref)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("epub %s is invalid: %w", file, errors.New(strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// xmlReferences parses a document strictly and returns its href and src references and ids.
+func xmlReferences(data []byte) ([]string, map[string]bool, error) {
+	refs := make([]string, 0)
+	ids := make(map[string]bool)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return refs, ids, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, a := range element.Attr {
+			switch {
+			case a.Name.Local == "id":
+				ids[a.Value] = true
+			case a.Name.Local == "href" || a.Name.Local == "src" || a.Name.Local == "textref":
+				refs = append(refs, a.Value)
+			}
+		}
+	}
+}
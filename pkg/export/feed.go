@@ -0,0 +1,293 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// FeedChannel describes the podcast the stories are published in.
+type FeedChannel struct {
+	Title       string
+	Description string
+	Author      string
+	Image       string // URL of the square podcast artwork
+	Category    string // iTunes category, "Kids & Family" by default
+	Language    utils.Language
+	BaseURL     string // Public URL of the directory the feed and audio files are served from
+}
+
+// FeedEpisode is a narrated story published as one feed item.
+type FeedEpisode struct {
+	Story    story.Story
+	Audio    string // Audio file path relative to the feed directory
+	Size     int64
+	Modified time.Time
+	Duration time.Duration
+	Language utils.Language
+	// Chapters and Transcripts are files relative to the feed directory
+	Chapters    string
+	Transcripts []string
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	ITunes  string     `xml:"xmlns:itunes,attr"`
+	Podcast string     `xml:"xmlns:podcast,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string      `xml:"title"`
+	Link           string      `xml:"link"`
+	Description    string      `xml:"description"`
+	Language       string      `xml:"language"`
+	Generator      string      `xml:"generator"`
+	LastBuildDate  string      `xml:"lastBuildDate"`
+	AtomLink       rssAtomLink `xml:"atom:link"`
+	ITunesAuthor   string      `xml:"itunes:author,omitempty"`
+	ITunesImage    *rssHref    `xml:"itunes:image,omitempty"`
+	ITunesCategory rssCategory `xml:"itunes:category"`
+	ITunesExplicit string      `xml:"itunes:explicit"`
+	ITunesType     string      `xml:"itunes:type"`
+	Items          []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssHref struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssCategory struct {
+	Text string `xml:"text,attr"`
+}
+
+type rssItem struct {
+	Title             string          `xml:"title"`
+	Description       string          `xml:"description"`
+	Enclosure         rssEnclosure    `xml:"enclosure"`
+	GUID              rssGUID         `xml:"guid"`
+	PubDate           string          `xml:"pubDate"`
+	Language          string          `xml:"dc:language"`
+	ITunesTitle       string          `xml:"itunes:title"`
+	ITunesSummary     string          `xml:"itunes:summary,omitempty"`
+	ITunesDuration    string          `xml:"itunes:duration"`
+	ITunesEpisodeType string          `xml:"itunes:episodeType"`
+	Chapters          *rssChapters    `xml:"podcast:chapters,omitempty"`
+	Transcripts       []rssTranscript `xml:"podcast:transcript"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssChapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssTranscript struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr,omitempty"`
+}
+
+// publishedItems reads the guids and publish dates of a feed written before.
+func publishedItems(file string) map[string]string {
+	published := make(map[string]string)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return published
+	}
+	var feed struct {
+		Items []struct {
+			GUID    string `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		fmt.Printf("Warning: ignoring unreadable feed %s: %v\n", file, err)
+		return published
+	}
+	for _, item := range feed.Items {
+		published[item.GUID] = item.PubDate
+	}
+	return published
+}
+
+// UpdateFeed writes an RSS 2.0 feed with iTunes and Podcasting 2.0 tags. Episodes already in the
+// feed keep their publish date, new ones are dated by their audio file, so running it again after
+// new stories appear only adds items. Episodes whose audio is gone are dropped. It returns the
+// number of new items.
+func UpdateFeed(file string, channel FeedChannel, episodes []FeedEpisode) (int, error) {
+	if channel.BaseURL == "" {
+		return 0, fmt.Errorf("feed base URL is required")
+	}
+	base := strings.TrimRight(channel.BaseURL, "/")
+	if u, err := url.Parse(base); err != nil || u.Scheme == "" || u.Host == "" {
+		return 0, fmt.Errorf("feed base URL must be absolute, got %q", channel.BaseURL)
+	}
+	if channel.Category == "" {
+		channel.Category = "Kids & Family"
+	}
+
+	published := publishedItems(file)
+	feed := rssFeed{
+		Version: "2.0",
+		ITunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Podcast: "https://podcastindex.org/namespace/1.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:          channel.Title,
+			Link:           base + "/",
+			Description:    channel.Description,
+			Language:       channel.Language.ISO1,
+			Generator:      "storygen",
+			LastBuildDate:  time.Now().Format(time.RFC1123Z),
+			AtomLink:       rssAtomLink{Href: feedURL(base, filepath.Base(file)), Rel: "self", Type: "application/rss+xml"},
+			ITunesAuthor:   channel.Author,
+			ITunesCategory: rssCategory{Text: channel.Category},
+			ITunesExplicit: "false",
+			ITunesType:     "episodic",
+		},
+	}
+	if channel.Image != "" {
+		feed.Channel.ITunesImage = &rssHref{Href: channel.Image}
+	}
+
+	added := 0
+	type dated struct {
+		item rssItem
+		date time.Time
+	}
+	items := make([]dated, 0, len(episodes))
+	for _, e := range episodes {
+		guid := filepath.ToSlash(e.Audio)
+		pubDate, ok := published[guid]
+		date, err := time.Parse(time.RFC1123Z, pubDate)
+		if !ok || err != nil {
+			date, pubDate = e.Modified, e.Modified.Format(time.RFC1123Z)
+			added++
+		}
+
+		title := Book{Story: e.Story}.Title()
+		summary := strings.TrimSpace(e.Story.Summary)
+		item := rssItem{
+			Title:             title,
+			Description:       summary,
+			Enclosure:         rssEnclosure{URL: feedURL(base, e.Audio), Length: e.Size, Type: audioMimeType(e.Audio)},
+			GUID:              rssGUID{IsPermaLink: "false", Value: guid},
+			PubDate:           pubDate,
+			Language:          e.Language.ISO1,
+			ITunesTitle:       title,
+			ITunesSummary:     summary,
+			ITunesDuration:    fmt.Sprintf("%02d:%02d:%02d", int(e.Duration.Hours()), int(e.Duration.Minutes())%60, int(e.Duration.Seconds())%60),
+			ITunesEpisodeType: "full",
+		}
+		if e.Chapters != "" {
+			item.Chapters = &rssChapters{URL: feedURL(base, e.Chapters), Type: "application/json+chapters"}
+		}
+		for _, t := range e.Transcripts {
+			transcriptType := "text/vtt"
+			if strings.EqualFold(filepath.Ext(t), ".srt") {
+				transcriptType = "application/x-subrip"
+			}
+			item.Transcripts = append(item.Transcripts, rssTranscript{URL: feedURL(base, t), Type: transcriptType, Language: e.Language.ISO1})
+		}
+		items = append(items, dated{item: item, date: date})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].date.After(items[j].date) })
+	for _, d := range items {
+		feed.Channel.Items = append(feed.Channel.Items, d.item)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(file, append([]byte(xml.Header), append(data, '\n')...), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write feed: %w", err)
+	}
+	return added, nil
+}
+
+// feedURL joins a path relative to the feed directory to the base URL, escaping every segment.
+func feedURL(base, file string) string {
+	segments := strings.Split(filepath.ToSlash(file), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return base + "/" + strings.Join(segments, "/")
+}
+
+func audioMimeType(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".m4a":
+		return "audio/x-m4a"
+	case ".ogg", ".opus":
+		return "audio/ogg"
+	case ".wav":
+		return "audio/wav"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+// podcastChapters is the Podcasting 2.0 JSON chapters format.
+type podcastChapters struct {
+	Version  string           `json:"version"`
+	Chapters []podcastChapter `json:"chapters"`
+}
+
+type podcastChapter struct {
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime,omitempty"`
+	Title     string  `json:"title"`
+}
+
+// WriteChapters writes the narration chapter markers as a Podcasting 2.0 chapters file.
+func WriteChapters(file string, s story.Story) error {
+	if s.Narration == nil {
+		return fmt.Errorf("story has no narration")
+	}
+	chapters := podcastChapters{Version: "1.2.0", Chapters: make([]podcastChapter, 0)}
+	book := Book{Story: s}
+	for _, m := range s.Narration.ChapterMarkers() {
+		title := fmt.Sprintf("%s %d", story.TextChapter, m.Chapter)
+		if m.Chapter <= len(s.Chapters) {
+			title = book.Heading(s.Chapters[m.Chapter-1])
+		}
+		chapters.Chapters = append(chapters.Chapters, podcastChapter{StartTime: m.Start, EndTime: m.End, Title: title})
+	}
+	data, err := json.MarshalIndent(chapters, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,190 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+type testFeedItem struct {
+	Title     string `xml:"title"`
+	GUID      string `xml:"guid"`
+	PubDate   string `xml:"pubDate"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"enclosure"`
+}
+
+func readFeed(t *testing.T, file string) (string, []testFeedItem) {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Items []testFeedItem `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("feed is not well-formed: %v", err)
+	}
+	return string(data), feed.Items
+}
+
+func testEpisode(title, audio string, size int64, modified time.Time) FeedEpisode {
+	return FeedEpisode{
+		Story:    story.Story{Title: title, Summary: "A story about " + title + "."},
+		Audio:    audio,
+		Size:     size,
+		Modified: modified,
+		Duration: 75*time.Minute + 3*time.Second,
+		Language: utils.FindLanguage("English"),
+	}
+}
+
+func TestUpdateFeed(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "feed.xml")
+	channel := FeedChannel{Title: "Bedtime", BaseURL: "https://example.com/stories/", Language: utils.FindLanguage("English")}
+	monday := time.Date(2025, 3, 3, 20, 0, 0, 0, time.UTC)
+
+	fox := testEpisode("The Fox", "en/the-fox_1a2b.mp3", 1234567, monday)
+	fox.Chapters = "en/the-fox_1a2b.chapters.json"
+	fox.Transcripts = []string{"en/the-fox_1a2b.vtt", "en/the-fox_1a2b.srt"}
+	owl := testEpisode("The Owl & Moon", "en/the owl_3c4d.m4a", 42, monday.Add(24*time.Hour))
+
+	added, err := UpdateFeed(file, channel, []FeedEpisode{fox, owl})
+	if err != nil {
+		t.Fatalf("UpdateFeed() error = %v", err)
+	}
+	if added != 2 {
+		t.Errorf("added = %d, want 2", added)
+	}
+
+	data, items := readFeed(t, file)
+	if len(items) != 2 || items[0].Title != "The Owl & Moon" || items[1].Title != "The Fox" {
+		t.Fatalf("items = %+v, want the newest first", items)
+	}
+	owlItem, foxItem := items[0], items[1]
+	if foxItem.GUID != "en/the-fox_1a2b.mp3" || foxItem.PubDate != monday.Format(time.RFC1123Z) {
+		t.Errorf("fox item = %+v", foxItem)
+	}
+	if foxItem.Enclosure.URL != "https://example.com/stories/en/the-fox_1a2b.mp3" || foxItem.Enclosure.Length != 1234567 || foxItem.Enclosure.Type != "audio/mpeg" {
+		t.Errorf("fox enclosure = %+v, want the audio URL, its size in bytes and audio/mpeg", foxItem.Enclosure)
+	}
+	if owlItem.Enclosure.URL != "https://example.com/stories/en/the%20owl_3c4d.m4a" || owlItem.Enclosure.Length != 42 || owlItem.Enclosure.Type != "audio/x-m4a" {
+		t.Errorf("owl enclosure = %+v", owlItem.Enclosure)
+	}
+	for _, want := range []string{
+		`<atom:link href="https://example.com/stories/feed.xml" rel="self" type="application/rss+xml"></atom:link>`,
+		`<itunes:category text="Kids &amp; Family"></itunes:category>`,
+		"<itunes:duration>01:15:03</itunes:duration>",
+		`<podcast:chapters url="https://example.com/stories/en/the-fox_1a2b.chapters.json" type="application/json+chapters"></podcast:chapters>`,
+		`<podcast:transcript url="https://example.com/stories/en/the-fox_1a2b.vtt" type="text/vtt" language="en"></podcast:transcript>`,
+		`<podcast:transcript url="https://example.com/stories/en/the-fox_1a2b.srt" type="application/x-subrip" language="en"></podcast:transcript>`,
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("feed has no %s", want)
+		}
+	}
+}
+
+func TestUpdateFeedKeepsPublishedItems(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "feed.xml")
+	channel := FeedChannel{Title: "Bedtime", BaseURL: "https://example.com"}
+	monday := time.Date(2025, 3, 3, 20, 0, 0, 0, time.UTC)
+
+	fox := testEpisode("The Fox", "the-fox.mp3", 100, monday)
+	owl := testEpisode("The Owl", "the-owl.mp3", 200, monday.Add(time.Hour))
+	if _, err := UpdateFeed(file, channel, []FeedEpisode{fox, owl}); err != nil {
+		t.Fatal(err)
+	}
+	_, first := readFeed(t, file)
+
+	// The fox was narrated again, the owl audio is gone and a new story appeared
+	fox.Modified = monday.Add(48 * time.Hour)
+	fox.Size = 150
+	bear := testEpisode("The Bear", "the-bear.mp3", 300, monday.Add(24*time.Hour))
+	added, err := UpdateFeed(file, channel, []FeedEpisode{fox, bear})
+	if err != nil {
+		t.Fatalf("UpdateFeed() error = %v", err)
+	}
+	if added != 1 {
+		t.Errorf("added = %d, want only the bear", added)
+	}
+
+	_, items := readFeed(t, file)
+	guids := make([]string, 0)
+	for _, item := range items {
+		guids = append(guids, item.GUID)
+	}
+	if want := []string{"the-bear.mp3", "the-fox.mp3"}; !reflect.DeepEqual(guids, want) {
+		t.Fatalf("guids = %q, want %q", guids, want)
+	}
+	fox1, fox2 := first[1], items[1]
+	if fox1.GUID != "the-fox.mp3" || fox2.GUID != fox1.GUID || fox2.PubDate != fox1.PubDate {
+		t.Errorf("republished fox = %+v, want the guid and date of %+v", fox2, fox1)
+	}
+	if fox2.Enclosure.Length != 150 {
+		t.Errorf("fox enclosure length = %d, want the new size 150", fox2.Enclosure.Length)
+	}
+
+	// Unchanged episodes add nothing
+	if added, err := UpdateFeed(file, channel, []FeedEpisode{fox, bear}); err != nil || added != 0 {
+		t.Errorf("UpdateFeed() again = %d, %v, want nothing added", added, err)
+	}
+}
+
+func TestUpdateFeedBaseURL(t *testing.T) {
+	for _, base := range []string{"", "stories/", "/stories"} {
+		file := filepath.Join(t.TempDir(), "feed.xml")
+		if _, err := UpdateFeed(file, FeedChannel{BaseURL: base}, nil); err == nil {
+			t.Errorf("UpdateFeed() with base URL %q error = nil", base)
+		}
+		if _, err := os.Stat(file); err == nil {
+			t.Errorf("UpdateFeed() with base URL %q wrote a feed", base)
+		}
+	}
+}
+
+func TestWriteChapters(t *testing.T) {
+	s := story.Story{
+		Chapters: story.Chapters{{Number: 1, Title: "The Night"}, {Number: 2}},
+		Narration: &story.Narration{Duration: 90, Chunks: []story.NarrationChunk{
+			{Chapter: 1, Start: 0.5, End: 40},
+			{Chapter: 2, Start: 41, End: 89},
+		}},
+	}
+	file := filepath.Join(t.TempDir(), "chapters.json")
+	if err := WriteChapters(file, s); err != nil {
+		t.Fatalf("WriteChapters() error = %v", err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got podcastChapters
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := podcastChapters{Version: "1.2.0", Chapters: []podcastChapter{
+		{StartTime: 0, EndTime: 41, Title: "The Night"},
+		{StartTime: 41, EndTime: 90, Title: "Chapter 2"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteChapters() = %+v, want %+v", got, want)
+	}
+
+	if err := WriteChapters(file, story.Story{}); err == nil {
+		t.Error("WriteChapters() without narration error = nil")
+	}
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+//go:embed book.html
+var bookTemplate string
+
+type htmlChapter struct {
+	Number       int
+	Heading      string
+	Illustration template.URL
+	Paragraphs   []string
+}
+
+// HTML renders the book as a styled, printable HTML page. Images are embedded so the page
+// is a single file, printing puts the title page and every chapter on their own pages.
+func HTML(b Book) ([]byte, error) {
+	data := struct {
+		Title, Language, Summary string
+		Cover                    template.URL
+		Morales                  story.Morales
+		Chapters                 []htmlChapter
+		About                    []Fact
+	}{
+		Title:    b.Title(),
+		Language: b.Language.ISO1,
+		Summary:  strings.TrimSpace(b.Story.Summary),
+		Morales:  b.Story.Morales,
+	}
+
+	var err error
+	if b.Cover != "" {
+		if data.Cover, err = dataURL(b.Cover); err != nil {
+			return nil, fmt.Errorf("failed to read cover image: %w", err)
+		}
+	}
+	for _, c := range b.Story.Chapters {
+		chapter := htmlChapter{Number: c.Number, Heading: b.Heading(c), Paragraphs: b.Paragraphs(c)}
+		if image, ok := b.Illustrations[c.Number]; ok {
+			if chapter.Illustration, err = dataURL(image); err != nil {
+				return nil, fmt.Errorf("failed to read illustration: %w", err)
+			}
+		}
+		data.Chapters = append(data.Chapters, chapter)
+	}
+	if b.Appendix {
+		data.About = b.About()
+	}
+
+	tmpl, err := template.New("book").Parse(bookTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		return nil, fmt.Errorf("failed to render html book: %w", err)
+	}
+	return page.Bytes(), nil
+}
+
+// dataURL embeds an image file into the page.
+func dataURL(file string) (template.URL, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return template.URL("data:" + imageMimeType(file) + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
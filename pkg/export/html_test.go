@@ -0,0 +1,72 @@
+package export
+
+import (
+	"encoding/base64"
+	"html"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	b := testBook(t)
+	b.Appendix = true
+
+	data, err := HTML(b)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	page := string(data)
+
+	cover, err := os.ReadFile(b.Cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<html lang="en">`,
+		"<title>The Fox &amp; the Moon</title>",
+		`<p class="summary">A fox looks for the &lt;moon&gt;.</p>`,
+		`<section class="chapter" id="chapter-1">` + "\n<h2>The Night</h2>\n\n<p>Max woke up. The moon was gone.</p>\n<p>He went out.</p>\n</section>",
+		`<section class="chapter" id="chapter-2">` + "\n<h2>Chapter 2</h2>\n" + `<img class="illustration" src="data:image/png;base64,`,
+		"<dt>Protagonist</dt><dd>Max - fox, child</dd>",
+		"<dt>Villain</dt><dd>Grumblesnort, a grumpy troll</dd>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML() has no %s", want)
+		}
+	}
+	// The template escapes + in attributes, browsers read the same data URL
+	if want := `src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(cover) + `"`; !strings.Contains(html.UnescapeString(page), want) {
+		t.Errorf("HTML() has no embedded cover")
+	}
+	if strings.Contains(page, `class="morales"`) {
+		t.Errorf("HTML() has morales the story does not have")
+	}
+	if strings.Index(page, `id="chapter-1"`) > strings.Index(page, `id="chapter-2"`) {
+		t.Errorf("chapters are out of order")
+	}
+}
+
+func TestHTMLWithoutImagesAndAppendix(t *testing.T) {
+	b := testBook(t)
+	b.Cover = ""
+	b.Illustrations = nil
+
+	page, err := HTML(b)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	for _, unwanted := range []string{"<img", `class="about"`, "data:"} {
+		if strings.Contains(string(page), unwanted) {
+			t.Errorf("HTML() has %s", unwanted)
+		}
+	}
+}
+
+func TestHTMLMissingImage(t *testing.T) {
+	b := testBook(t)
+	b.Illustrations[1] = b.Cover + ".missing"
+	if _, err := HTML(b); err == nil {
+		t.Error("HTML() error = nil, want the missing illustration")
+	}
+}
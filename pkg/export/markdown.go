@@ -0,0 +1,76 @@
+package export
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownEscaper    = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+	markdownLineStarts = regexp.MustCompile(`^(#|>|-|\+|\d+\.)`)
+)
+
+// markdownText escapes characters Markdown would read as formatting.
+func markdownText(text string) string {
+	text = markdownEscaper.Replace(strings.TrimSpace(text))
+	return markdownLineStarts.ReplaceAllString(text, `\$1`)
+}
+
+// Markdown renders the book as clean Markdown: a title page with the summary and morales,
+// one section per chapter and the optional appendix. Images are linked relative to dir,
+// the directory the Markdown file is written to.
+func Markdown(b Book, dir string) string {
+	var md strings.Builder
+	md.WriteString("# " + markdownText(b.Title()) + "\n\n")
+	if b.Cover != "" {
+		md.WriteString(fmt.Sprintf("![%s](<%s>)\n\n", markdownText(b.Title()), relativePath(dir, b.Cover)))
+	}
+	if summary := strings.TrimSpace(b.Story.Summary); summary != "" {
+		md.WriteString("*" + markdownText(summary) + "*\n\n")
+	}
+	if len(b.Story.Morales) > 0 {
+		md.WriteString("**Morales:**\n\n")
+		for _, m := range b.Story.Morales {
+			md.WriteString("- **" + markdownText(m.Name) + "**")
+			if m.Description != "" {
+				md.WriteString(": " + markdownText(m.Description))
+			}
+			md.WriteString("\n")
+		}
+		md.WriteString("\n")
+	}
+
+	for _, c := range b.Story.Chapters {
+		md.WriteString("## " + markdownText(b.Heading(c)) + "\n\n")
+		if image, ok := b.Illustrations[c.Number]; ok {
+			md.WriteString(fmt.Sprintf("![](<%s>)\n\n", relativePath(dir, image)))
+		}
+		for _, p := range b.Paragraphs(c) {
+			md.WriteString(markdownText(p) + "\n\n")
+		}
+	}
+
+	if b.Appendix {
+		if facts := b.About(); len(facts) > 0 {
+			md.WriteString("---\n\n## About this story\n\n")
+			for _, f := range facts {
+				md.WriteString(fmt.Sprintf("- **%s:** %s\n", f.Label, markdownText(f.Value)))
+			}
+		}
+	}
+	return strings.TrimRight(md.String(), "\n") + "\n"
+}
+
+// relativePath links file from dir, falling back to the path as given.
+func relativePath(dir, file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if rel, err := filepath.Rel(absDir, abs); err == nil {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(file)
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+func TestMarkdown(t *testing.T) {
+	b := testBook(t)
+	b.Story.Morales = story.Morales{{Name: "Courage", Description: "Be *brave*"}, {Name: "Patience"}}
+	b.Story.Chapters = append(b.Story.Chapters, story.Chapter{Number: 3, Title: "# 1. The_End", Text: "- not a list\n> not a quote\n3. not a number [link](x)"})
+	b.Appendix = true
+
+	want := `# The Fox & the Moon
+
+![The Fox & the Moon](<cover.png>)
+
+*A fox looks for the \<moon>.*
+
+**Morales:**
+
+- **Courage**: Be \*brave\*
+- **Patience**
+
+## The Night
+
+Max woke up. The moon was gone.
+
+He went out.
+
+## Chapter 2
+
+![](<chapter_2.png>)
+
+Max found the moon in the river.
+
+## \# 1. The\_End
+
+\- not a list
+
+\> not a quote
+
+\3. not a number \[link\](x)
+
+---
+
+## About this story
+
+- **Location:** Dark forest
+- **Protagonist:** Max - fox, child
+- **Villain:** Grumblesnort, a grumpy troll
+`
+	if got := Markdown(b, filepath.Dir(b.Cover)); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarkdownWithoutAppendix(t *testing.T) {
+	b := testBook(t)
+	b.Cover = ""
+	b.Illustrations = nil
+
+	want := "# The Fox & the Moon\n\n*A fox looks for the \\<moon>.*\n\n" +
+		"## The Night\n\nMax woke up. The moon was gone.\n\nHe went out.\n\n" +
+		"## Chapter 2\n\nMax found the moon in the river.\n"
+	if got := Markdown(b, t.TempDir()); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
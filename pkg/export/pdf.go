@@ -0,0 +1,238 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	_ "golang.org/x/image/webp"
+)
+
+// pdfFont is the embedded Go font family, it covers Latin, Latvian, Cyrillic and Greek
+// and keeps easily confused letters like I, l and 1 apart.
+const pdfFont = "go"
+
+// PageLayout is a printable page size, the booklet sizes fold from A4 and Letter sheets.
+type PageLayout struct {
+	Name          string
+	Width, Height float64 // Millimetres
+	Margin        float64
+	BodySize      float64 // Points
+}
+
+var pageLayouts = []PageLayout{
+	{Name: "a4", Width: 210, Height: 297, Margin: 22, BodySize: 13},
+	{Name: "letter", Width: 215.9, Height: 279.4, Margin: 22, BodySize: 13},
+	{Name: "a5", Width: 148, Height: 210, Margin: 15, BodySize: 11.5},
+	{Name: "half-letter", Width: 139.7, Height: 215.9, Margin: 15, BodySize: 11.5},
+}
+
+// FindPageLayout returns the named page layout, "booklet" is A5.
+func FindPageLayout(name string) (PageLayout, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "":
+		name = "a4"
+	case "booklet":
+		name = "a5"
+	}
+	names := make([]string, 0, len(pageLayouts))
+	for _, l := range pageLayouts {
+		if l.Name == name {
+			return l, nil
+		}
+		names = append(names, l.Name)
+	}
+	return PageLayout{}, fmt.Errorf("unknown page layout %q, use %s or booklet", name, strings.Join(names, ", "))
+}
+
+// PDFOptions selects the page layout and typography.
+type PDFOptions struct {
+	Layout PageLayout
+	// Dyslexic uses large left aligned type, wide line spacing, no italics and a cream page
+	Dyslexic bool
+}
+
+// pdfStyle is the typography derived from the options.
+type pdfStyle struct {
+	body, line, paragraph float64 // Points, line height and paragraph gap in mm
+	align                 string
+	emphasis              string // Font style for the summary
+}
+
+func newPDFStyle(opts PDFOptions) pdfStyle {
+	if opts.Dyslexic {
+		size := opts.Layout.BodySize * 1.35
+		return pdfStyle{body: size, line: size * 0.3528 * 1.8, paragraph: size * 0.3528, align: "L", emphasis: ""}
+	}
+	size := opts.Layout.BodySize
+	return pdfStyle{body: size, line: size * 0.3528 * 1.45, paragraph: size * 0.3528 * 0.5, align: "J", emphasis: "I"}
+}
+
+// WritePDF writes the book as a printable picture book: a title page with the cover, summary and
+// morales, every chapter on a new page with its illustration, and the optional appendix.
+// Fonts are embedded, no external programs are needed.
+func WritePDF(file string, b Book, opts PDFOptions) error {
+	layout, style := opts.Layout, newPDFStyle(opts)
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: layout.Width, Ht: layout.Height},
+	})
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "I", goitalic.TTF)
+	pdf.SetMargins(layout.Margin, layout.Margin, layout.Margin)
+	pdf.SetAutoPageBreak(true, layout.Margin+5)
+	pdf.SetTitle(b.Title(), true)
+	pdf.SetCreator("storygen", true)
+	if b.Language.ISO1 != "" {
+		pdf.SetLang(b.Language.ISO1)
+	}
+	if b.Story.Summary != "" {
+		pdf.SetSubject(strings.TrimSpace(b.Story.Summary), true)
+	}
+
+	if opts.Dyslexic {
+		pdf.SetHeaderFunc(func() {
+			pdf.SetFillColor(253, 248, 232)
+			pdf.Rect(0, 0, layout.Width, layout.Height, "F")
+			pdf.SetY(layout.Margin)
+		})
+	}
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() == 1 {
+			return
+		}
+		pdf.SetY(-layout.Margin + 5)
+		pdf.SetFont(pdfFont, "", style.body*0.75)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.SetTextColor(40, 40, 40)
+	width := layout.Width - 2*layout.Margin
+
+	// Title page
+	pdf.AddPage()
+	if b.Cover != "" {
+		pdfImage(pdf, b.Cover, width, layout.Height*0.4)
+		pdf.Ln(8)
+	} else {
+		pdf.SetY(layout.Height * 0.3)
+	}
+	pdf.SetFont(pdfFont, "B", style.body*2.2)
+	pdf.MultiCell(0, style.body*2.2*0.3528*1.3, b.Title(), "", "C", false)
+	pdf.Bookmark(b.Title(), 0, -1)
+	pdf.Ln(6)
+	if summary := strings.TrimSpace(b.Story.Summary); summary != "" {
+		pdf.SetFont(pdfFont, style.emphasis, style.body*1.1)
+		pdf.MultiCell(0, style.line*1.1, summary, "", "C", false)
+		pdf.Ln(6)
+	}
+	if len(b.Story.Morales) > 0 {
+		pdf.SetFont(pdfFont, "B", style.body)
+		pdf.MultiCell(0, style.line, "Morales", "", "C", false)
+		for _, m := range b.Story.Morales {
+			text := m.Name
+			if m.Description != "" {
+				text += ": " + m.Description
+			}
+			pdf.SetFont(pdfFont, "", style.body*0.9)
+			pdf.MultiCell(0, style.line*0.9, text, "", "C", false)
+		}
+	}
+
+	// Chapters
+	for _, c := range b.Story.Chapters {
+		pdf.AddPage()
+		pdf.SetFont(pdfFont, "B", style.body*1.6)
+		pdf.MultiCell(0, style.body*1.6*0.3528*1.4, b.Heading(c), "", "C", false)
+		pdf.Bookmark(b.Heading(c), 1, -1)
+		pdf.Ln(style.paragraph * 2)
+		if image, ok := b.Illustrations[c.Number]; ok {
+			pdfImage(pdf, image, width, layout.Height*0.35)
+			pdf.Ln(style.paragraph * 2)
+		}
+		pdf.SetFont(pdfFont, "", style.body)
+		for _, p := range b.Paragraphs(c) {
+			pdf.MultiCell(0, style.line, p, "", style.align, false)
+			pdf.Ln(style.paragraph)
+		}
+	}
+
+	if b.Appendix {
+		if facts := b.About(); len(facts) > 0 {
+			pdf.AddPage()
+			pdf.SetFont(pdfFont, "B", style.body*1.4)
+			pdf.MultiCell(0, style.line*1.4, "About this story", "", "L", false)
+			pdf.Bookmark("About this story", 1, -1)
+			pdf.Ln(style.paragraph * 2)
+			for _, f := range facts {
+				pdf.SetFont(pdfFont, "B", style.body)
+				pdf.MultiCell(0, style.line, f.Label, "", "L", false)
+				pdf.SetFont(pdfFont, "", style.body)
+				pdf.MultiCell(0, style.line, f.Value, "", "L", false)
+				pdf.Ln(style.paragraph)
+			}
+		}
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to lay out pdf: %w", err)
+	}
+	return pdf.OutputFileAndClose(file)
+}
+
+// pdfImage places an image centred in the current line, scaled down to fit the box.
+// Images fpdf cannot read natively are converted to PNG, unreadable ones are skipped.
+func pdfImage(pdf *fpdf.Fpdf, file string, maxWidth, maxHeight float64) {
+	imageType := strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	var info *fpdf.ImageInfoType
+	switch imageType {
+	case "jpg", "jpeg", "png", "gif":
+		info = pdf.RegisterImageOptions(file, fpdf.ImageOptions{ImageType: imageType, ReadDpi: true})
+	default:
+		data, err := toPNG(file)
+		if err != nil {
+			fmt.Printf("Warning: skipping image %s: %v\n", file, err)
+			return
+		}
+		info = pdf.RegisterImageOptionsReader(file, fpdf.ImageOptions{ImageType: "png"}, bytes.NewReader(data))
+	}
+	if pdf.Err() || info == nil {
+		return
+	}
+
+	w, h := info.Extent()
+	scale := min(maxWidth/w, maxHeight/h)
+	w, h = w*scale, h*scale
+	left, _, _, _ := pdf.GetMargins()
+	pdf.ImageOptions(file, left+(maxWidth-w)/2, pdf.GetY(), w, h, true, fpdf.ImageOptions{}, 0, "")
+}
+
+// toPNG converts images the Go decoders read, like WebP, to PNG.
+func toPNG(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestFindPageLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: "a4"},
+		{name: "A4", want: "a4"},
+		{name: " letter ", want: "letter"},
+		{name: "booklet", want: "a5"},
+		{name: "half-letter", want: "half-letter"},
+		{name: "a3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindPageLayout(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindPageLayout(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got.Name != tt.want {
+				t.Errorf("FindPageLayout(%q) = %q, want %q", tt.name, got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFStyle(t *testing.T) {
+	layout, _ := FindPageLayout("a5")
+	regular := newPDFStyle(PDFOptions{Layout: layout})
+	dyslexic := newPDFStyle(PDFOptions{Layout: layout, Dyslexic: true})
+
+	if regular.align != "J" || regular.emphasis != "I" || regular.body != layout.BodySize {
+		t.Errorf("regular style = %+v, want justified body text with an italic summary", regular)
+	}
+	if dyslexic.align != "L" || dyslexic.emphasis != "" {
+		t.Errorf("dyslexic style = %+v, want left aligned text without italics", dyslexic)
+	}
+	if dyslexic.body <= regular.body || dyslexic.line/dyslexic.body <= regular.line/regular.body {
+		t.Errorf("dyslexic style = %+v, want larger type with wider line spacing than %+v", dyslexic, regular)
+	}
+}
+
+// pdfString encodes text the way fpdf writes UTF-8 strings, UTF-16 with a byte order mark.
+func pdfString(text string) []byte {
+	b := []byte{0xFE, 0xFF}
+	for _, r := range utf16.Encode([]rune(text)) {
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return b
+}
+
+func TestWritePDF(t *testing.T) {
+	tests := []struct {
+		layout   string
+		appendix bool
+		mediaBox string
+		pages    int
+	}{
+		{layout: "a4", mediaBox: "[0 0 595.28 841.89]", pages: 3},
+		{layout: "letter", appendix: true, mediaBox: "[0 0 612.00 792.00]", pages: 4},
+		{layout: "booklet", mediaBox: "[0 0 419.53 595.28]", pages: 3},
+		{layout: "half-letter", appendix: true, mediaBox: "[0 0 396.00 612.00]", pages: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			b := testBook(t)
+			b.Appendix = tt.appendix
+			layout, err := FindPageLayout(tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file := filepath.Join(t.TempDir(), "story.pdf")
+			if err := WritePDF(file, b, PDFOptions{Layout: layout}); err != nil {
+				t.Fatalf("WritePDF() error = %v", err)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(data, []byte("%PDF-")) {
+				t.Fatalf("not a pdf")
+			}
+			if got := len(regexp.MustCompile(`/Type /Page\b`).FindAll(data, -1)); got != tt.pages {
+				t.Errorf("got %d pages, want %d", got, tt.pages)
+			}
+			if !bytes.Contains(data, []byte("/MediaBox "+tt.mediaBox)) {
+				t.Errorf("pdf has no /MediaBox %s", tt.mediaBox)
+			}
+			if got := len(regexp.MustCompile(`/Subtype /Image`).FindAll(data, -1)); got != 2 {
+				t.Errorf("got %d images, want the cover and the illustration", got)
+			}
+			if !bytes.Contains(data, []byte("/Lang (en)")) {
+				t.Errorf("pdf has no language")
+			}
+			for _, bookmark := range []string{"The Fox & the Moon", "The Night", "Chapter 2"} {
+				if !bytes.Contains(data, pdfString(bookmark)) {
+					t.Errorf("pdf has no bookmark %q", bookmark)
+				}
+			}
+			if got := bytes.Contains(data, pdfString("About this story")); got != tt.appendix {
+				t.Errorf("appendix bookmark = %v, want %v", got, tt.appendix)
+			}
+		})
+	}
+}
+
+func TestWritePDFSkipsUnreadableImages(t *testing.T) {
+	b := testBook(t)
+	b.Illustrations[1] = filepath.Join(t.TempDir(), "chapter_1.webp")
+	if err := os.WriteFile(b.Illustrations[1], []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	layout, _ := FindPageLayout("a4")
+
+	file := filepath.Join(t.TempDir(), "story.pdf")
+	if err := WritePDF(file, b, PDFOptions{Layout: layout, Dyslexic: true}); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(regexp.MustCompile(`/Subtype /Image`).FindAll(data, -1)); got != 2 {
+		t.Errorf("got %d images, want the readable cover and illustration", got)
+	}
+}
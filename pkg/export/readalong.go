@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/tts"
+)
+
+//go:embed readalong.html
+var readAlongTemplate string
+
+// Sentence is a piece of story text with the time it is narrated at, in seconds.
+type Sentence struct {
+	Text       string
+	Start, End float64
+}
+
+// Section is a chapter with its paragraphs of timed sentences.
+type Section struct {
+	Number     int
+	Heading    Sentence
+	Paragraphs [][]Sentence
+}
+
+// TimedStory is the story text placed on the narration timeline.
+type TimedStory struct {
+	Title    Sentence
+	Language string
+	Sections []Section
+}
+
+// AlignStory splits the story into sentences and finds when each of them is narrated.
+// The narration text differs from the story text (numbers are spelled out, headings get
+// a chapter label), so sentences are matched to the narration by their words.
+func AlignStory(s story.Story, timings story.Timings, segmenter *tts.Segmenter) TimedStory {
+	book := Book{Story: s}
+	timed := TimedStory{Title: Sentence{Text: book.Title()}}
+	for i, c := range s.Chapters {
+		// The title is narrated at the start of the first chapter
+		sentences := []string{book.Heading(c)}
+		if i == 0 {
+			sentences = append([]string{timed.Title.Text}, sentences...)
+		}
+		sizes := make([]int, 0)
+		for _, paragraph := range book.Paragraphs(c) {
+			split := segmenter.Sentences(paragraph)
+			sentences = append(sentences, split...)
+			sizes = append(sizes, len(split))
+		}
+
+		aligned := toSentences(sentences, tts.AlignSentences(sentences, timings.Words(i+1)))
+		if i == 0 {
+			timed.Title, aligned = aligned[0], aligned[1:]
+		}
+		section := Section{Number: c.Number, Heading: aligned[0]}
+		aligned = aligned[1:]
+		for _, size := range sizes {
+			section.Paragraphs = append(section.Paragraphs, aligned[:size])
+			aligned = aligned[size:]
+		}
+		timed.Sections = append(timed.Sections, section)
+	}
+	return timed
+}
+
+// toSentences keeps the original text, untimed sentences get no times when the chapter was not narrated.
+func toSentences(texts []string, aligned []story.SentenceTiming) []Sentence {
+	sentences := make([]Sentence, len(texts))
+	for i, text := range texts {
+		sentences[i].Text = text
+		if i < len(aligned) {
+			sentences[i].Start, sentences[i].End = aligned[i].Start, aligned[i].End
+		}
+	}
+	return sentences
+}
+
+// ReadAlong renders a self-contained HTML page with the story text and its narration embedded.
+// The sentence being read is highlighted and clicking a sentence plays from it.
+func ReadAlong(timed TimedStory, audioFile string) ([]byte, error) {
+	data, err := os.ReadFile(audioFile)
+	if err != nil {
+		return nil, err
+	}
+	format, err := audio.DetectFile(audioFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("readalong").Parse(readAlongTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, struct {
+		TimedStory
+		Audio template.URL
+	}{
+		TimedStory: timed,
+		Audio:      template.URL("data:" + format.MimeType() + ";base64," + base64.StdEncoding.EncodeToString(data)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render read-along page: %w", err)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,41 @@
+package story
+
+// Casting records which TTS voice reads the narration and each character.
+// It is stored with the story so re-narrating it keeps the same voices.
+type Casting struct {
+	Provider string `json:"provider"`
+	Narrator string `json:"narrator"`
+	Roles    []Role `json:"roles"`
+}
+
+// Role is a story character and the voice cast for it.
+type Role struct {
+	Character   string `json:"character"`
+	Voice       string `json:"voice"`
+	Description string `json:"description"` // How the character sounds, passed to the TTS instructions
+}
+
+// Role returns the role of the named character.
+func (c *Casting) Role(character string) (Role, bool) {
+	if c == nil {
+		return Role{}, false
+	}
+	for _, r := range c.Roles {
+		if r.Character == character {
+			return r, true
+		}
+	}
+	return Role{}, false
+}
+
+// Characters returns the names of all cast characters.
+func (c *Casting) Characters() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.Roles))
+	for _, r := range c.Roles {
+		names = append(names, r.Character)
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package story
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Narration markup marks pauses, emphasis and headings in text prepared for TTS.
+// Tags contain no spaces so sentence splitting never cuts through them:
+//
+//	[pause:1.5s] [emphasis]word[/emphasis] [heading]Chapter 1. Title.[/heading]
+const (
+	EmphasisOpen  = "[emphasis]"
+	EmphasisClose = "[/emphasis]"
+	HeadingOpen   = "[heading]"
+	HeadingClose  = "[/heading]"
+)
+
+// MarkupTag matches every markup tag, the pause length is captured.
+var MarkupTag = regexp.MustCompile(`\[pause:([0-9.]+m?s)\]|\[/?emphasis\]|\[/?heading\]`)
+
+// Pause returns markup for a pause of the given length.
+func Pause(d time.Duration) string {
+	return fmt.Sprintf("[pause:%ss]", strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+}
+
+func Emphasis(text string) string {
+	return EmphasisOpen + text + EmphasisClose
+}
+
+func Heading(text string) string {
+	return HeadingOpen + text + HeadingClose
+}
+
+// StripMarkup returns text without markup tags.
+func StripMarkup(text string) string {
+	return MarkupTag.ReplaceAllString(text, "")
+}
+
+// Pauses between the parts of a narrated story.
+const (
+	PauseAfterTitle   = 1500 * time.Millisecond
+	PauseAfterHeading = time.Second
+	PauseChapter      = 2 * time.Second
+)
+
+// markdownEmphasis matches *word* and **words** the LLM sometimes writes.
+var markdownEmphasis = regexp.MustCompile(`\*{1,2}([^*\n]+?)\*{1,2}`)
+
+// BuildNarration returns the story text with narration markup: the title and chapter titles are
+// headings, pauses separate chapters and markdown emphasis becomes spoken emphasis.
+// BuildContent stays the plain text version used for reading and prompts.
+func (s *Story) BuildNarration(chapterLabel, theEnd string) string {
+	content := make([]string, 0)
+
+	storyTitle := removeChars(s.Title)
+	storyTitle = strings.TrimPrefix(storyTitle, "Title:")
+	storyTitle = strings.TrimSpace(storyTitle)
+	if storyTitle != "" {
+		content = append(content, Heading(storyTitle)+Pause(PauseAfterTitle))
+	}
+
+	for i, c := range s.Chapters {
+		header := fmt.Sprintf("%s %d.", chapterLabel, c.Number)
+		if title := strings.TrimSpace(removeChars(c.Title)); title != "" {
+			header += " " + strings.TrimRight(title, ".?!:;,") + "."
+		}
+		content = append(content, Heading(header)+Pause(PauseAfterHeading))
+
+		chapterText := trimChapterTitleFromText(c)
+		chapterText = markdownEmphasis.ReplaceAllString(chapterText, EmphasisOpen+"${1}"+EmphasisClose)
+		chapterText = removeChars(chapterText)
+		chapterText = newlineNormalizerRegex.ReplaceAllString(chapterText, "\n")
+		chapterText = strings.TrimSpace(chapterText)
+		if chapterText != "" {
+			content = append(content, chapterText)
+		}
+
+		if i < len(s.Chapters)-1 {
+			content = append(content, Pause(PauseChapter))
+		}
+	}
+
+	content = append(content, Pause(PauseChapter))
+	content = append(content, theEnd)
+
+	// RemoveEmojis collapses newlines, paragraphs are kept for the chapter split and the segmenter
+	for i, part := range content {
+		lines := strings.Split(part, "\n")
+		for k, line := range lines {
+			lines[k] = RemoveEmojis(line)
+		}
+		content[i] = strings.Join(lines, "\n")
+	}
+
+	return strings.Join(content, "\n\n")
+}
@@ -0,0 +1,14 @@
+package story
+
+import "fmt"
+
+// Mood describes how a chunk of the story should be performed.
+type Mood struct {
+	Mood      string `json:"mood"`      // e.g. tense, cheerful, sleepy
+	Intensity int    `json:"intensity"` // 1 (calm) to 5 (most intense)
+	Pacing    string `json:"pacing"`    // e.g. slow, steady, fast
+}
+
+func (m Mood) String() string {
+	return fmt.Sprintf("%s, intensity %d of 5, %s pacing", m.Mood, m.Intensity, m.Pacing)
+}
@@ -0,0 +1,98 @@
+package story
+
+import (
+	"math"
+
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Narration records how a story was turned into audio.
+type Narration struct {
+	File     string           `json:"file"`
+	Voice    string           `json:"voice"`
+	Speed    float64          `json:"speed"`
+	Loudness *Loudness        `json:"loudness,omitempty"`
+	Duration float64          `json:"duration,omitempty"` // Seconds, only known when joined natively
+	Chunks   []NarrationChunk `json:"chunks"`
+}
+
+// Loudness is an EBU R128 measurement of narrated audio.
+type Loudness struct {
+	IntegratedLUFS float64 `json:"integrated_lufs"`
+	TruePeakDBTP   float64 `json:"true_peak_dbtp"`
+}
+
+type NarrationChunk struct {
+	Chapter  int       `json:"chapter"`
+	Index    int       `json:"index"`
+	Speaker  string    `json:"speaker,omitempty"`
+	Text     string    `json:"text"`
+	Mood     *Mood     `json:"mood,omitempty"`
+	Loudness *Loudness `json:"loudness,omitempty"`
+	Attempts int       `json:"attempts,omitempty"`
+	Problems []string  `json:"qa_problems,omitempty"`
+	// Position of the chunk audio in the narration file in seconds
+	Start float64 `json:"start,omitempty"`
+	End   float64 `json:"end,omitempty"`
+	// Words transcribed from the chunk audio, positioned in the narration file
+	Words []TranscriptWord `json:"words,omitempty"`
+}
+
+// ChapterMarker is where a chapter starts and ends in the narration file, in seconds.
+type ChapterMarker struct {
+	Chapter int
+	Start   float64
+	End     float64
+}
+
+// HasTimings reports whether the chunk positions in the narration file are known.
+func (n *Narration) HasTimings() bool {
+	return n.Duration > 0 && len(n.Chunks) > 0 && n.Chunks[len(n.Chunks)-1].End > 0
+}
+
+// ChapterMarkers returns the chapters of the narration file, nil without timings.
+// The first chapter starts with the file, every chapter ends where the next one starts.
+func (n *Narration) ChapterMarkers() []ChapterMarker {
+	if !n.HasTimings() {
+		return nil
+	}
+
+	markers := make([]ChapterMarker, 0)
+	for _, c := range n.Chunks {
+		if len(markers) > 0 && markers[len(markers)-1].Chapter == c.Chapter {
+			continue
+		}
+		start := c.Start
+		if len(markers) == 0 {
+			start = 0
+		} else {
+			markers[len(markers)-1].End = start
+		}
+		markers = append(markers, ChapterMarker{Chapter: c.Chapter, Start: start})
+	}
+	markers[len(markers)-1].End = n.Duration
+
+	return markers
+}
+
+// Retime updates the narration for audio played speed times faster.
+func (n *Narration) Retime(speed float64) {
+	scale := func(seconds float64) float64 {
+		return math.Round(seconds/speed*1000) / 1000
+	}
+
+	n.Speed = math.Round(n.Speed*speed*100) / 100
+	n.Duration = scale(n.Duration)
+	for i := range n.Chunks {
+		n.Chunks[i].Start = scale(n.Chunks[i].Start)
+		n.Chunks[i].End = scale(n.Chunks[i].End)
+		for k := range n.Chunks[i].Words {
+			n.Chunks[i].Words[k].Start = scale(n.Chunks[i].Words[k].Start)
+			n.Chunks[i].Words[k].End = scale(n.Chunks[i].Words[k].End)
+		}
+	}
+}
+
+func (n *Narration) ToJson() string {
+	return utils.ToJsonStr(n)
+}
@@ -0,0 +1,84 @@
+package story
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChapterMarkers(t *testing.T) {
+	tests := []struct {
+		name      string
+		narration Narration
+		want      []ChapterMarker
+	}{
+		{
+			name: "no timings",
+			narration: Narration{Chunks: []NarrationChunk{
+				{Chapter: 1, Index: 0},
+				{Chapter: 2, Index: 0},
+			}},
+			want: nil,
+		},
+		{
+			name: "chapters end where the next one starts",
+			narration: Narration{Duration: 30, Chunks: []NarrationChunk{
+				{Chapter: 1, Index: 0, Start: 0.5, End: 5},
+				{Chapter: 1, Index: 1, Start: 5.2, End: 10},
+				{Chapter: 2, Index: 0, Start: 11, End: 19},
+				{Chapter: 3, Index: 0, Start: 20, End: 28},
+			}},
+			want: []ChapterMarker{
+				{Chapter: 1, Start: 0, End: 11},
+				{Chapter: 2, Start: 11, End: 20},
+				{Chapter: 3, Start: 20, End: 30},
+			},
+		},
+		{
+			name: "one chapter lasts the whole file",
+			narration: Narration{Duration: 12.5, Chunks: []NarrationChunk{
+				{Chapter: 1, Index: 0, Start: 1, End: 6},
+				{Chapter: 1, Index: 1, Start: 6, End: 11},
+			}},
+			want: []ChapterMarker{{Chapter: 1, Start: 0, End: 12.5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.narration.ChapterMarkers()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChapterMarkers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetime(t *testing.T) {
+	n := Narration{Speed: 1, Duration: 30, Chunks: []NarrationChunk{
+		{Chapter: 1, Index: 0, Start: 0, End: 10, Words: []TranscriptWord{{Word: "Once", Start: 1, End: 1.5}}},
+		{Chapter: 2, Index: 0, Start: 12, End: 27},
+	}}
+
+	n.Retime(1.5)
+
+	if n.Speed != 1.5 {
+		t.Errorf("Speed = %v, want 1.5", n.Speed)
+	}
+	if n.Duration != 20 {
+		t.Errorf("Duration = %v, want 20", n.Duration)
+	}
+	if c := n.Chunks[1]; c.Start != 8 || c.End != 18 {
+		t.Errorf("chunk = %v-%v, want 8-18", c.Start, c.End)
+	}
+	if w := n.Chunks[0].Words[0]; w.Start != 0.667 || w.End != 1 {
+		t.Errorf("word = %v-%v, want 0.667-1", w.Start, w.End)
+	}
+
+	want := []ChapterMarker{
+		{Chapter: 1, Start: 0, End: 8},
+		{Chapter: 2, Start: 8, End: 20},
+	}
+	if got := n.ChapterMarkers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChapterMarkers() after Retime = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package story
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// SchemaVersion is the story JSON layout this version writes.
+// Files written before stories carried a version are version 1.
+const SchemaVersion = 3
+
+// JSONSchema describes the story JSON of SchemaVersion for other tools.
+//
+//go:embed story.schema.json
+var JSONSchema []byte
+
+// migrations upgrade a decoded story JSON from the version they are keyed by to the next one.
+var migrations = map[int]func(doc map[string]any) error{
+	1: migrateFromV1,
+	2: migrateFromV2,
+}
+
+// migrateFromV1 upgrades unversioned files. They already have the version 2 layout,
+// only the version Migrate sets is missing.
+func migrateFromV1(map[string]any) error {
+	return nil
+}
+
+// migrateFromV2 gives the story its ID. Stories from before IDs get one derived from the texts
+// grooming keeps, so every revision of the original gets the same one when loaded. Translations
+// of that time only carry translated texts and get a different one, workspace.LegacyOriginalID
+// finds the ID of their original.
+func migrateFromV2(doc map[string]any) error {
+	if id, _ := doc["id"].(string); id != "" {
+		return nil
+	}
+	h := sha1.New()
+	for _, key := range []string{"title", "story_prompt", "summary", "plan"} {
+		text, _ := doc[key].(string)
+		h.Write([]byte(text + "\x00"))
+	}
+	doc["id"] = hex.EncodeToString(h.Sum(nil))[:idLength]
+	return nil
+}
+
+// idLength is the number of hex digits in a story ID.
+const idLength = 12
+
+// idPattern is what a story ID may contain, it is used as a directory name.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// NewID returns a random story ID.
+func NewID() string {
+	b := make([]byte, idLength/2)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MarshalJSON stamps the current schema version, stories are always written in the latest layout.
+func (s Story) MarshalJSON() ([]byte, error) {
+	type plain Story
+	p := plain(s)
+	p.Version = SchemaVersion
+	return json.Marshal(p)
+}
+
+// Migrate upgrades a story JSON to SchemaVersion. It returns the upgraded JSON and the version
+// the data was in, data is returned as is when it already is current.
+func Migrate(data []byte) ([]byte, int, error) {
+	doc := make(map[string]any)
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, describeJSONError(data, err, true)
+	}
+
+	version := 1
+	if v, ok := doc["version"]; ok {
+		n, isNumber := v.(float64)
+		if !isNumber || n < 1 || n != math.Trunc(n) {
+			return nil, 0, fmt.Errorf("version must be a positive whole number, got %v", v)
+		}
+		version = int(n)
+	}
+	if version > SchemaVersion {
+		return nil, version, fmt.Errorf("version %d is newer than this storygen reads (%d), update storygen", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return data, version, nil
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return nil, version, fmt.Errorf("failed to migrate from version %d to %d: %w", v, v+1, err)
+		}
+		doc["version"] = v + 1
+	}
+	migrated, err := json.Marshal(doc)
+	return migrated, version, err
+}
+
+// Parse reads a story JSON of any known version. Unknown fields, wrong types and
+// stories failing Validate are rejected.
+func Parse(data []byte) (Story, error) {
+	migrated, version, err := Migrate(data)
+	if err != nil {
+		return Story{}, err
+	}
+
+	s := Story{}
+	decoder := json.NewDecoder(bytes.NewReader(migrated))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&s); err != nil {
+		return Story{}, describeJSONError(migrated, err, version == SchemaVersion)
+	}
+	if decoder.More() {
+		return Story{}, fmt.Errorf("unexpected data after the story object")
+	}
+	if err := s.Validate(); err != nil {
+		return Story{}, err
+	}
+	return s, nil
+}
+
+// Load reads and migrates a story JSON file.
+func Load(file string) (Story, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Story{}, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return Story{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return s, nil
+}
+
+// arrayIndex matches the array indexes in encoding/json field paths like chapters.0.number.
+var arrayIndex = regexp.MustCompile(`\.(\d+)`)
+
+// describeJSONError names the field and, when the offset points into the original file, the line and column.
+func describeJSONError(data []byte, err error, located bool) error {
+	position := func(offset int64) string {
+		if !located {
+			return ""
+		}
+		before := data[:min(int(offset), len(data))]
+		line := bytes.Count(before, []byte("\n")) + 1
+		column := len(before) - bytes.LastIndexByte(before, '\n')
+		return fmt.Sprintf(" at line %d, column %d", line, column)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON%s: %s", position(syntaxErr.Offset), syntaxErr.Error())
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		field := arrayIndex.ReplaceAllString(typeErr.Field, "[$1]")
+		return fmt.Errorf("%s must be %s, got %s%s", field, typeErr.Type, typeErr.Value, position(typeErr.Offset))
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("story JSON must be an object, got %s", typeErr.Value)
+	}
+	return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+}
+
+// ValidationError lists everything wrong with a story.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid story: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks what the JSON types cannot: required texts, chapter numbering and narration timings.
+func (s *Story) Validate() error {
+	problems := make([]string, 0)
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if s.ID == "" {
+		add("id is empty")
+	} else if !idPattern.MatchString(s.ID) {
+		add("id %q must be lowercase letters, digits, - and _", s.ID)
+	}
+	if strings.TrimSpace(s.Title) == "" {
+		add("title is empty")
+	}
+	if len(s.Chapters) == 0 {
+		add("chapters is empty")
+	}
+	numbers := make(map[int]int)
+	for i, c := range s.Chapters {
+		if c.Number < 1 {
+			add("chapters[%d].number must be positive, got %d", i, c.Number)
+		} else if j, ok := numbers[c.Number]; ok {
+			add("chapters[%d].number %d is already used by chapters[%d]", i, c.Number, j)
+		} else {
+			numbers[c.Number] = i
+		}
+		if strings.TrimSpace(c.Text) == "" {
+			add("chapters[%d].text is empty", i)
+		}
+	}
+	for i, p := range s.Protagonists {
+		if strings.TrimSpace(p.Name) == "" {
+			add("protagonists[%d].name is empty", i)
+		}
+	}
+	for i, m := range s.Morales {
+		if strings.TrimSpace(m.Name) == "" {
+			add("morales[%d].name is empty", i)
+		}
+	}
+
+	if s.Casting != nil {
+		for i, r := range s.Casting.Roles {
+			if r.Character == "" || r.Voice == "" {
+				add("casting.roles[%d] needs a character and a voice", i)
+			}
+		}
+	}
+
+	if n := s.Narration; n != nil {
+		if n.File == "" {
+			add("narration.file is empty")
+		}
+		if n.Speed < 0 || n.Duration < 0 {
+			add("narration speed and duration must not be negative")
+		}
+		for i, c := range n.Chunks {
+			if c.Chapter < 0 || c.Index < 0 {
+				add("narration.chunks[%d] chapter and index must not be negative", i)
+			}
+			if c.End < c.Start {
+				add("narration.chunks[%d] ends at %.3fs before it starts at %.3fs", i, c.End, c.Start)
+			} else if n.Duration > 0 && c.Start > n.Duration {
+				add("narration.chunks[%d] starts at %.3fs after the %.3fs narration ends", i, c.Start, n.Duration)
+			}
+			if c.Mood != nil && (c.Mood.Intensity < 1 || c.Mood.Intensity > 5) {
+				add("narration.chunks[%d].mood.intensity must be 1 to 5, got %d", i, c.Mood.Intensity)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package story
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantVersion int
+		wantID      string
+		wantErr     string
+	}{
+		{
+			name:        "current",
+			data:        `{"version":3,"id":"1a2b3c4d5e6f","title":"The Fox"}`,
+			wantVersion: 3,
+			wantID:      "1a2b3c4d5e6f",
+		},
+		{
+			name:        "version 2 keeps its id",
+			data:        `{"version":2,"id":"1a2b3c4d5e6f","title":"The Fox"}`,
+			wantVersion: 2,
+			wantID:      "1a2b3c4d5e6f",
+		},
+		{
+			name:        "unversioned gets an id",
+			data:        `{"title":"The Fox","story_prompt":"a fox","summary":"A fox.","plan":"1. Night"}`,
+			wantVersion: 1,
+			// Derived from the title, prompt, summary and plan, workspaces of migrated stories are named by it
+			wantID: "76586080878b",
+		},
+		{
+			name:    "newer",
+			data:    `{"version":4,"title":"The Fox"}`,
+			wantErr: "newer than this storygen reads",
+		},
+		{
+			name:    "version is not a number",
+			data:    `{"version":"3","title":"The Fox"}`,
+			wantErr: "version must be a positive whole number",
+		},
+		{
+			name:    "fractional version",
+			data:    `{"version":2.5,"title":"The Fox"}`,
+			wantErr: "version must be a positive whole number",
+		},
+		{
+			name:    "not json",
+			data:    "{\n\"title\": \"The Fox\",\n}",
+			wantErr: "invalid JSON at line 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, version, err := Migrate([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Migrate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Migrate() version = %d, want %d", version, tt.wantVersion)
+			}
+			var doc struct {
+				Version int    `json:"version"`
+				ID      string `json:"id"`
+			}
+			if err := json.Unmarshal(migrated, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.Version != SchemaVersion || doc.ID != tt.wantID {
+				t.Errorf("Migrate() = version %d, id %q, want version %d, id %q", doc.Version, doc.ID, SchemaVersion, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMigrateRevisionsShareTheID(t *testing.T) {
+	written := `{"title":"The Fox","story_prompt":"a fox","summary":"A fox.","plan":"1. Night","chapters":[{"number":1,"title":"","text":"Once."}]}`
+	groomed := `{"title":"The Fox","story_prompt":"a fox","summary":"A fox.","plan":"1. Night","chapters":[{"number":1,"title":"Night","text":"Once upon a time."}]}`
+	translated := `{"title":"Lapsa","chapters":[{"number":1,"title":"Nakts","text":"Reiz sen."}]}`
+
+	a, err := Parse([]byte(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse([]byte(groomed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == "" || a.ID != b.ID {
+		t.Errorf("revisions got ids %q and %q, want the same", a.ID, b.ID)
+	}
+
+	c, err := Parse([]byte(translated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID == "" || c.ID == a.ID {
+		t.Errorf("translation id = %q, a legacy translation cannot derive the id %q of its original", c.ID, a.ID)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantErr      string
+		wantProblems []string
+	}{
+		{
+			name: "valid",
+			data: `{"version":3,"id":"1a2b3c4d5e6f","title":"The Fox","chapters":[{"number":1,"title":"","text":"Once."}]}`,
+		},
+		{
+			name:    "unknown field",
+			data:    `{"version":3,"id":"1a2b3c4d5e6f","title":"The Fox","colour":"red","chapters":[{"number":1,"title":"","text":"Once."}]}`,
+			wantErr: `unknown field "colour"`,
+		},
+		{
+			name:    "wrong type",
+			data:    `{"version":3,"id":"1a2b3c4d5e6f","title":"The Fox","chapters":[{"number":"one","title":"","text":"Once."}]}`,
+			wantErr: "chapters[0].number must be int, got string",
+		},
+		{
+			name:    "trailing data",
+			data:    `{"version":3,"id":"1a2b3c4d5e6f","title":"The Fox","chapters":[{"number":1,"title":"","text":"Once."}]} {}`,
+			wantErr: "after top-level value",
+		},
+		{
+			name:    "not an object",
+			data:    `[]`,
+			wantErr: "story JSON must be an object",
+		},
+		{
+			name:         "invalid story",
+			data:         `{"version":3,"id":"The Fox","title":" ","chapters":[]}`,
+			wantProblems: []string{`id "The Fox" must be lowercase letters, digits, - and _`, "title is empty", "chapters is empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			switch {
+			case tt.wantProblems != nil:
+				var problems *ValidationError
+				if !errors.As(err, &problems) {
+					t.Fatalf("Parse() error = %v, want a ValidationError", err)
+				}
+				if !reflect.DeepEqual(problems.Problems, tt.wantProblems) {
+					t.Errorf("Parse() problems = %q, want %q", problems.Problems, tt.wantProblems)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("Parse() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Story {
+		return Story{ID: "1a2b3c4d5e6f", Title: "The Fox", Chapters: Chapters{{Number: 1, Text: "Once."}, {Number: 2, Text: "Then."}}}
+	}
+	tests := []struct {
+		name   string
+		change func(s *Story)
+		want   []string
+	}{
+		{name: "valid", change: func(*Story) {}},
+		{
+			name: "chapters",
+			change: func(s *Story) {
+				s.Chapters = Chapters{{Number: 0, Text: "Once."}, {Number: 2, Text: " "}, {Number: 2, Text: "Then."}}
+			},
+			want: []string{"chapters[0].number must be positive, got 0", "chapters[1].text is empty", "chapters[2].number 2 is already used by chapters[1]"},
+		},
+		{
+			name: "characters",
+			change: func(s *Story) {
+				s.Protagonists = Protagonists{{Name: ""}}
+				s.Morales = Morales{{Name: " "}}
+				s.Casting = &Casting{Roles: []Role{{Character: "Max"}}}
+			},
+			want: []string{"protagonists[0].name is empty", "morales[0].name is empty", "casting.roles[0] needs a character and a voice"},
+		},
+		{
+			name: "narration",
+			change: func(s *Story) {
+				s.Narration = &Narration{Speed: -1, Duration: 10, Chunks: []NarrationChunk{
+					{Chapter: 1, Start: 5, End: 4},
+					{Chapter: 1, Index: 1, Start: 12, End: 13},
+					{Chapter: -1, Start: 1, End: 2, Mood: &Mood{Intensity: 6}},
+				}}
+			},
+			want: []string{
+				"narration.file is empty",
+				"narration speed and duration must not be negative",
+				"narration.chunks[0] ends at 4.000s before it starts at 5.000s",
+				"narration.chunks[1] starts at 12.000s after the 10.000s narration ends",
+				"narration.chunks[2] chapter and index must not be negative",
+				"narration.chunks[2].mood.intensity must be 1 to 5, got 6",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.change(&s)
+			err := s.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			var problems *ValidationError
+			if !errors.As(err, &problems) {
+				t.Fatalf("Validate() error = %v, want a ValidationError", err)
+			}
+			if !reflect.DeepEqual(problems.Problems, tt.want) {
+				t.Errorf("Validate() problems =\n%q\nwant\n%q", problems.Problems, tt.want)
+			}
+		})
+	}
+}
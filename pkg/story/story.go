@@ -3,6 +3,7 @@ package story
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 
@@ -52,6 +53,8 @@ type Protagonist struct {
 }
 
 type Story struct {
+	Version         int          `json:"version"` // SchemaVersion, stamped when the story is written
+	ID              string       `json:"id"`      // Stable across revisions and translations, names the workspace
 	StorySuggestion string       `json:"story_prompt"`
 	Structure       Structure    `json:"structure"`
 	TimePeriod      TimePeriod   `json:"time_period"`
@@ -65,10 +68,12 @@ type Story struct {
 	Summary         string       `json:"summary"`
 	Chapters        Chapters     `json:"chapters"`
 	Title           string       `json:"title"`
+	Casting         *Casting     `json:"casting,omitempty"`
+	Narration       *Narration   `json:"narration,omitempty"`
 }
 
 func NewStory() Story {
-	return Story{}
+	return Story{Version: SchemaVersion, ID: NewID()}
 }
 
 func (s *Structures) ToJson() string {
@@ -201,6 +206,11 @@ func trimChapterTitleFromText(c Chapter) string {
 	return strings.TrimSpace(text)
 }
 
+// Body returns the chapter text without its title and markdown characters.
+func (c Chapter) Body() string {
+	return removeChars(trimChapterTitleFromText(c))
+}
+
 func removeChars(text string) string {
 	text = strings.Replace(text, "*", "", -1)
 	text = strings.Replace(text, "#", "", -1)
@@ -266,3 +276,53 @@ func (s *Story) BuildContent(chapterLabel, theEnd string) string {
 	// This creates ONE blank line between each element in the content slice.
 	return RemoveEmojis(strings.Join(content, "\n\n"))
 }
+
+// VillainName guesses the villain's name from the free text villain description,
+// e.g. "Grumblesnort, a grumpy troll" gives "Grumblesnort".
+func (s *Story) VillainName() string {
+	description := strings.TrimSpace(removeChars(s.Villain))
+	if description == "" {
+		return ""
+	}
+
+	head := description
+	for _, sep := range []string{",", " - ", " – ", " — ", ":", ".", "(", " is ", " the ", " a ", " an "} {
+		if idx := strings.Index(head, sep); idx > 0 {
+			head = head[:idx]
+		}
+	}
+
+	words := strings.Fields(head)
+	for len(words) > 0 && (strings.EqualFold(words[0], "the") || strings.EqualFold(words[0], "a")) {
+		words = words[1:]
+	}
+	if len(words) == 0 || len(words) > 4 {
+		return ""
+	}
+	for _, w := range words {
+		if r := []rune(w)[0]; !unicode.IsUpper(r) {
+			return ""
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Translation returns a story to put the translated title and chapters in. It keeps the ID, the
+// characters and their casting, so full-cast narration of the translation can still tell who speaks
+// and every language is read by the same voices.
+func (s *Story) Translation() Story {
+	t := Story{
+		Version:      SchemaVersion,
+		ID:           s.ID,
+		Protagonists: slices.Clone(s.Protagonists),
+		Villain:      s.Villain,
+		VillainVoice: s.VillainVoice,
+	}
+	if s.Casting != nil {
+		casting := *s.Casting
+		casting.Roles = slices.Clone(s.Casting.Roles)
+		t.Casting = &casting
+	}
+	return t
+}
@@ -0,0 +1,56 @@
+package story
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslationKeepsCharacters(t *testing.T) {
+	s := Story{
+		ID:           "1a2b3c4d5e6f",
+		Title:        "The Fox",
+		Summary:      "A fox finds the moon.",
+		Protagonists: Protagonists{{Name: "Max", Voice: "squeaky", Type: "fox", Gender: "boy", Age: "child"}},
+		Villain:      "Grumblesnort, a grumpy troll",
+		VillainVoice: "booming",
+		Chapters:     Chapters{{Number: 1, Text: "Once."}},
+	}
+
+	translated := s.Translation()
+	if translated.ID != s.ID {
+		t.Errorf("ID = %q, want %q", translated.ID, s.ID)
+	}
+	if !reflect.DeepEqual(translated.Protagonists, s.Protagonists) {
+		t.Errorf("Protagonists = %+v, want %+v", translated.Protagonists, s.Protagonists)
+	}
+	if translated.Villain != s.Villain || translated.VillainVoice != s.VillainVoice {
+		t.Errorf("villain = %q, %q, want %q, %q", translated.Villain, translated.VillainVoice, s.Villain, s.VillainVoice)
+	}
+	if translated.VillainName() != "Grumblesnort" {
+		t.Errorf("VillainName() = %q, want Grumblesnort", translated.VillainName())
+	}
+	if translated.Title != "" || translated.Summary != "" || len(translated.Chapters) != 0 {
+		t.Errorf("translation has texts of the original: %+v", translated)
+	}
+
+	translated.Protagonists[0].Name = "Макс"
+	if s.Protagonists[0].Name != "Max" {
+		t.Errorf("changing the translation changed the original protagonist to %q", s.Protagonists[0].Name)
+	}
+}
+
+func TestTranslationKeepsCasting(t *testing.T) {
+	s := Story{ID: "1a2b3c4d5e6f", Casting: &Casting{Provider: "openai", Narrator: "sage", Roles: []Role{{Character: "Max", Voice: "ash"}}}}
+
+	translated := s.Translation()
+	if !reflect.DeepEqual(translated.Casting, s.Casting) {
+		t.Errorf("Casting = %+v, want %+v", translated.Casting, s.Casting)
+	}
+	translated.Casting.Roles[0].Voice = "coral"
+	if s.Casting.Roles[0].Voice != "ash" {
+		t.Errorf("changing the translation changed the original casting to %q", s.Casting.Roles[0].Voice)
+	}
+	if (&Story{}).Translation().Casting != nil {
+		t.Error("translation of an uncast story has a casting")
+	}
+}
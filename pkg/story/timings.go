@@ -0,0 +1,62 @@
+package story
+
+import "github.com/andrejsstepanovs/storygen/pkg/utils"
+
+// Timings maps a narration file to text, chunk by chunk, sentence by sentence and word by word.
+// All times are seconds from the start of the audio file.
+type Timings struct {
+	Audio    string        `json:"audio"`
+	Duration float64       `json:"duration"`
+	Chunks   []ChunkTiming `json:"chunks"`
+}
+
+type ChunkTiming struct {
+	Chapter   int              `json:"chapter"`
+	Index     int              `json:"index"`
+	Speaker   string           `json:"speaker,omitempty"`
+	Start     float64          `json:"start"`
+	End       float64          `json:"end"`
+	Sentences []SentenceTiming `json:"sentences"`
+}
+
+type SentenceTiming struct {
+	Text  string       `json:"text"`
+	Start float64      `json:"start"`
+	End   float64      `json:"end"`
+	Words []WordTiming `json:"words"`
+}
+
+type WordTiming struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Estimated is set when the time is interpolated from the text, not taken from a transcription
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Sentences returns all sentences in narration order.
+func (t Timings) Sentences() []SentenceTiming {
+	sentences := make([]SentenceTiming, 0)
+	for _, c := range t.Chunks {
+		sentences = append(sentences, c.Sentences...)
+	}
+	return sentences
+}
+
+// Words returns the words of one chapter in narration order, chapter 0 returns all words.
+func (t Timings) Words(chapter int) []WordTiming {
+	words := make([]WordTiming, 0)
+	for _, c := range t.Chunks {
+		if chapter != 0 && c.Chapter != chapter {
+			continue
+		}
+		for _, s := range c.Sentences {
+			words = append(words, s.Words...)
+		}
+	}
+	return words
+}
+
+func (t Timings) ToJson() string {
+	return utils.ToJsonStr(t)
+}
@@ -0,0 +1,14 @@
+package story
+
+// Transcript is the speech-to-text result for a piece of narrated audio.
+type Transcript struct {
+	Text  string           `json:"text"`
+	Words []TranscriptWord `json:"words,omitempty"`
+}
+
+// TranscriptWord is a single recognized word with its position in seconds.
+type TranscriptWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
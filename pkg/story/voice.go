@@ -20,10 +20,53 @@ type VoiceInstruction struct {
 	Pauses  string
 	Pacing  string
 	Story   Story
+	// Character is set when the text is a quote spoken by a story character
+	Character string
+	// Mood is set when the scene was annotated, it refines the global emotion and pacing
+	Mood *Mood
+	// Bedtime is set in sleep mode while the story winds down
+	Bedtime string
+	// Delivery describes headings, emphasis and pauses of the text for models without SSML
+	Delivery string
+}
+
+func (vi VoiceInstruction) emotion() string {
+	if vi.Mood == nil || vi.Mood.Mood == "" {
+		return vi.Emotion
+	}
+	return fmt.Sprintf("%s, intensity %d of 5. %s", vi.Mood.Mood, vi.Mood.Intensity, vi.Emotion)
+}
+
+func (vi VoiceInstruction) pacing() string {
+	if vi.Mood == nil || vi.Mood.Pacing == "" {
+		return vi.Pacing
+	}
+	return fmt.Sprintf("%s in this scene. %s", vi.Mood.Pacing, vi.Pacing)
 }
 
 func (vi VoiceInstruction) String() string {
+	text := vi.text()
+	if vi.Bedtime != "" {
+		text += fmt.Sprintf("\nBedtime: %s\n", vi.Bedtime)
+	}
+	if vi.Delivery != "" {
+		text += fmt.Sprintf("\nDelivery: %s\n", vi.Delivery)
+	}
+	return text
+}
+
+func (vi VoiceInstruction) text() string {
 	protagonists := vi.Story.Protagonists.String()
+	if vi.Character != "" {
+		return fmt.Sprintf(
+			"Voice Affect: Voice actor playing a character in a story.\n\n"+
+				"Tone: %s\n\n"+
+				"Emotion: %s\n\n"+
+				"Reading: Only the quoted words of this character, fully in character.\n\n"+
+				"Character: %s\n",
+			vi.Tone, vi.emotion(), vi.Character,
+		)
+	}
 	return fmt.Sprintf(
 		"Voice Affect: %s\n\n"+
 			"Tone: %s\n\n"+
@@ -34,7 +77,7 @@ func (vi VoiceInstruction) String() string {
 			"Story protagonists: %s\n"+
 			"Story villain: %s\n"+
 			"Story villain Voice: %s\n",
-		vi.Affect, vi.Tone, vi.Pacing, vi.Emotion, vi.Pauses,
+		vi.Affect, vi.Tone, vi.pacing(), vi.emotion(), vi.Pauses,
 		protagonists, vi.Story.Villain, vi.Story.VillainVoice,
 	)
 }
@@ -0,0 +1,183 @@
+package tts
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// VoicePreset is a named set of voice instructions to audition.
+type VoicePreset struct {
+	Name    string
+	Affect  string
+	Tone    string
+	Pacing  string
+	Emotion string
+	Pauses  string
+}
+
+func (p VoicePreset) apply(vi story.VoiceInstruction) story.VoiceInstruction {
+	vi.Affect, vi.Tone, vi.Pacing, vi.Emotion, vi.Pauses = p.Affect, p.Tone, p.Pacing, p.Emotion, p.Pauses
+	return vi
+}
+
+// DefaultPresets are instruction styles parents usually choose between.
+func DefaultPresets() []VoicePreset {
+	return []VoicePreset{
+		{
+			Name:    "calm",
+			Affect:  "Soft, warm parent voice reading a bedtime story to a sleepy child.",
+			Tone:    "Gentle, soothing, reassuring.",
+			Pacing:  "Slow and even, never rushed.",
+			Emotion: "Quiet warmth, only light colouring of exciting moments.",
+			Pauses:  "Long pauses between sentences and before every chapter.",
+		},
+		{
+			Name:    "lively",
+			Affect:  "Fun, active and engaged teacher voice reading a story to a group of kids.",
+			Tone:    "Cheerful, playful, involved.",
+			Pacing:  "Slow enough for kids to understand, faster when the story picks up action.",
+			Emotion: "Follows the story, animates protagonist and villain voices when they talk.",
+			Pauses:  "Short pauses between sentences, a big pause before every chapter.",
+		},
+		{
+			Name:    "dramatic",
+			Affect:  "Expressive audiobook storyteller performing the story.",
+			Tone:    "Rich, theatrical, immersive.",
+			Pacing:  "Varied, slows down for suspense and speeds up in action.",
+			Emotion: "Strong and clear, every character gets a distinct voice.",
+			Pauses:  "Dramatic pauses before surprises and at the end of chapters.",
+		},
+	}
+}
+
+// FindPresets returns the named presets, "current" is taken from the given instruction.
+func FindPresets(names []string, current story.VoiceInstruction) ([]VoicePreset, error) {
+	presets := make([]VoicePreset, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "current" {
+			presets = append(presets, VoicePreset{
+				Name: name, Affect: current.Affect, Tone: current.Tone, Pacing: current.Pacing,
+				Emotion: current.Emotion, Pauses: current.Pauses,
+			})
+			continue
+		}
+
+		found := false
+		for _, p := range DefaultPresets() {
+			if p.Name == name {
+				presets, found = append(presets, p), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown voice preset %q", name)
+		}
+	}
+	return presets, nil
+}
+
+// Audition is the grid of voices, speeds and instruction presets a text is rendered with.
+type Audition struct {
+	Voices  []string
+	Speeds  []float64
+	Presets []VoicePreset
+	// Stretch applies the speeds locally for models that ignore the speed parameter
+	Stretch bool
+}
+
+// AuditionSample is one rendered cell of the grid.
+type AuditionSample struct {
+	File   string
+	Voice  string
+	Speed  float64
+	Preset VoicePreset
+}
+
+// Run renders text once for every grid cell into dir.
+func (a Audition) Run(dir, text string, voice story.Voice, converter TTSConverter) ([]AuditionSample, error) {
+	samples := make([]AuditionSample, 0, len(a.Voices)*len(a.Speeds)*len(a.Presets))
+	part := markupPart{Input: text, Text: text}
+	for _, name := range a.Voices {
+		for _, preset := range a.Presets {
+			sampleVoice := voice
+			sampleVoice.Provider.Voice = name
+			sampleVoice.Instruction = preset.apply(voice.Instruction)
+
+			var original Chunk
+			for _, speed := range a.Speeds {
+				sample := AuditionSample{Voice: name, Speed: speed, Preset: preset}
+				target := path.Join(dir, fmt.Sprintf("%02d_%s_%sx_%s.mp3", len(samples)+1, name, strconv.FormatFloat(speed, 'f', -1, 64), preset.Name))
+				fmt.Printf("Audition %s\n", target)
+
+				if !a.Stretch {
+					sampleVoice.Provider.Speed = speed
+					chunk, err := generateChunk(converter, QA{}, nil, part, target, sampleVoice)
+					if err != nil {
+						return samples, err
+					}
+					sample.File = chunk.File
+					samples = append(samples, sample)
+					continue
+				}
+
+				// One TTS call per voice and preset, every speed is stretched from it
+				if original.File == "" {
+					sampleVoice.Provider.Speed = 1
+					chunk, err := generateChunk(converter, QA{}, nil, part, path.Join(dir, fmt.Sprintf("original_%s_%s.mp3", name, preset.Name)), sampleVoice)
+					if err != nil {
+						return samples, err
+					}
+					original = chunk
+				}
+				sample.File = target
+				if _, err := Retime(original.File, sample.File, speed); err != nil {
+					return samples, err
+				}
+				samples = append(samples, sample)
+			}
+			if original.File != "" {
+				_ = os.Remove(original.File)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// WriteAuditionIndex writes a markdown table of the samples and the parameters used.
+func WriteAuditionIndex(file, text string, samples []AuditionSample) error {
+	var b strings.Builder
+	b.WriteString("# Voice audition\n\n")
+	b.WriteString("Text:\n\n> " + strings.ReplaceAll(text, "\n", "\n> ") + "\n\n")
+	b.WriteString("| File | Voice | Speed | Preset |\n|---|---|---|---|\n")
+	for _, s := range samples {
+		b.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", filepath.Base(s.File), s.Voice, strconv.FormatFloat(s.Speed, 'f', -1, 64), s.Preset.Name))
+	}
+
+	presets := make(map[string]bool)
+	b.WriteString("\n## Presets\n")
+	for _, s := range samples {
+		p := s.Preset
+		if presets[p.Name] {
+			continue
+		}
+		presets[p.Name] = true
+		b.WriteString(fmt.Sprintf("\n### %s\n\n", p.Name))
+		b.WriteString(fmt.Sprintf("- STORYGEN_VOICE_AFFECT: %s\n", p.Affect))
+		b.WriteString(fmt.Sprintf("- STORYGEN_VOICE_TONE: %s\n", p.Tone))
+		b.WriteString(fmt.Sprintf("- STORYGEN_VOICE_PACING: %s\n", p.Pacing))
+		b.WriteString(fmt.Sprintf("- STORYGEN_VOICE_EMOTION: %s\n", p.Emotion))
+		b.WriteString(fmt.Sprintf("- STORYGEN_VOICE_PAUSES: %s\n", p.Pauses))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(b.String()), 0644)
+}
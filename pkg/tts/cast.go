@@ -0,0 +1,207 @@
+package tts
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// Cast assigns voices to story characters for full-cast narration.
+type Cast struct {
+	Characters map[string]story.Role // By character name
+}
+
+// NewCast prepares the story casting for full-cast narration.
+func NewCast(casting *story.Casting) *Cast {
+	cast := &Cast{Characters: make(map[string]story.Role)}
+	for _, r := range casting.Roles {
+		if r.Voice != "" {
+			cast.Characters[r.Character] = r
+		}
+	}
+	return cast
+}
+
+// normalized returns the cast with character names as the normalizer rewrites them,
+// so quotes are still attributed after names were respelled by the pronunciation lexicon.
+func (c *Cast) normalized(n *Normalizer) *Cast {
+	if c == nil || n == nil {
+		return c
+	}
+	cast := &Cast{Characters: make(map[string]story.Role, len(c.Characters))}
+	for name, role := range c.Characters {
+		cast.Characters[n.Normalize(name)] = role
+	}
+	return cast
+}
+
+// Character returns the story name of a speaker found in the text.
+func (c *Cast) Character(speaker string) string {
+	if c == nil {
+		return speaker
+	}
+	if role, ok := c.Characters[speaker]; ok {
+		return role.Character
+	}
+	return speaker
+}
+
+// Names returns the cast character names, longest first so "Captain Whiskers" wins over "Whiskers".
+func (c *Cast) Names() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.Characters))
+	for name := range c.Characters {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) == len(names[j]) {
+			return names[i] < names[j]
+		}
+		return len(names[i]) > len(names[j])
+	})
+	return names
+}
+
+// Split breaks text into narrator and character segments.
+func (c *Cast) Split(text string) []Segment {
+	return splitDialogue(text, c.Names())
+}
+
+// VoiceFor returns the voice reading a segment spoken by speaker.
+func (c *Cast) VoiceFor(speaker string, narrator story.Voice) story.Voice {
+	if c == nil || speaker == "" {
+		return narrator
+	}
+	member, ok := c.Characters[speaker]
+	if !ok {
+		return narrator
+	}
+
+	voice := narrator
+	voice.Provider.Voice = member.Voice
+	voice.Instruction.Character = fmt.Sprintf("%s. %s", speaker, member.Description)
+	return voice
+}
+
+// narratorDescription is what the narrator is cast by when no narrator voice is configured.
+const narratorDescription = "adult warm calm storyteller"
+
+// CastStory maps the narrator, every protagonist and the villain to provider voices from the catalog.
+// Roles already cast in the story are kept, so re-narration sounds the same; new characters get a
+// distinct voice whose gender, age and timbre match their description best. narrator is the
+// configured narrator voice, when empty one is picked from the catalog.
+func CastStory(s story.Story, provider, narrator string, catalog []CatalogVoice) *story.Casting {
+	casting := &story.Casting{Provider: provider, Roles: make([]story.Role, 0)}
+	if s.Casting != nil && s.Casting.Provider == provider {
+		casting.Narrator = s.Casting.Narrator
+		casting.Roles = append(casting.Roles, s.Casting.Roles...)
+	}
+
+	voices := ProviderVoices(catalog, provider)
+	if len(voices) == 0 {
+		log.Printf("Warning: voice catalog has no %q voices, characters are read by the narrator\n", provider)
+	}
+	used := make(map[string]bool)
+	for _, r := range casting.Roles {
+		used[r.Voice] = true
+	}
+
+	if casting.Narrator == "" {
+		casting.Narrator = narrator
+		if casting.Narrator == "" {
+			casting.Narrator = pickVoice(narratorDescription, voices, used)
+		}
+		log.Printf("Cast narrator as %q\n", casting.Narrator)
+	} else if narrator != "" && narrator != casting.Narrator {
+		log.Printf("Keeping narrator %q from story casting instead of %q\n", casting.Narrator, narrator)
+	}
+	used[casting.Narrator] = true
+
+	characters := make([]story.Role, 0)
+	for _, p := range s.Protagonists {
+		if p.Name == "" {
+			continue
+		}
+		characters = append(characters, story.Role{
+			Character:   p.Name,
+			Description: strings.Join(strings.Fields(fmt.Sprintf("%s %s %s %s, voice %s", p.Age, p.Size, p.Gender, p.Type, p.Voice)), " "),
+		})
+	}
+	if villain := s.VillainName(); villain != "" {
+		characters = append(characters, story.Role{Character: villain, Description: "villain, voice " + s.VillainVoice})
+	}
+
+	for _, role := range characters {
+		if _, ok := casting.Role(role.Character); ok {
+			continue
+		}
+		role.Voice = pickVoice(role.Description, voices, used)
+		used[role.Voice] = true
+		casting.Roles = append(casting.Roles, role)
+		log.Printf("Cast %s as %q\n", role.Character, role.Voice)
+	}
+
+	return casting
+}
+
+// pickVoice returns the voice matching description best, preferring voices not used yet.
+// Gender outweighs age, age outweighs timbre.
+func pickVoice(description string, voices []CatalogVoice, used map[string]bool) string {
+	wanted := descriptorWords(description)
+	for _, unusedOnly := range []bool{true, false} {
+		best, bestScore := "", math.MinInt
+		for _, v := range voices {
+			if unusedOnly && used[v.Name] {
+				continue
+			}
+			score := 0
+			switch {
+			case wanted[v.Gender]:
+				score += 3
+			case (wanted["male"] && v.Gender == "female") || (wanted["female"] && v.Gender == "male"):
+				score -= 10
+			}
+			if wanted[v.Age] {
+				score += 2
+			}
+			for _, t := range v.Timbre {
+				if wanted[strings.ToLower(t)] {
+					score++
+				}
+			}
+			if score > bestScore {
+				best, bestScore = v.Name, score
+			}
+		}
+		if best != "" {
+			return best
+		}
+	}
+	return ""
+}
+
+// descriptorSynonyms folds the vocabulary used by story.GetAvailableProtagonists into the
+// words used by the voice catalog.
+var descriptorSynonyms = map[string]string{
+	"boy": "male", "man": "male", "girl": "female", "woman": "female",
+	"child": "young", "childish": "young", "teenager": "young", "kid": "young", "little": "young",
+	"squeaky": "high", "sweet": "soft", "old": "mature", "wise": "mature", "elderly": "mature",
+	"scary": "deep", "evil": "deep", "booming": "deep",
+}
+
+func descriptorWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range normalizeWords(text) {
+		words[w] = true
+		if synonym, ok := descriptorSynonyms[w]; ok {
+			words[synonym] = true
+		}
+	}
+	return words
+}
@@ -0,0 +1,105 @@
+package tts
+
+import (
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+var testCatalog = []CatalogVoice{
+	{Name: "warm", Provider: "openai", Gender: "female", Age: "adult", Timbre: []string{"warm", "calm"}},
+	{Name: "kid", Provider: "openai", Gender: "male", Age: "young", Timbre: []string{"bright"}},
+	{Name: "girl", Provider: "openai", Gender: "female", Age: "young", Timbre: []string{"high"}},
+	{Name: "deep", Provider: "openai", Gender: "male", Age: "mature", Timbre: []string{"deep"}},
+	{Name: "Kore", Provider: "gemini", Gender: "female", Age: "adult"},
+}
+
+func testStory() story.Story {
+	return story.Story{
+		Protagonists: story.Protagonists{
+			{Name: "Max", Gender: "boy", Age: "child", Voice: "cheerful"},
+			{Name: "Luna", Gender: "girl", Age: "little", Voice: "squeaky"},
+		},
+		Villain:      "Grumblesnort, a grumpy troll",
+		VillainVoice: "booming",
+	}
+}
+
+func TestCastStoryMatchesCatalog(t *testing.T) {
+	casting := CastStory(testStory(), "openai", "", testCatalog)
+
+	if casting.Narrator != "warm" {
+		t.Errorf("narrator = %q, want warm", casting.Narrator)
+	}
+	want := map[string]string{"Max": "kid", "Luna": "girl", "Grumblesnort": "deep"}
+	for character, voice := range want {
+		role, ok := casting.Role(character)
+		if !ok || role.Voice != voice {
+			t.Errorf("%s is cast as %q, want %q", character, role.Voice, voice)
+		}
+	}
+}
+
+func TestCastStoryKeepsExistingCasting(t *testing.T) {
+	s := testStory()
+	s.Casting = &story.Casting{Provider: "openai", Narrator: "deep", Roles: []story.Role{{Character: "Max", Voice: "girl"}}}
+
+	casting := CastStory(s, "openai", "warm", testCatalog)
+	if casting.Narrator != "deep" {
+		t.Errorf("narrator = %q, want the cast deep", casting.Narrator)
+	}
+	if role, _ := casting.Role("Max"); role.Voice != "girl" {
+		t.Errorf("Max is cast as %q, want the cast girl", role.Voice)
+	}
+	if role, _ := casting.Role("Luna"); role.Voice == "" || role.Voice == "girl" || role.Voice == "deep" {
+		t.Errorf("Luna is cast as %q, want a voice nobody else uses", role.Voice)
+	}
+}
+
+func TestCastStoryTranslationKeepsVoices(t *testing.T) {
+	s := testStory()
+	s.Casting = CastStory(s, "openai", "", testCatalog)
+
+	translated := s.Translation()
+	casting := CastStory(translated, "openai", "", testCatalog)
+	if casting.Narrator != s.Casting.Narrator {
+		t.Errorf("narrator = %q, want %q", casting.Narrator, s.Casting.Narrator)
+	}
+	for _, r := range s.Casting.Roles {
+		if role, _ := casting.Role(r.Character); role.Voice != r.Voice {
+			t.Errorf("%s is cast as %q in the translation, want %q", r.Character, role.Voice, r.Voice)
+		}
+	}
+}
+
+func TestCastStoryFallbacks(t *testing.T) {
+	s := testStory()
+	s.Casting = &story.Casting{Provider: "openai", Narrator: "deep"}
+
+	// Another provider casts again from its own voices
+	casting := CastStory(s, "gemini", "", testCatalog)
+	if casting.Provider != "gemini" || casting.Narrator != "Kore" {
+		t.Errorf("casting = %+v, want gemini voices", casting)
+	}
+	// With fewer voices than characters voices are shared
+	for _, r := range casting.Roles {
+		if r.Voice != "Kore" {
+			t.Errorf("%s is cast as %q, want the only gemini voice", r.Character, r.Voice)
+		}
+	}
+
+	// Without catalog voices characters get no voice and are read by the narrator
+	casting = CastStory(testStory(), "elevenlabs", "narrator", testCatalog)
+	if casting.Narrator != "narrator" {
+		t.Errorf("narrator = %q, want the configured one", casting.Narrator)
+	}
+	cast := NewCast(casting)
+	if len(cast.Names()) != 0 {
+		t.Errorf("cast = %q, want no characters with a voice", cast.Names())
+	}
+	narrator := story.Voice{}
+	narrator.Provider.Voice = "narrator"
+	if v := cast.VoiceFor("Max", narrator); v.Provider.Voice != "narrator" {
+		t.Errorf("Max is read by %q, want the narrator", v.Provider.Voice)
+	}
+}
@@ -0,0 +1,85 @@
+package tts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CatalogVoice describes a TTS voice available for casting.
+type CatalogVoice struct {
+	Name     string   `json:"name"`
+	Provider string   `json:"provider"`
+	Gender   string   `json:"gender"` // male, female or neutral
+	Age      string   `json:"age"`    // young, adult or mature
+	Timbre   []string `json:"timbre"` // e.g. deep, warm, bright
+}
+
+func (v CatalogVoice) String() string {
+	return fmt.Sprintf("%s %s %s %s", v.Gender, v.Age, strings.Join(v.Timbre, " "), v.Name)
+}
+
+// DefaultCatalog describes the OpenAI and Gemini voices.
+func DefaultCatalog() []CatalogVoice {
+	return []CatalogVoice{
+		{Name: "alloy", Provider: "openai", Gender: "neutral", Age: "adult", Timbre: []string{"balanced", "clear"}},
+		{Name: "ash", Provider: "openai", Gender: "male", Age: "adult", Timbre: []string{"warm", "clear"}},
+		{Name: "ballad", Provider: "openai", Gender: "male", Age: "adult", Timbre: []string{"soft", "gentle", "expressive"}},
+		{Name: "coral", Provider: "openai", Gender: "female", Age: "adult", Timbre: []string{"warm", "cheerful", "friendly"}},
+		{Name: "echo", Provider: "openai", Gender: "male", Age: "mature", Timbre: []string{"calm", "deep"}},
+		{Name: "fable", Provider: "openai", Gender: "neutral", Age: "adult", Timbre: []string{"animated", "expressive", "funny", "storyteller"}},
+		{Name: "onyx", Provider: "openai", Gender: "male", Age: "mature", Timbre: []string{"deep", "serious", "wise"}},
+		{Name: "nova", Provider: "openai", Gender: "female", Age: "young", Timbre: []string{"bright", "energetic", "cheerful"}},
+		{Name: "sage", Provider: "openai", Gender: "female", Age: "mature", Timbre: []string{"calm", "soft", "wise"}},
+		{Name: "shimmer", Provider: "openai", Gender: "female", Age: "young", Timbre: []string{"high", "soft", "sweet"}},
+		{Name: "verse", Provider: "openai", Gender: "male", Age: "adult", Timbre: []string{"animated", "dramatic", "energetic"}},
+
+		{Name: "Puck", Provider: "gemini", Gender: "male", Age: "young", Timbre: []string{"upbeat", "energetic", "funny"}},
+		{Name: "Charon", Provider: "gemini", Gender: "male", Age: "mature", Timbre: []string{"deep", "informative", "calm"}},
+		{Name: "Kore", Provider: "gemini", Gender: "female", Age: "adult", Timbre: []string{"firm", "clear"}},
+		{Name: "Fenrir", Provider: "gemini", Gender: "male", Age: "adult", Timbre: []string{"excitable", "energetic", "dramatic"}},
+		{Name: "Leda", Provider: "gemini", Gender: "female", Age: "young", Timbre: []string{"youthful", "bright", "high"}},
+		{Name: "Orus", Provider: "gemini", Gender: "male", Age: "adult", Timbre: []string{"firm", "serious"}},
+		{Name: "Aoede", Provider: "gemini", Gender: "female", Age: "adult", Timbre: []string{"breezy", "warm", "storyteller"}},
+		{Name: "Zephyr", Provider: "gemini", Gender: "female", Age: "young", Timbre: []string{"bright", "cheerful"}},
+		{Name: "Enceladus", Provider: "gemini", Gender: "male", Age: "adult", Timbre: []string{"breathy", "soft"}},
+		{Name: "Algenib", Provider: "gemini", Gender: "male", Age: "mature", Timbre: []string{"gravelly", "deep", "grumpy"}},
+		{Name: "Achernar", Provider: "gemini", Gender: "female", Age: "adult", Timbre: []string{"soft", "gentle"}},
+		{Name: "Gacrux", Provider: "gemini", Gender: "female", Age: "mature", Timbre: []string{"mature", "wise", "calm"}},
+		{Name: "Sulafat", Provider: "gemini", Gender: "female", Age: "adult", Timbre: []string{"warm", "friendly"}},
+		{Name: "Sadachbia", Provider: "gemini", Gender: "male", Age: "young", Timbre: []string{"lively", "animated", "squeaky"}},
+	}
+}
+
+// LoadCatalog reads a JSON array of catalog voices.
+func LoadCatalog(file string) ([]CatalogVoice, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read voice catalog: %w", err)
+	}
+	catalog := make([]CatalogVoice, 0)
+	if err = json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse voice catalog %q: %w", file, err)
+	}
+	return catalog, nil
+}
+
+// ProviderVoices returns the catalog voices of a single provider.
+func ProviderVoices(catalog []CatalogVoice, provider string) []CatalogVoice {
+	voices := make([]CatalogVoice, 0)
+	for _, v := range catalog {
+		if strings.EqualFold(v.Provider, provider) {
+			voices = append(voices, v)
+		}
+	}
+	return voices
+}
+
+// GuessProvider picks the catalog provider from the TTS model name, e.g. "tts-gemini" gives "gemini".
+func GuessProvider(model string) string {
+	if strings.Contains(strings.ToLower(model), "gemini") {
+		return "gemini"
+	}
+	return "openai"
+}
@@ -0,0 +1,149 @@
+package tts
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Segment is a run of text read by a single voice.
+type Segment struct {
+	Speaker string // Character name, empty for the narrator
+	Text    string
+}
+
+// quotePairs maps opening quotation marks to the marks that may close them.
+var quotePairs = map[rune]string{
+	'"': `"`,
+	'“': `”"`,
+	'„': `“”"`,
+	'«': `»`,
+	'»': `«`,
+	'「': `」`,
+}
+
+// splitDialogue splits text into narrator and character segments.
+// Story prompts always put the speaker before the quote (Max said "Hello!"), so a quote
+// belongs to the last character name mentioned earlier in the same sentence.
+// Quotes without a recognizable speaker stay with the narrator.
+func splitDialogue(text string, speakers []string) []Segment {
+	if len(speakers) == 0 {
+		return []Segment{{Text: text}}
+	}
+	segments := make([]Segment, 0)
+
+	runes := []rune(text)
+	narration := strings.Builder{}
+	sentenceStart := 0
+
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		if isSentenceEnd(r) || r == '\n' {
+			sentenceStart = narration.Len()
+		}
+
+		closers, isOpening := quotePairs[r]
+		if !isOpening {
+			narration.WriteRune(r)
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && !strings.ContainsRune(closers, runes[end]) {
+			end++
+		}
+		if end >= len(runes) {
+			narration.WriteRune(r)
+			i++
+			continue
+		}
+
+		sentence := narration.String()[sentenceStart:]
+		speaker := findSpeaker(sentence, speakers)
+		quote := strings.TrimSpace(string(runes[i+1 : end]))
+		if speaker == "" || quote == "" {
+			narration.WriteString(string(runes[i : end+1]))
+			i = end + 1
+			continue
+		}
+
+		segments = appendSegment(segments, Segment{Text: narration.String()})
+		segments = appendSegment(segments, Segment{Speaker: speaker, Text: quote})
+		narration.Reset()
+		sentenceStart = 0
+		i = end + 1
+	}
+	segments = appendSegment(segments, Segment{Text: narration.String()})
+
+	return segments
+}
+
+// appendSegment adds s unless it has nothing to read, merging it into the previous
+// segment when both are read by the same voice.
+func appendSegment(segments []Segment, s Segment) []Segment {
+	s.Text = strings.TrimSpace(s.Text)
+	if !strings.ContainsFunc(s.Text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+		return segments
+	}
+	if len(segments) > 0 && segments[len(segments)-1].Speaker == s.Speaker {
+		segments[len(segments)-1].Text += " " + s.Text
+		return segments
+	}
+	return append(segments, s)
+}
+
+// findSpeaker returns the speaker whose name appears last in text.
+func findSpeaker(text string, speakers []string) string {
+	found, position := "", -1
+	for _, speaker := range speakers {
+		for _, name := range nameVariants(speaker) {
+			if idx := lastWordIndex(text, name); idx > position {
+				found, position = speaker, idx
+			}
+		}
+	}
+	return found
+}
+
+// nameVariants returns the full name and its distinctive parts, so "Captain Whiskers"
+// is also found when the text only says "Whiskers".
+func nameVariants(name string) []string {
+	variants := []string{name}
+	parts := strings.Fields(name)
+	if len(parts) > 1 {
+		for _, part := range parts {
+			part = strings.TrimFunc(part, func(r rune) bool { return !unicode.IsLetter(r) })
+			if len([]rune(part)) >= 3 && unicode.IsUpper([]rune(part)[0]) {
+				variants = append(variants, part)
+			}
+		}
+	}
+	return variants
+}
+
+// lastWordIndex finds the last occurrence of word in text that is not part of a longer word.
+func lastWordIndex(text, word string) int {
+	for end := len(text); end > 0; {
+		idx := strings.LastIndex(text[:end], word)
+		if idx < 0 {
+			return -1
+		}
+		before := idx == 0 || !isWordRune(lastRune(text[:idx]))
+		after := idx+len(word) == len(text) || !isWordRune([]rune(text[idx+len(word):])[0])
+		if before && after {
+			return idx
+		}
+		end = idx
+	}
+	return -1
+}
+
+func lastRune(s string) rune {
+	r := []rune(s)
+	return r[len(r)-1]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,92 @@
+package tts
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+func TestSplitDialogue(t *testing.T) {
+	speakers := []string{"Captain Whiskers", "Max", "Luna"}
+	tests := []struct {
+		name string
+		text string
+		want []Segment
+	}{
+		{
+			name: "speaker before the quote",
+			text: `Max said "Hello!" and waved.`,
+			want: []Segment{{Text: "Max said"}, {Speaker: "Max", Text: "Hello!"}, {Text: "and waved."}},
+		},
+		{
+			name: "last name in the sentence speaks",
+			text: `Max looked at Luna. Luna whispered “Be quiet.”`,
+			want: []Segment{{Text: "Max looked at Luna. Luna whispered"}, {Speaker: "Luna", Text: "Be quiet."}},
+		},
+		{
+			name: "name in an earlier sentence does not speak",
+			text: `Max ran home. Somebody shouted "Stop!"`,
+			want: []Segment{{Text: `Max ran home. Somebody shouted "Stop!"`}},
+		},
+		{
+			name: "part of a name",
+			text: `Whiskers grumbled «Fine.»`,
+			want: []Segment{{Text: "Whiskers grumbled"}, {Speaker: "Captain Whiskers", Text: "Fine."}},
+		},
+		{
+			name: "name inside a longer word",
+			text: `Maximilian said "Hi."`,
+			want: []Segment{{Text: `Maximilian said "Hi."`}},
+		},
+		{
+			name: "german quotes and a following quote of the same speaker",
+			text: `Luna sagte „Komm!“ Luna lachte „Schnell!“`,
+			want: []Segment{{Text: "Luna sagte"}, {Speaker: "Luna", Text: "Komm!"}, {Text: "Luna lachte"}, {Speaker: "Luna", Text: "Schnell!"}},
+		},
+		{
+			name: "unclosed quote stays with the narrator",
+			text: `Max said "Hello`,
+			want: []Segment{{Text: `Max said "Hello`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitDialogue(tt.text, speakers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitDialogue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitDialogueWithoutSpeakers(t *testing.T) {
+	text := `Max said "Hello!"`
+	if got := splitDialogue(text, nil); !reflect.DeepEqual(got, []Segment{{Text: text}}) {
+		t.Errorf("splitDialogue() = %+v, want the whole text read by the narrator", got)
+	}
+}
+
+func TestTranslatedStoryAttributesDialogue(t *testing.T) {
+	original := story.Story{
+		ID:           "1a2b3c4d5e6f",
+		Protagonists: story.Protagonists{{Name: "Max", Gender: "boy", Age: "child"}},
+		Villain:      "Grumblesnort, a grumpy troll",
+	}
+	translated := original.Translation()
+	translated.Chapters = story.Chapters{{Number: 1, Text: `Max sacīja "Sveiki!" Grumblesnort rūca "Ej prom!"`}}
+
+	catalog := []CatalogVoice{
+		{Name: "warm", Provider: "openai", Gender: "female", Age: "adult"},
+		{Name: "kid", Provider: "openai", Gender: "male", Age: "young"},
+		{Name: "deep", Provider: "openai", Gender: "male", Age: "mature", Timbre: []string{"deep"}},
+	}
+	cast := NewCast(CastStory(translated, "openai", "warm", catalog))
+
+	speakers := make([]string, 0)
+	for _, s := range cast.Split(translated.Chapters[0].Text) {
+		speakers = append(speakers, s.Speaker)
+	}
+	if want := []string{"", "Max", "", "Grumblesnort"}; !reflect.DeepEqual(speakers, want) {
+		t.Errorf("speakers = %q, want %q", speakers, want)
+	}
+}
@@ -0,0 +1,104 @@
+package tts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+	"github.com/bogem/id3v2/v2"
+)
+
+// Tags holds the ID3v2 metadata written into a finished story MP3.
+type Tags struct {
+	Title    string
+	Comment  string
+	Genre    string
+	Language string
+	Artist   string
+	Date     time.Time
+	Cover    string // Optional path to a JPEG or PNG cover image
+	Chapters []ChapterTag
+}
+
+// ChapterTag is written as an ID3v2 CHAP frame so players can jump between chapters.
+type ChapterTag struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// WriteTags replaces any existing ID3v2 tag in file with the given tags.
+func WriteTags(file string, tags Tags) error {
+	tag, err := id3v2.Open(file, id3v2.Options{Parse: false})
+	if err != nil {
+		return fmt.Errorf("failed to open %q for tagging: %w", file, err)
+	}
+	defer tag.Close()
+
+	tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+	lang := utils.FindLanguage(tags.Language)
+
+	tag.SetTitle(tags.Title)
+	tag.SetArtist(tags.Artist)
+	tag.SetGenre(tags.Genre)
+	tag.AddTextFrame("TLAN", id3v2.EncodingUTF8, lang.ISO2)
+	if !tags.Date.IsZero() {
+		tag.AddTextFrame("TDRC", id3v2.EncodingUTF8, tags.Date.Format("2006-01-02"))
+	}
+	if tags.Comment != "" {
+		tag.AddCommentFrame(id3v2.CommentFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			Language:    lang.ISO2,
+			Description: "Summary",
+			Text:        tags.Comment,
+		})
+	}
+
+	for i, c := range tags.Chapters {
+		tag.AddChapterFrame(id3v2.ChapterFrame{
+			ElementID:   fmt.Sprintf("chp%d", i),
+			StartTime:   c.Start,
+			EndTime:     c.End,
+			StartOffset: id3v2.IgnoredOffset,
+			EndOffset:   id3v2.IgnoredOffset,
+			Title:       &id3v2.TextFrame{Encoding: id3v2.EncodingUTF8, Text: c.Title},
+		})
+	}
+
+	if tags.Cover != "" {
+		picture, err := coverFrame(tags.Cover)
+		if err != nil {
+			return err
+		}
+		tag.AddAttachedPicture(picture)
+	}
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save tags to %q: %w", file, err)
+	}
+
+	return nil
+}
+
+func coverFrame(file string) (id3v2.PictureFrame, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return id3v2.PictureFrame{}, fmt.Errorf("failed to read cover image: %w", err)
+	}
+
+	mimeType := "image/jpeg"
+	if strings.ToLower(filepath.Ext(file)) == ".png" {
+		mimeType = "image/png"
+	}
+
+	return id3v2.PictureFrame{
+		Encoding:    id3v2.EncodingUTF8,
+		MimeType:    mimeType,
+		PictureType: id3v2.PTFrontCover,
+		Description: "Cover",
+		Picture:     data,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package tts
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// How narration markup (see story.BuildNarration) reaches the TTS provider.
+const (
+	MarkupSSML         = "ssml"         // <break>, <emphasis> and <p> tags, for providers that accept SSML
+	MarkupInstructions = "instructions" // Plain text plus delivery notes in the voice instructions
+	MarkupSilence      = "silence"      // Plain text, pauses are inserted as silence while joining
+)
+
+// markupToken is a piece of text with its markup, or a pause when Pause is set.
+type markupToken struct {
+	Text     string
+	Emphasis bool
+	Heading  bool
+	Pause    time.Duration
+}
+
+// markupPart is the text sent to the converter in one call.
+type markupPart struct {
+	Input       string // Rendered for the provider
+	Text        string // Without markup, used for QA and the narration metadata
+	Delivery    string // Added to the voice instructions
+	PauseBefore time.Duration
+	PauseAfter  time.Duration
+}
+
+func parseMarkup(text string) []markupToken {
+	tokens := make([]markupToken, 0)
+	emphasis, heading := false, false
+	add := func(s string) {
+		if s != "" {
+			tokens = append(tokens, markupToken{Text: s, Emphasis: emphasis, Heading: heading})
+		}
+	}
+
+	last := 0
+	for _, m := range story.MarkupTag.FindAllStringSubmatchIndex(text, -1) {
+		add(text[last:m[0]])
+		last = m[1]
+
+		switch tag := text[m[0]:m[1]]; tag {
+		case story.EmphasisOpen, story.EmphasisClose:
+			emphasis = tag == story.EmphasisOpen
+		case story.HeadingOpen, story.HeadingClose:
+			heading = tag == story.HeadingOpen
+		default:
+			pause, err := time.ParseDuration(text[m[2]:m[3]])
+			if err == nil && pause > 0 {
+				tokens = append(tokens, markupToken{Pause: pause})
+			}
+		}
+	}
+	add(text[last:])
+
+	return tokens
+}
+
+func hasText(tokens []markupToken) bool {
+	for _, t := range tokens {
+		if t.Pause == 0 && strings.TrimSpace(t.Text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderMarkup splits text into the parts sent to the converter. Pauses at the start and end
+// always become silence, pauses in between are rendered for the provider or split the text
+// in silence mode. A text without words returns a single part holding only the pause.
+func renderMarkup(text, mode string) []markupPart {
+	parts := make([]markupPart, 0)
+	pending := make([]markupToken, 0)
+	var before time.Duration
+
+	tokens := parseMarkup(text)
+	for i, token := range tokens {
+		if token.Pause == 0 {
+			pending = append(pending, token)
+			continue
+		}
+		switch {
+		case !hasText(pending) && len(parts) > 0:
+			parts[len(parts)-1].PauseAfter += token.Pause
+		case !hasText(pending):
+			before += token.Pause
+		case mode == MarkupSilence || !hasText(tokens[i+1:]):
+			parts = append(parts, renderPart(pending, mode, before, token.Pause))
+			pending, before = pending[:0:0], 0
+		default:
+			pending = append(pending, token)
+		}
+	}
+	if hasText(pending) {
+		parts = append(parts, renderPart(pending, mode, before, 0))
+	} else if len(parts) == 0 {
+		parts = append(parts, markupPart{PauseBefore: before})
+	}
+
+	return parts
+}
+
+func renderPart(tokens []markupToken, mode string, before, after time.Duration) markupPart {
+	part := markupPart{PauseBefore: before, PauseAfter: after}
+
+	var plain, ssml strings.Builder
+	emphasized, headings, pauses := make([]string, 0), make([]string, 0), make([]string, 0)
+	for _, t := range tokens {
+		if t.Pause > 0 {
+			ssml.WriteString(fmt.Sprintf(`<break time="%dms"/>`, t.Pause.Milliseconds()))
+			pauses = append(pauses, fmt.Sprintf("%s after %q", t.Pause, lastWords(plain.String(), 4)))
+			continue
+		}
+		plain.WriteString(t.Text)
+		text := html.EscapeString(t.Text)
+		switch {
+		case t.Heading:
+			ssml.WriteString(`<p><emphasis level="moderate">` + text + `</emphasis></p>`)
+			headings = append(headings, strings.TrimSpace(t.Text))
+		case t.Emphasis:
+			ssml.WriteString(`<emphasis level="strong">` + text + `</emphasis>`)
+			emphasized = append(emphasized, strings.TrimSpace(t.Text))
+		default:
+			ssml.WriteString(text)
+		}
+	}
+	part.Text = strings.TrimSpace(plain.String())
+
+	switch mode {
+	case MarkupSSML:
+		part.Input = "<speak>" + strings.TrimSpace(ssml.String()) + "</speak>"
+	case MarkupInstructions:
+		part.Input = part.Text
+		part.Delivery = delivery(headings, emphasized, pauses)
+	default:
+		part.Input = part.Text
+	}
+
+	return part
+}
+
+// delivery describes headings, emphasis and pauses for models that only take instructions.
+func delivery(headings, emphasized, pauses []string) string {
+	notes := make([]string, 0)
+	for _, h := range headings {
+		notes = append(notes, fmt.Sprintf("Read %q as a heading, slower and clearer than the story.", h))
+	}
+	if len(emphasized) > 0 {
+		notes = append(notes, fmt.Sprintf("Stress %s.", quoteJoin(emphasized)))
+	}
+	for _, p := range pauses {
+		notes = append(notes, fmt.Sprintf("Pause for %s.", p))
+	}
+	return strings.Join(notes, " ")
+}
+
+func quoteJoin(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = fmt.Sprintf("%q", w)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func lastWords(text string, n int) string {
+	words := strings.Fields(text)
+	if len(words) > n {
+		words = words[len(words)-n:]
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeMarkup runs the normalizer over the text between markup tags, so pause lengths
+// are not spelled out.
+func normalizeMarkup(n *Normalizer, text string) string {
+	if n == nil {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range story.MarkupTag.FindAllStringIndex(text, -1) {
+		b.WriteString(n.Normalize(text[last:m[0]]))
+		b.WriteString(text[m[0]:m[1]])
+		last = m[1]
+	}
+	b.WriteString(n.Normalize(text[last:]))
+
+	return b.String()
+}
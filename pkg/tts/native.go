@@ -0,0 +1,140 @@
+package tts
+
+import (
+	"log"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// Cleanup configures the native silence and click removal.
+type Cleanup struct {
+	SilenceThreshold float64       // dBFS below which audio counts as silence
+	MaxSilence       time.Duration // Silences longer than this are shortened
+	KeepSilence      time.Duration // Length a shortened silence is cut down to
+	SpikeKnee        float64       // dBFS where spike attenuation starts
+	SpikeThreshold   float64       // dBFS at which a spike is muted completely
+}
+
+func DefaultCleanup() Cleanup {
+	return Cleanup{
+		SilenceThreshold: -60,
+		MaxSilence:       2 * time.Second,
+		KeepSilence:      700 * time.Millisecond,
+		SpikeKnee:        -6,
+		SpikeThreshold:   -2,
+	}
+}
+
+// maxMP3SampleRate is the highest sample rate the MP3 encoder takes.
+const maxMP3SampleRate = 48000
+
+// joinNative decodes all chunk files, cleans and normalizes each of them, adds the
+// configured sounds and encodes the joined result once into output.
+// Chunks may come in any format and sample rate, they are converted to the best of them.
+func joinNative(chunks []Chunk, output string, opts Options) (*audio.Loudness, time.Duration, error) {
+	voices := make([]*audio.PCM, len(chunks))
+	sampleRate, channels := 0, 0
+	for i, chunk := range chunks {
+		log.Printf("Decoding %d %s file %s\n", i, chunk.Format, chunk.File)
+		pcm, err := audio.DecodeFile(chunk.File)
+		if err != nil {
+			return nil, 0, err
+		}
+		sampleRate, channels = max(sampleRate, pcm.SampleRate), max(channels, pcm.Channels)
+		voices[i] = pcm
+	}
+	if opts.format() == audio.FormatMP3 {
+		sampleRate, channels = min(sampleRate, maxMP3SampleRate), min(channels, 2)
+	}
+
+	for i, pcm := range voices {
+		pcm = audio.Convert(pcm, sampleRate, channels)
+		if opts.cleanNatively() {
+			raw := pcm.Seconds()
+			cleanPCM(pcm, opts.Cleanup)
+			// Shortened silences move the words, spread the difference evenly
+			if raw > 0 {
+				stretchWords(chunks[i].Words, pcm.Seconds()/raw)
+			}
+		}
+		if opts.Normalize {
+			measured := audio.Measure(pcm)
+			chunks[i].Loudness = &measured
+			log.Printf("Chunk %d: %.1f LUFS, %.1f dBTP\n", i, measured.Integrated, measured.TruePeak)
+			audio.Normalize(pcm, opts.LoudnessTarget, opts.TruePeakLimit)
+		}
+		voices[i] = pcm
+	}
+
+	sounds, err := opts.Sounds.load(sampleRate, channels, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	sounds.tail, err = loadSound(opts.Sleep.Tail, sampleRate, channels, Options{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	joined := audio.New(sampleRate, channels)
+	if sounds.intro != nil {
+		joined.Append(sounds.intro)
+	}
+	narrationStart := joined.Frames()
+	for i, pcm := range voices {
+		if i > 0 && chunks[i].Chapter != chunks[i-1].Chapter && sounds.transition != nil {
+			joined.Append(sounds.transition)
+		}
+		joined.AppendSilence(chunks[i].PauseBefore)
+		chunks[i].Start = joined.Duration()
+		joined.Append(pcm)
+		chunks[i].End = joined.Duration()
+		joined.AppendSilence(chunks[i].PauseAfter)
+	}
+	narrationEnd := joined.Frames()
+	joined.AppendSilence(opts.Sleep.tailLength(sounds.bed != nil))
+	if sounds.bed != nil {
+		if err := audio.MixBed(joined, sounds.bed, narrationStart, joined.Frames(), opts.Sounds.Ducking); err != nil {
+			return nil, 0, err
+		}
+	}
+	if opts.Sleep.Enabled {
+		// Nothing loud after "The End." in sleep mode, the outro is skipped
+		if err := opts.Sleep.addTail(joined, sounds.tail, narrationEnd); err != nil {
+			return nil, 0, err
+		}
+	} else if sounds.outro != nil {
+		joined.Append(sounds.outro)
+	}
+
+	var loudness audio.Loudness
+	if opts.Normalize {
+		loudness = audio.Normalize(joined, opts.LoudnessTarget, opts.TruePeakLimit)
+	} else {
+		loudness = audio.Measure(joined)
+	}
+	log.Printf("Final: %.1f LUFS, %.1f dBTP\n", loudness.Integrated, loudness.TruePeak)
+
+	log.Printf("Encoding %s\n", output)
+	if err := audio.EncodeFile(output, joined, opts.format()); err != nil {
+		return nil, 0, err
+	}
+	return &loudness, joined.Duration(), nil
+}
+
+func stretchWords(words []story.TranscriptWord, scale float64) {
+	for k := range words {
+		words[k].Start *= scale
+		words[k].End *= scale
+	}
+}
+
+func cleanPCM(pcm *audio.PCM, cleanup Cleanup) {
+	spikes := audio.SuppressSpikes(pcm, cleanup.SpikeKnee, cleanup.SpikeThreshold)
+	trimmed, removed := audio.TrimSilences(pcm, cleanup.SilenceThreshold, cleanup.MaxSilence, cleanup.KeepSilence)
+	*pcm = *trimmed
+	if spikes > 0 || removed > 0 {
+		log.Printf("Attenuated %d loud spikes, removed %s of silence\n", spikes, removed.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,392 @@
+package tts
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Normalizer rewrites text the way it should be spoken before it is sent to TTS.
+// Numbers, ordinals, dates, times, currency, units and abbreviations are spelled out
+// for languages in numberLanguages, the pronunciation lexicon applies to every language.
+type Normalizer struct {
+	lexicon  []lexiconEntry
+	words    numberWords
+	spelling bool // Language has number words
+
+	abbreviation *regexp.Regexp
+	isoDate      *regexp.Regexp
+	monthDate    *regexp.Regexp
+	dateMonth    *regexp.Regexp
+	ordinalNoun  *regexp.Regexp
+	clock        *regexp.Regexp
+	money        *regexp.Regexp
+	moneyAfter   *regexp.Regexp
+	ordinal      *regexp.Regexp
+	unit         *regexp.Regexp
+	year         *regexp.Regexp
+	negative     *regexp.Regexp
+	grouped      *regexp.Regexp
+	decimal      *regexp.Regexp
+	integer      *regexp.Regexp
+}
+
+type lexiconEntry struct {
+	word    *regexp.Regexp
+	replace string
+}
+
+// NewNormalizer prepares normalization rules for the language.
+// lexicon maps words, e.g. invented character names, to how they should be pronounced.
+func NewNormalizer(language utils.Language, lexicon map[string]string) *Normalizer {
+	n := &Normalizer{}
+
+	// Longest words first so "Captain Whiskers" wins over "Whiskers"
+	words := make([]string, 0, len(lexicon))
+	for w := range lexicon {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	for _, w := range words {
+		n.lexicon = append(n.lexicon, lexiconEntry{
+			word:    regexp.MustCompile(`(?i)(^|[^\p{L}\d])` + regexp.QuoteMeta(w) + `($|[^\p{L}\d])`),
+			replace: lexicon[w],
+		})
+	}
+
+	n.words, n.spelling = numberLanguages[language.ISO1]
+	if !n.spelling {
+		return n
+	}
+	w := n.words
+
+	abbreviations := make([]string, 0, len(w.abbreviations)+1)
+	for a := range w.abbreviations {
+		abbreviations = append(abbreviations, regexp.QuoteMeta(a))
+	}
+	if language.ISO1 == "en" {
+		abbreviations = append(abbreviations, regexp.QuoteMeta("St."))
+	}
+	sort.Slice(abbreviations, func(i, j int) bool { return len(abbreviations[i]) > len(abbreviations[j]) })
+	n.abbreviation = regexp.MustCompile(`(^|[^\p{L}\d.])(` + strings.Join(abbreviations, "|") + `)`)
+
+	months := make([]string, 0, len(w.months))
+	for _, m := range w.months {
+		months = append(months, regexp.QuoteMeta(m))
+	}
+	month := `(` + strings.Join(months, "|") + `)`
+	end := `([^\p{L}\d]|$)`
+
+	n.isoDate = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	switch language.ISO1 {
+	case "en":
+		n.monthDate = regexp.MustCompile(month + `\s+(\d{1,2})(?:st|nd|rd|th)?(?:,?\s+(\d{4}))?` + end)
+		n.dateMonth = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + month + `(?:,?\s+(\d{4}))?` + end)
+	case "de":
+		n.dateMonth = regexp.MustCompile(`\b(\d{1,2})\.\s+` + month + `(?:\s+(\d{4}))?` + end)
+	case "ru":
+		n.dateMonth = regexp.MustCompile(`\b(\d{1,2})\s+` + month + `(?:\s+(\d{4}))?` + end)
+	}
+	if w.ordinalNoun != nil {
+		// Latvian writes ordinals as "3." and inflects them like the noun: "3. maijā", "2024. gada"
+		n.ordinalNoun = regexp.MustCompile(`\b(\d+)\.\s+(\p{Ll}+)`)
+	}
+	n.clock = regexp.MustCompile(`\b(\d{1,2}):(\d{2})\b`)
+
+	symbols := make([]string, 0, len(w.currencies))
+	for s := range w.currencies {
+		symbols = append(symbols, regexp.QuoteMeta(s))
+	}
+	amount := `(\d+(?:[.,]\d{1,2})?)`
+	n.money = regexp.MustCompile(`(` + strings.Join(symbols, "|") + `)\s?` + amount)
+	n.moneyAfter = regexp.MustCompile(amount + `\s?(` + strings.Join(symbols, "|") + `)`)
+
+	switch language.ISO1 {
+	case "en":
+		n.ordinal = regexp.MustCompile(`\b(\d+)(?:st|nd|rd|th)\b`)
+	case "ru":
+		n.ordinal = regexp.MustCompile(`\b(\d+)-(?:й|я|е|го|ый|ой|ий)`)
+	}
+
+	// Single letter units like "m" or "g" are words or abbreviations ("1990 г.") as often as units,
+	// they are only read as units when written right after the number, "5m" or "200g"
+	units, letters := make([]string, 0, len(w.units)), make([]string, 0)
+	for u := range w.units {
+		if r := []rune(u); len(r) == 1 && unicode.IsLetter(r[0]) {
+			letters = append(letters, regexp.QuoteMeta(u))
+		} else {
+			units = append(units, regexp.QuoteMeta(u))
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return len(units[i]) > len(units[j]) })
+	unit := `\s?(?:` + strings.Join(units, "|") + `)`
+	if len(letters) > 0 {
+		unit += `|(?:` + strings.Join(letters, "|") + `)`
+	}
+	n.unit = regexp.MustCompile(`(\d+(?:[.,]\d+)?)(` + unit + `)($|[^\p{L}\d])`)
+
+	if w.year != nil {
+		prefixes := map[string]string{
+			"en": `in|of|since|until|by|from|year|before|after`,
+			"de": `im|Jahr|Jahre|seit|bis|von|vor|nach`,
+		}[language.ISO1]
+		n.year = regexp.MustCompile(`(?i)\b(` + prefixes + `)\s+(\d{4})\b`)
+	}
+	n.negative = regexp.MustCompile(`(^|[\s(])[-−](\d)`)
+	if w.decimalSeparator == '.' {
+		n.grouped = regexp.MustCompile(`\b\d{1,3}(?:,\d{3})+\b`)
+		n.decimal = regexp.MustCompile(`\b(\d+)\.(\d+)\b`)
+	} else {
+		n.grouped = regexp.MustCompile(`\b\d{1,3}(?:[.\x{00A0}]\d{3})+\b`)
+		n.decimal = regexp.MustCompile(`\b(\d+),(\d+)\b`)
+	}
+	n.integer = regexp.MustCompile(`\d+`)
+
+	return n
+}
+
+// LoadLexicon reads a pronunciation lexicon with one "word = pronunciation" per line.
+// Empty lines and lines starting with # are skipped.
+func LoadLexicon(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lexicon: %w", err)
+	}
+	defer f.Close()
+
+	lexicon := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		word, pronunciation, ok := strings.Cut(text, "=")
+		if !ok || strings.TrimSpace(word) == "" {
+			return nil, fmt.Errorf("lexicon %s line %d: expected \"word = pronunciation\"", file, line)
+		}
+		lexicon[strings.TrimSpace(word)] = strings.TrimSpace(pronunciation)
+	}
+	return lexicon, scanner.Err()
+}
+
+// Normalize returns text rewritten for speech. A nil Normalizer returns text unchanged.
+func (n *Normalizer) Normalize(text string) string {
+	if n == nil {
+		return text
+	}
+	for _, entry := range n.lexicon {
+		// Run twice because neighbouring matches share the separator between them
+		for i := 0; i < 2; i++ {
+			text = entry.word.ReplaceAllString(text, "${1}"+strings.ReplaceAll(entry.replace, "$", "$$")+"${2}")
+		}
+	}
+	if !n.spelling {
+		return text
+	}
+
+	text = n.expandAbbreviations(text)
+	text = n.negative.ReplaceAllString(text, "${1}"+n.words.minus+" ${2}")
+	text = replaceSubmatches(n.isoDate, text, func(m []string) string {
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			return m[0]
+		}
+		return n.date(m[3], n.words.months[month-1], m[1], true)
+	})
+	if n.monthDate != nil {
+		text = replaceSubmatches(n.monthDate, text, func(m []string) string {
+			return n.date(m[2], m[1], m[3], false) + m[4]
+		})
+	}
+	if n.dateMonth != nil {
+		text = replaceSubmatches(n.dateMonth, text, func(m []string) string {
+			return n.date(m[1], m[2], m[3], true) + m[4]
+		})
+	}
+	if n.ordinalNoun != nil {
+		text = replaceSubmatches(n.ordinalNoun, text, func(m []string) string {
+			return n.words.ordinalNoun(n.spell(m[1], n.words.ordinal), m[2]) + " " + m[2]
+		})
+	}
+	text = replaceSubmatches(n.clock, text, func(m []string) string {
+		hours, _ := strconv.ParseInt(m[1], 10, 64)
+		minutes, _ := strconv.ParseInt(m[2], 10, 64)
+		if hours > 24 || minutes > 59 {
+			return m[0]
+		}
+		return n.words.clock(n.words, hours, minutes)
+	})
+	text = replaceSubmatches(n.money, text, func(m []string) string {
+		return n.amount(m[2], n.words.currencies[m[1]])
+	})
+	text = replaceSubmatches(n.moneyAfter, text, func(m []string) string {
+		return n.amount(m[1], n.words.currencies[m[2]])
+	})
+	if n.ordinal != nil {
+		text = replaceSubmatches(n.ordinal, text, func(m []string) string {
+			return n.spell(m[1], n.words.ordinal)
+		})
+	}
+	text = replaceSubmatches(n.unit, text, func(m []string) string {
+		forms := n.words.units[strings.TrimSpace(m[2])]
+		count := int64(2) // Fractions use the plural form
+		if !strings.ContainsAny(m[1], ".,") {
+			count, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		return n.number(m[1]) + " " + n.words.forms(count, forms) + m[3]
+	})
+	if n.year != nil {
+		text = replaceSubmatches(n.year, text, func(m []string) string {
+			return m[1] + " " + n.spell(m[2], n.words.year)
+		})
+	}
+	text = replaceSubmatches(n.grouped, text, func(m []string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, m[0])
+	})
+	text = replaceSubmatches(n.decimal, text, func(m []string) string {
+		return n.number(m[0])
+	})
+	text = replaceSubmatches(n.integer, text, func(m []string) string {
+		return n.spell(m[0], n.words.cardinal)
+	})
+	text = strings.ReplaceAll(text, " & ", " "+n.words.and+" ")
+
+	return text
+}
+
+// expandAbbreviations replaces abbreviations, keeping the full stop when the abbreviation ended a sentence.
+func (n *Normalizer) expandAbbreviations(text string) string {
+	out := strings.Builder{}
+	last := 0
+	for _, m := range n.abbreviation.FindAllStringSubmatchIndex(text, -1) {
+		abbreviation := text[m[4]:m[5]]
+		rest := text[m[5]:]
+		next := strings.TrimLeftFunc(rest, unicode.IsSpace)
+		spaced := len(next) < len(rest)
+
+		expanded, ok := n.words.abbreviations[abbreviation]
+		if abbreviation == "St." {
+			// "Baker St." is a street, "St. Peter" a saint
+			expanded = "Street"
+			if !streetName(text[:m[4]]) && spaced && startsUpper(next) {
+				expanded = "Saint"
+			}
+		} else if !ok {
+			continue
+		}
+
+		// Titles like "Dr." always precede a name, other abbreviations may end a sentence
+		title := startsUpper(abbreviation) && expanded != "Street"
+		newSentence := next == "" || strings.Contains(rest[:len(rest)-len(next)], "\n") ||
+			(!n.words.nounsCapitalized && spaced && startsUpper(next))
+		if !title && newSentence {
+			expanded += "."
+		}
+
+		out.WriteString(text[last:m[4]])
+		out.WriteString(expanded)
+		last = m[5]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// streetName reports whether the text before "St." ends with a capitalized word that does not
+// start a sentence, the name of the street.
+func streetName(before string) bool {
+	words := strings.Fields(before)
+	if len(words) < 2 || !startsUpper(words[len(words)-1]) || words[len(words)-1] == "I" {
+		return false
+	}
+	previous := words[len(words)-2]
+	return !strings.ContainsAny(previous[len(previous)-1:], ".!?")
+}
+
+func startsUpper(s string) bool {
+	for _, r := range s {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+func (n *Normalizer) date(day, month, year string, dayFirst bool) string {
+	d, err := strconv.ParseInt(day, 10, 64)
+	if err != nil || d < 1 || d > 31 {
+		return strings.TrimSpace(day + " " + month + " " + year)
+	}
+	spoken := n.words.date(n.words, d, month, dayFirst)
+	if year != "" {
+		spoken += " " + n.spell(year, n.yearWords())
+	}
+	return spoken
+}
+
+func (n *Normalizer) yearWords() func(int64) string {
+	if n.words.year != nil {
+		return n.words.year
+	}
+	return n.words.cardinal
+}
+
+// amount spells money, "5.50" dollars gives "five dollars fifty cents".
+func (n *Normalizer) amount(value string, c currency) string {
+	whole, fraction, _ := strings.Cut(strings.ReplaceAll(value, ",", "."), ".")
+	main, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return value
+	}
+	spoken := n.words.cardinal(main) + " " + n.words.forms(main, c.main)
+	if fraction == "" {
+		return spoken
+	}
+	if len(fraction) == 1 {
+		fraction += "0"
+	}
+	cents, _ := strconv.ParseInt(fraction, 10, 64)
+	if cents == 0 {
+		return spoken
+	}
+	return spoken + " " + n.words.cardinal(cents) + " " + n.words.forms(cents, c.fraction)
+}
+
+// number spells an integer or a decimal number, reading decimals digit by digit.
+func (n *Normalizer) number(value string) string {
+	whole, fraction, ok := strings.Cut(strings.ReplaceAll(value, ",", "."), ".")
+	spoken := n.spell(whole, n.words.cardinal)
+	if !ok {
+		return spoken
+	}
+	digits := make([]string, 0, len(fraction))
+	for _, d := range fraction {
+		digits = append(digits, n.words.cardinal(int64(d-'0')))
+	}
+	return spoken + " " + n.words.decimal + " " + strings.Join(digits, " ")
+}
+
+// spell converts digits with the given speller, numbers too long to read stay as they are.
+func (n *Normalizer) spell(digits string, speller func(int64) string) string {
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || len(digits) > 15 {
+		return digits
+	}
+	return speller(value)
+}
+
+func replaceSubmatches(re *regexp.Regexp, text string, replace func(m []string) string) string {
+	return re.ReplaceAllStringFunc(text, func(match string) string {
+		return replace(re.FindStringSubmatch(match))
+	})
+}
@@ -0,0 +1,80 @@
+package tts
+
+import (
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		language string
+		name     string
+		text     string
+		want     string
+	}{
+		{"english", "month date", "On May 3rd, 2024 we met.", "On May third twenty twenty-four we met."},
+		{"english", "date month", "The 3rd of March.", "The third of March."},
+		{"english", "iso date", "Born 2024-01-15.", "Born fifteenth of January twenty twenty-four."},
+		{"english", "currency", "It cost $5.50 and £3.", "It cost five dollars fifty cents and three pounds."},
+		{"english", "ordinals", "He was 1st and 22nd.", "He was first and twenty-second."},
+		{"english", "clock", "Wake at 7:05.", "Wake at seven oh five."},
+		{"english", "negative", "It was -5 degrees.", "It was minus five degrees."},
+		{"english", "year", "In 1999 it rained.", "In nineteen ninety-nine it rained."},
+		{"english", "grouped and decimal", "1,234 stars and 3.14.", "one thousand two hundred thirty-four stars and three point one four."},
+		{"english", "units", "We walked 5 km and 200g.", "We walked five kilometers and two hundred grams."},
+		{"english", "spaced single letter is not a unit", "Plan B: 2 m away.", "Plan B: two m away."},
+		{"english", "title", "Mr. Smith came.", "Mister Smith came."},
+		{"english", "abbreviation at sentence end", "Books, pens, etc. Then we left.", "Books, pens, et cetera. Then we left."},
+		{"english", "abbreviation at text end", "Books, pens, etc.", "Books, pens, et cetera."},
+		{"english", "saint and street at sentence end", "Go to St. Peter on Baker St. Then rest.", "Go to Saint Peter on Baker Street. Then rest."},
+		{"english", "street in a sentence", "Baker St. was busy.", "Baker Street was busy."},
+		{"english", "saint after I", "I saw St. Paul.", "I saw Saint Paul."},
+
+		{"german", "date and clock", "Am 3. Mai 2024 um 14:30.", "Am dritten Mai zweitausendvierundzwanzig um vierzehn Uhr dreißig."},
+		{"german", "currency after the amount", "Es kostet 5,50 € heute.", "Es kostet fünf Euro fünfzig Cent heute."},
+		{"german", "negative temperature", "Es war -3 °C kalt.", "Es war minus drei Grad Celsius kalt."},
+		{"german", "year", "Im Jahr 1999.", "Im Jahr neunzehnhundertneunundneunzig."},
+		{"german", "grouped", "1.234 Sterne.", "eintausendzweihundertvierunddreißig Sterne."},
+		{"german", "abbreviation before a number", "Nr. 5 ist da.", "Nummer fünf ist da."},
+		{"german", "abbreviation at text end", "Äpfel, Birnen usw.", "Äpfel, Birnen und so weiter."},
+
+		{"latvian", "ordinal date and clock", "2024. gada 3. maijā plkst. 7:30.", "divi tūkstoši divdesmit ceturtā gada trešajā maijā pulksten septiņi trīsdesmit."},
+		{"latvian", "ordinal noun", "Viņš bija 1. klasē.", "Viņš bija pirmajā klasē."},
+		{"latvian", "currency", "Tas maksā 5,50 €.", "Tas maksā pieci eiro piecdesmit centi."},
+		{"latvian", "negative temperature", "Bija -3 °C.", "Bija mīnus trīs grādi pēc Celsija."},
+		{"latvian", "unit at sentence end", "Noskrēja 5 km.", "Noskrēja pieci kilometri."},
+		{"latvian", "abbreviation at sentence end", "Āboli, bumbieri utt. Tad mēs ēdām.", "Āboli, bumbieri un tā tālāk. Tad mēs ēdām."},
+
+		{"russian", "date and clock", "3 мая 2024 года в 7:30.", "третье мая две тысячи двадцать четыре года в семь тридцать."},
+		{"russian", "currency plural forms", "Это стоит 5 ₽ и 21 ₽.", "Это стоит пять рублей и двадцать один рубль."},
+		{"russian", "negative temperature", "Было -5 °C.", "Было минус пять градусов Цельсия."},
+		{"russian", "ordinal", "Он был 1-й.", "Он был первый."},
+		{"russian", "year abbreviation is not grams", "В 1990 г. было 2 кг яблок и 5л воды.", "В одна тысяча девятьсот девяносто г. было два килограмма яблок и пять литров воды."},
+		{"russian", "abbreviation at sentence end", "Яблоки, груши и т.д. Потом мы ели.", "Яблоки, груши и так далее. Потом мы ели."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language+" "+tt.name, func(t *testing.T) {
+			n := NewNormalizer(utils.FindLanguage(tt.language), nil)
+			if got := n.Normalize(tt.text); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLexicon(t *testing.T) {
+	n := NewNormalizer(utils.FindLanguage("greek"), map[string]string{"Whiskers": "Wiss-kers", "Captain Whiskers": "Cap-tin Wiss-kers"})
+	got := n.Normalize("Captain Whiskers and Whiskers, 5 cats.")
+	// Greek has no number words, only the lexicon applies
+	if want := "Cap-tin Wiss-kers and Wiss-kers, 5 cats."; got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+}
+
+func TestNilNormalizer(t *testing.T) {
+	var n *Normalizer
+	if got := n.Normalize("5 cats"); got != "5 cats" {
+		t.Errorf("Normalize() = %q, want the text unchanged", got)
+	}
+}
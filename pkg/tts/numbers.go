@@ -0,0 +1,595 @@
+package tts
+
+import "strings"
+
+// numberWords spells numbers and names things counted by them in one language.
+type numberWords struct {
+	cardinal func(n int64) string
+	ordinal  func(n int64) string
+	year     func(n int64) string // Nil reads years as cardinals
+	// plural picks the form index for forms given as {one, few, many}
+	plural func(n int64) int
+
+	decimal          string // Word read for the decimal separator
+	minus            string
+	and              string
+	decimalSeparator byte
+	nounsCapitalized bool // Capital letters do not mark a new sentence
+
+	clock func(w numberWords, hours, minutes int64) string
+	// date reads a day of the month, dayFirst is set for "3 May" and unset for "May 3"
+	date func(w numberWords, day int64, month string, dayFirst bool) string
+	// ordinalNoun inflects an ordinal written as "3." to agree with the noun after it, nil when unused
+	ordinalNoun func(ordinal, noun string) string
+
+	months        []string
+	abbreviations map[string]string
+	currencies    map[string]currency
+	units         map[string][3]string
+}
+
+type currency struct {
+	main, fraction [3]string
+}
+
+// forms returns the form of word matching n.
+func (w numberWords) forms(n int64, forms [3]string) string {
+	return forms[w.plural(n)]
+}
+
+// clockCardinal reads 10:30 as "ten thirty".
+func clockCardinal(w numberWords, hours, minutes int64) string {
+	if minutes == 0 {
+		return w.cardinal(hours)
+	}
+	return w.cardinal(hours) + " " + w.cardinal(minutes)
+}
+
+func pluralSimple(n int64) int {
+	if n == 1 {
+		return 0
+	}
+	return 2
+}
+
+// numberLanguages holds the languages with full number support, keyed by ISO 639-1 code.
+var numberLanguages = map[string]numberWords{
+	"en": englishWords,
+	"de": germanWords,
+	"lv": latvianWords,
+	"ru": russianWords,
+}
+
+// scaleWords spells n by splitting it into groups of thousands.
+// group spells 1..999 and scale returns the name of the power of thousand for the group count.
+func scaleWords(n int64, group func(n int64, scale int) string, scale func(n int64, scale int) string, sep string) string {
+	parts := make([]string, 0)
+	for s := 4; s >= 0; s-- {
+		div := int64(1)
+		for i := 0; i < s; i++ {
+			div *= 1000
+		}
+		g := (n / div) % 1000
+		if g == 0 {
+			continue
+		}
+		word := group(g, s)
+		if s > 0 {
+			word = scale(g, s)
+		}
+		parts = append(parts, word)
+	}
+	return strings.Join(parts, sep)
+}
+
+var englishWords = numberWords{
+	cardinal: englishCardinal,
+	ordinal:  englishOrdinal,
+	year:     englishYear,
+	plural:   pluralSimple,
+
+	decimal:          "point",
+	minus:            "minus",
+	and:              "and",
+	decimalSeparator: '.',
+
+	clock: func(w numberWords, hours, minutes int64) string {
+		switch {
+		case minutes == 0:
+			return w.cardinal(hours) + " o'clock"
+		case minutes < 10:
+			return w.cardinal(hours) + " oh " + w.cardinal(minutes)
+		}
+		return w.cardinal(hours) + " " + w.cardinal(minutes)
+	},
+	date: func(w numberWords, day int64, month string, dayFirst bool) string {
+		if dayFirst {
+			return w.ordinal(day) + " of " + month
+		}
+		return month + " " + w.ordinal(day)
+	},
+
+	months: []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	abbreviations: map[string]string{
+		"Mr.": "Mister", "Mrs.": "Missus", "Ms.": "Miz", "Dr.": "Doctor", "Prof.": "Professor",
+		"Jr.": "Junior", "Sr.": "Senior", "Mt.": "Mount", "Capt.": "Captain", "Gen.": "General",
+		"etc.": "et cetera", "e.g.": "for example", "i.e.": "that is", "vs.": "versus", "approx.": "approximately",
+		"Ave.": "Avenue", "Rd.": "Road",
+	},
+	currencies: map[string]currency{
+		"$": {main: [3]string{"dollar", "dollars", "dollars"}, fraction: [3]string{"cent", "cents", "cents"}},
+		"€": {main: [3]string{"euro", "euros", "euros"}, fraction: [3]string{"cent", "cents", "cents"}},
+		"£": {main: [3]string{"pound", "pounds", "pounds"}, fraction: [3]string{"penny", "pence", "pence"}},
+	},
+	units: map[string][3]string{
+		"km": {"kilometer", "kilometers", "kilometers"}, "m": {"meter", "meters", "meters"},
+		"cm": {"centimeter", "centimeters", "centimeters"}, "mm": {"millimeter", "millimeters", "millimeters"},
+		"kg": {"kilogram", "kilograms", "kilograms"}, "g": {"gram", "grams", "grams"},
+		"l": {"liter", "liters", "liters"}, "ml": {"milliliter", "milliliters", "milliliters"},
+		"km/h": {"kilometer per hour", "kilometers per hour", "kilometers per hour"},
+		"mph":  {"mile per hour", "miles per hour", "miles per hour"},
+		"°C":   {"degree Celsius", "degrees Celsius", "degrees Celsius"}, "°F": {"degree Fahrenheit", "degrees Fahrenheit", "degrees Fahrenheit"},
+		"%": {"percent", "percent", "percent"},
+	},
+}
+
+var englishSmall = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+	"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+var englishTens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+var englishScales = []string{"", "thousand", "million", "billion", "trillion"}
+
+func englishCardinal(n int64) string {
+	if n < 0 {
+		return "minus " + englishCardinal(-n)
+	}
+	if n == 0 {
+		return englishSmall[0]
+	}
+	group := func(n int64, _ int) string {
+		parts := make([]string, 0)
+		if n >= 100 {
+			parts = append(parts, englishSmall[n/100], "hundred")
+			n %= 100
+		}
+		switch {
+		case n == 0:
+		case n < 20:
+			parts = append(parts, englishSmall[n])
+		case n%10 == 0:
+			parts = append(parts, englishTens[n/10])
+		default:
+			parts = append(parts, englishTens[n/10]+"-"+englishSmall[n%10])
+		}
+		return strings.Join(parts, " ")
+	}
+	scale := func(n int64, s int) string {
+		return group(n, s) + " " + englishScales[s]
+	}
+	return scaleWords(n, group, scale, " ")
+}
+
+var englishOrdinalIrregular = map[string]string{
+	"one": "first", "two": "second", "three": "third", "five": "fifth",
+	"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+}
+
+func englishOrdinal(n int64) string {
+	words := englishCardinal(n)
+	// Only the last word changes: "twenty-one" gives "twenty-first"
+	cut := strings.LastIndexAny(words, " -") + 1
+	last := words[cut:]
+	switch {
+	case englishOrdinalIrregular[last] != "":
+		last = englishOrdinalIrregular[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return words[:cut] + last
+}
+
+// englishYear reads years in pairs, 1999 gives "nineteen ninety-nine".
+func englishYear(n int64) string {
+	switch {
+	case n < 1100 || n > 2099:
+		return englishCardinal(n)
+	case n >= 2000 && n < 2010:
+		return englishCardinal(n)
+	case n%100 == 0:
+		return englishCardinal(n/100) + " hundred"
+	case n%100 < 10:
+		return englishCardinal(n/100) + " oh " + englishCardinal(n%100)
+	default:
+		return englishCardinal(n/100) + " " + englishCardinal(n%100)
+	}
+}
+
+var germanWords = numberWords{
+	cardinal: germanCardinal,
+	ordinal:  germanOrdinal,
+	year:     germanYear,
+	plural:   pluralSimple,
+
+	decimal:          "Komma",
+	minus:            "minus",
+	and:              "und",
+	decimalSeparator: ',',
+	nounsCapitalized: true,
+
+	clock: func(w numberWords, hours, minutes int64) string {
+		if minutes == 0 {
+			return w.cardinal(hours) + " Uhr"
+		}
+		return w.cardinal(hours) + " Uhr " + w.cardinal(minutes)
+	},
+	date: func(w numberWords, day int64, month string, _ bool) string {
+		// Dates mostly follow "am", "vom" or "bis zum": "am dritten Mai"
+		return w.ordinal(day) + "n " + month
+	},
+
+	months: []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	abbreviations: map[string]string{
+		"Dr.": "Doktor", "Prof.": "Professor", "Hr.": "Herr", "Fr.": "Frau", "St.": "Sankt",
+		"z.B.": "zum Beispiel", "bzw.": "beziehungsweise", "usw.": "und so weiter", "d.h.": "das heißt",
+		"ca.": "circa", "Nr.": "Nummer", "Str.": "Straße",
+	},
+	currencies: map[string]currency{
+		"$": {main: [3]string{"Dollar", "Dollar", "Dollar"}, fraction: [3]string{"Cent", "Cent", "Cent"}},
+		"€": {main: [3]string{"Euro", "Euro", "Euro"}, fraction: [3]string{"Cent", "Cent", "Cent"}},
+		"£": {main: [3]string{"Pfund", "Pfund", "Pfund"}, fraction: [3]string{"Penny", "Pence", "Pence"}},
+	},
+	units: map[string][3]string{
+		"km": {"Kilometer", "Kilometer", "Kilometer"}, "m": {"Meter", "Meter", "Meter"},
+		"cm": {"Zentimeter", "Zentimeter", "Zentimeter"}, "mm": {"Millimeter", "Millimeter", "Millimeter"},
+		"kg": {"Kilogramm", "Kilogramm", "Kilogramm"}, "g": {"Gramm", "Gramm", "Gramm"},
+		"l": {"Liter", "Liter", "Liter"}, "ml": {"Milliliter", "Milliliter", "Milliliter"},
+		"km/h": {"Kilometer pro Stunde", "Kilometer pro Stunde", "Kilometer pro Stunde"},
+		"°C":   {"Grad Celsius", "Grad Celsius", "Grad Celsius"}, "°F": {"Grad Fahrenheit", "Grad Fahrenheit", "Grad Fahrenheit"},
+		"%": {"Prozent", "Prozent", "Prozent"},
+	},
+}
+
+var germanSmall = []string{"null", "eins", "zwei", "drei", "vier", "fünf", "sechs", "sieben", "acht", "neun", "zehn",
+	"elf", "zwölf", "dreizehn", "vierzehn", "fünfzehn", "sechzehn", "siebzehn", "achtzehn", "neunzehn"}
+var germanTens = []string{"", "", "zwanzig", "dreißig", "vierzig", "fünfzig", "sechzig", "siebzig", "achtzig", "neunzig"}
+var germanScales = [][2]string{{"", ""}, {"tausend", "tausend"}, {"Million", "Millionen"}, {"Milliarde", "Milliarden"}, {"Billion", "Billionen"}}
+
+// germanUnits spells 1..99, "ein" is used in compounds like "einundzwanzig".
+func germanUnits(n int64) string {
+	switch {
+	case n == 1:
+		return "eins"
+	case n < 20:
+		return germanSmall[n]
+	case n%10 == 0:
+		return germanTens[n/10]
+	}
+	unit := germanSmall[n%10]
+	if n%10 == 1 {
+		unit = "ein"
+	}
+	return unit + "und" + germanTens[n/10]
+}
+
+func germanCardinal(n int64) string {
+	if n < 0 {
+		return "minus " + germanCardinal(-n)
+	}
+	if n == 0 {
+		return germanSmall[0]
+	}
+	group := func(n int64, _ int) string {
+		word := ""
+		if n >= 100 {
+			hundreds := germanSmall[n/100]
+			if n/100 == 1 {
+				hundreds = "ein"
+			}
+			word = hundreds + "hundert"
+			n %= 100
+		}
+		if n > 0 {
+			word += germanUnits(n)
+		}
+		return word
+	}
+	scale := func(n int64, s int) string {
+		if s == 1 {
+			word := group(n, s)
+			if n == 1 {
+				word = "ein"
+			}
+			return word + germanScales[s][0]
+		}
+		if n == 1 {
+			return "eine " + germanScales[s][0]
+		}
+		return group(n, s) + " " + germanScales[s][1]
+	}
+	words := scaleWords(n, group, scale, " ")
+	// Everything below a million is written as one word
+	return strings.Replace(words, "tausend ", "tausend", 1)
+}
+
+func germanOrdinal(n int64) string {
+	switch n {
+	case 1:
+		return "erste"
+	case 3:
+		return "dritte"
+	case 7:
+		return "siebte"
+	case 8:
+		return "achte"
+	}
+	words := germanCardinal(n)
+	if n%100 < 20 && n%100 != 0 {
+		if n%100 == 1 {
+			return strings.TrimSuffix(words, "s") + "te"
+		}
+		return words + "te"
+	}
+	return words + "ste"
+}
+
+func germanYear(n int64) string {
+	if n >= 1100 && n < 2000 {
+		word := germanSmall[n/100] + "hundert"
+		if n%100 > 0 {
+			word += germanUnits(n % 100)
+		}
+		return word
+	}
+	return germanCardinal(n)
+}
+
+var latvianWords = numberWords{
+	cardinal: latvianCardinal,
+	ordinal:  latvianOrdinal,
+	plural: func(n int64) int {
+		if n%10 == 1 && n%100 != 11 {
+			return 0
+		}
+		return 2
+	},
+
+	decimal:          "komats",
+	minus:            "mīnus",
+	and:              "un",
+	decimalSeparator: ',',
+
+	clock: clockCardinal,
+	date: func(w numberWords, day int64, month string, _ bool) string {
+		return latvianOrdinalNoun(w.ordinal(day), month) + " " + month
+	},
+	ordinalNoun: latvianOrdinalNoun,
+
+	months: []string{"janvāris", "februāris", "marts", "aprīlis", "maijs", "jūnijs", "jūlijs", "augusts", "septembris", "oktobris", "novembris", "decembris"},
+	abbreviations: map[string]string{
+		"utt.": "un tā tālāk", "piem.": "piemēram", "t.i.": "tas ir", "u.c.": "un citi",
+		"Dr.": "doktors", "prof.": "profesors", "plkst.": "pulksten",
+	},
+	currencies: map[string]currency{
+		"$": {main: [3]string{"dolārs", "dolāri", "dolāri"}, fraction: [3]string{"cents", "centi", "centi"}},
+		"€": {main: [3]string{"eiro", "eiro", "eiro"}, fraction: [3]string{"cents", "centi", "centi"}},
+		"£": {main: [3]string{"mārciņa", "mārciņas", "mārciņas"}, fraction: [3]string{"penss", "pensi", "pensi"}},
+	},
+	units: map[string][3]string{
+		"km": {"kilometrs", "kilometri", "kilometri"}, "m": {"metrs", "metri", "metri"},
+		"cm": {"centimetrs", "centimetri", "centimetri"}, "mm": {"milimetrs", "milimetri", "milimetri"},
+		"kg": {"kilograms", "kilogrami", "kilogrami"}, "g": {"grams", "grami", "grami"},
+		"l": {"litrs", "litri", "litri"}, "ml": {"mililitrs", "mililitri", "mililitri"},
+		"km/h": {"kilometrs stundā", "kilometri stundā", "kilometri stundā"},
+		"°C":   {"grāds pēc Celsija", "grādi pēc Celsija", "grādi pēc Celsija"},
+		"%":    {"procents", "procenti", "procenti"},
+	},
+}
+
+var latvianSmall = []string{"nulle", "viens", "divi", "trīs", "četri", "pieci", "seši", "septiņi", "astoņi", "deviņi", "desmit",
+	"vienpadsmit", "divpadsmit", "trīspadsmit", "četrpadsmit", "piecpadsmit", "sešpadsmit", "septiņpadsmit", "astoņpadsmit", "deviņpadsmit"}
+var latvianTens = []string{"", "", "divdesmit", "trīsdesmit", "četrdesmit", "piecdesmit", "sešdesmit", "septiņdesmit", "astoņdesmit", "deviņdesmit"}
+var latvianScales = [][2]string{{"", ""}, {"tūkstotis", "tūkstoši"}, {"miljons", "miljoni"}, {"miljards", "miljardi"}, {"triljons", "triljoni"}}
+
+func latvianCardinal(n int64) string {
+	if n < 0 {
+		return "mīnus " + latvianCardinal(-n)
+	}
+	if n == 0 {
+		return latvianSmall[0]
+	}
+	group := func(n int64, _ int) string {
+		parts := make([]string, 0)
+		if n >= 200 {
+			parts = append(parts, latvianSmall[n/100], "simti")
+		} else if n >= 100 {
+			parts = append(parts, "simts")
+		}
+		n %= 100
+		switch {
+		case n == 0:
+		case n < 20:
+			parts = append(parts, latvianSmall[n])
+		case n%10 == 0:
+			parts = append(parts, latvianTens[n/10])
+		default:
+			parts = append(parts, latvianTens[n/10], latvianSmall[n%10])
+		}
+		return strings.Join(parts, " ")
+	}
+	scale := func(n int64, s int) string {
+		if n == 1 {
+			return latvianScales[s][0]
+		}
+		form := latvianScales[s][1]
+		if n%10 == 1 && n%100 != 11 {
+			form = latvianScales[s][0]
+		}
+		return group(n, s) + " " + form
+	}
+	return scaleWords(n, group, scale, " ")
+}
+
+var latvianOrdinals = map[string]string{
+	"viens": "pirmais", "divi": "otrais", "trīs": "trešais", "četri": "ceturtais", "pieci": "piektais",
+	"seši": "sestais", "septiņi": "septītais", "astoņi": "astotais", "deviņi": "devītais",
+	"simts": "simtais", "tūkstotis": "tūkstošais",
+}
+
+func latvianOrdinal(n int64) string {
+	words := latvianCardinal(n)
+	cut := strings.LastIndex(words, " ") + 1
+	last := words[cut:]
+	switch {
+	case latvianOrdinals[last] != "":
+		last = latvianOrdinals[last]
+	case strings.HasSuffix(last, "desmit"):
+		last += "ais"
+	default:
+		return words
+	}
+	return words[:cut] + last
+}
+
+// latvianOrdinalNoun inflects a definite masculine ordinal by the ending of the noun after it:
+// "3. maijā" gives "trešajā", "2024. gada" gives "ceturtā", "5. nodaļas" gives "piektās".
+func latvianOrdinalNoun(ordinal, noun string) string {
+	if !strings.HasSuffix(ordinal, "ais") {
+		return ordinal
+	}
+	stem := strings.TrimSuffix(ordinal, "ais")
+	switch {
+	case strings.HasSuffix(noun, "ā"):
+		return stem + "ajā"
+	case strings.HasSuffix(noun, "as"):
+		return stem + "ās"
+	case strings.HasSuffix(noun, "a"):
+		return stem + "ā"
+	case strings.HasSuffix(noun, "ē"), strings.HasSuffix(noun, "ī"):
+		return stem + "ajā"
+	case strings.HasSuffix(noun, "e"):
+		return stem + "ā"
+	}
+	return ordinal
+}
+
+var russianWords = numberWords{
+	cardinal: russianCardinal,
+	ordinal:  russianOrdinal,
+	plural:   russianPlural,
+
+	decimal:          "запятая",
+	minus:            "минус",
+	and:              "и",
+	decimalSeparator: ',',
+
+	clock: clockCardinal,
+	date: func(w numberWords, day int64, month string, _ bool) string {
+		return russianNeuter(w.ordinal(day)) + " " + month
+	},
+
+	months: []string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+	abbreviations: map[string]string{
+		"т.е.": "то есть", "т.д.": "так далее", "т.п.": "тому подобное", "др.": "другие",
+		"ул.": "улица", "д-р": "доктор", "проф.": "профессор",
+	},
+	currencies: map[string]currency{
+		"$": {main: [3]string{"доллар", "доллара", "долларов"}, fraction: [3]string{"цент", "цента", "центов"}},
+		"€": {main: [3]string{"евро", "евро", "евро"}, fraction: [3]string{"цент", "цента", "центов"}},
+		"£": {main: [3]string{"фунт", "фунта", "фунтов"}, fraction: [3]string{"пенс", "пенса", "пенсов"}},
+		"₽": {main: [3]string{"рубль", "рубля", "рублей"}, fraction: [3]string{"копейка", "копейки", "копеек"}},
+	},
+	units: map[string][3]string{
+		"км": {"километр", "километра", "километров"}, "м": {"метр", "метра", "метров"},
+		"см": {"сантиметр", "сантиметра", "сантиметров"}, "мм": {"миллиметр", "миллиметра", "миллиметров"},
+		"кг": {"килограмм", "килограмма", "килограммов"}, "г": {"грамм", "грамма", "граммов"},
+		"л": {"литр", "литра", "литров"}, "мл": {"миллилитр", "миллилитра", "миллилитров"},
+		"км/ч": {"километр в час", "километра в час", "километров в час"},
+		"°C":   {"градус Цельсия", "градуса Цельсия", "градусов Цельсия"},
+		"%":    {"процент", "процента", "процентов"},
+	},
+}
+
+func russianPlural(n int64) int {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	}
+	return 2
+}
+
+var russianSmall = []string{"ноль", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять", "десять",
+	"одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+var russianTens = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+var russianHundreds = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+var russianScales = [][3]string{{"", "", ""}, {"тысяча", "тысячи", "тысяч"}, {"миллион", "миллиона", "миллионов"},
+	{"миллиард", "миллиарда", "миллиардов"}, {"триллион", "триллиона", "триллионов"}}
+
+func russianCardinal(n int64) string {
+	if n < 0 {
+		return "минус " + russianCardinal(-n)
+	}
+	if n == 0 {
+		return russianSmall[0]
+	}
+	group := func(n int64, s int) string {
+		parts := make([]string, 0)
+		if n >= 100 {
+			parts = append(parts, russianHundreds[n/100])
+		}
+		n %= 100
+		if n >= 20 {
+			parts = append(parts, russianTens[n/10])
+			n %= 10
+		}
+		if n > 0 {
+			unit := russianSmall[n]
+			// Thousands are feminine: "одна тысяча", "две тысячи"
+			if s == 1 && n == 1 {
+				unit = "одна"
+			} else if s == 1 && n == 2 {
+				unit = "две"
+			}
+			parts = append(parts, unit)
+		}
+		return strings.Join(parts, " ")
+	}
+	scale := func(n int64, s int) string {
+		return group(n, s) + " " + russianScales[s][russianPlural(n)]
+	}
+	return scaleWords(n, group, scale, " ")
+}
+
+var russianOrdinals = map[string]string{
+	"один": "первый", "два": "второй", "три": "третий", "четыре": "четвёртый", "пять": "пятый",
+	"шесть": "шестой", "семь": "седьмой", "восемь": "восьмой", "девять": "девятый", "десять": "десятый",
+	"одиннадцать": "одиннадцатый", "двенадцать": "двенадцатый", "тринадцать": "тринадцатый",
+	"четырнадцать": "четырнадцатый", "пятнадцать": "пятнадцатый", "шестнадцать": "шестнадцатый",
+	"семнадцать": "семнадцатый", "восемнадцать": "восемнадцатый", "девятнадцать": "девятнадцатый",
+	"двадцать": "двадцатый", "тридцать": "тридцатый", "сорок": "сороковой", "пятьдесят": "пятидесятый",
+	"шестьдесят": "шестидесятый", "семьдесят": "семидесятый", "восемьдесят": "восьмидесятый", "девяносто": "девяностый",
+	"сто": "сотый", "тысяча": "тысячный",
+}
+
+func russianOrdinal(n int64) string {
+	words := russianCardinal(n)
+	cut := strings.LastIndex(words, " ") + 1
+	last, ok := russianOrdinals[words[cut:]]
+	if !ok {
+		return words
+	}
+	return words[:cut] + last
+}
+
+// russianNeuter turns a masculine ordinal into the neuter form used for dates, "третий" gives "третье".
+func russianNeuter(ordinal string) string {
+	switch {
+	case strings.HasSuffix(ordinal, "ий"):
+		return strings.TrimSuffix(ordinal, "ий") + "ье"
+	case strings.HasSuffix(ordinal, "ый"), strings.HasSuffix(ordinal, "ой"):
+		return ordinal[:len(ordinal)-len("ый")] + "ое"
+	}
+	return ordinal
+}
@@ -0,0 +1,121 @@
+package tts
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// QA validates every generated chunk so truncated, repeated or silent audio is regenerated.
+type QA struct {
+	Enabled          bool
+	WordsPerMinute   float64 // Expected narration rate at speed 1.0
+	MinDurationRatio float64 // Shortest acceptable actual/expected duration
+	MaxDurationRatio float64 // Longest acceptable actual/expected duration
+	SilenceLUFS      float64 // Chunks quieter than this count as silent
+	MaxWER           float64 // Highest accepted word error rate, 0 disables transcription
+	MaxRegenerations int
+	Transcribe       func(file string) (story.Transcript, error)
+}
+
+func DefaultQA() QA {
+	return QA{
+		WordsPerMinute:   150,
+		MinDurationRatio: 0.5,
+		MaxDurationRatio: 1.8,
+		SilenceLUFS:      -50,
+		MaxRegenerations: 2,
+	}
+}
+
+// Check returns the problems found in a chunk audio file, or nothing if it is fine.
+func (q QA) Check(file, text string, speed float64) []string {
+	problems, _ := q.check(file, text, speed)
+	return problems
+}
+
+// check also returns the transcript when the word error rate was checked.
+func (q QA) check(file, text string, speed float64) ([]string, *story.Transcript) {
+	problems := make([]string, 0)
+
+	pcm, err := audio.DecodeFile(file)
+	if err != nil {
+		return append(problems, fmt.Sprintf("audio cannot be decoded: %v", err)), nil
+	}
+
+	if loudness := audio.IntegratedLoudness(pcm); loudness < q.SilenceLUFS {
+		problems = append(problems, fmt.Sprintf("near silent (%.1f LUFS)", loudness))
+	}
+
+	if speed <= 0 {
+		speed = 1
+	}
+	words := len(strings.Fields(text))
+	expected := time.Duration(float64(words) / (q.WordsPerMinute * speed) * float64(time.Minute))
+	actual := pcm.Duration()
+	// Short chunks such as titles are dominated by leading and trailing silence, so allow some slack.
+	const slack = 1500 * time.Millisecond
+	if actual < time.Duration(float64(expected)*q.MinDurationRatio)-slack {
+		problems = append(problems, fmt.Sprintf("too short, probably truncated (%s, expected ~%s)", actual.Round(time.Millisecond), expected.Round(time.Millisecond)))
+	}
+	if actual > time.Duration(float64(expected)*q.MaxDurationRatio)+slack {
+		problems = append(problems, fmt.Sprintf("too long, probably repeated or padded with silence (%s, expected ~%s)", actual.Round(time.Millisecond), expected.Round(time.Millisecond)))
+	}
+
+	if q.MaxWER > 0 && q.Transcribe != nil {
+		transcript, err := q.Transcribe(file)
+		if err != nil {
+			log.Printf("Warning: transcription failed, skipping word error rate check: %v\n", err)
+			return problems, nil
+		}
+		if wer := WordErrorRate(text, transcript.Text); wer > q.MaxWER {
+			problems = append(problems, fmt.Sprintf("word error rate %.0f%% exceeds %.0f%%", wer*100, q.MaxWER*100))
+		}
+		return problems, &transcript
+	}
+
+	return problems, nil
+}
+
+// WordErrorRate is the word level edit distance between reference and hypothesis,
+// divided by the reference length. Case and punctuation are ignored.
+func WordErrorRate(reference, hypothesis string) float64 {
+	ref := normalizeWords(reference)
+	hyp := normalizeWords(hypothesis)
+	if len(ref) == 0 {
+		if len(hyp) == 0 {
+			return 0
+		}
+		return 1
+	}
+
+	prev := make([]int, len(hyp)+1)
+	curr := make([]int, len(hyp)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		curr[0] = i
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return float64(prev[len(hyp)]) / float64(len(ref))
+}
+
+func normalizeWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
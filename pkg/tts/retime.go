@@ -0,0 +1,37 @@
+package tts
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+)
+
+// Retime writes input played speed times faster into output without calling TTS again.
+// The pitch stays the same, the output format follows the output file extension.
+func Retime(input, output string, speed float64) (time.Duration, error) {
+	if speed <= 0 {
+		return 0, fmt.Errorf("speed must be positive, got %v", speed)
+	}
+	format, err := audio.ParseFormat(filepath.Ext(output))
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Decoding %s\n", input)
+	pcm, err := audio.DecodeFile(input)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Stretching %s to %.2fx speed\n", pcm.Duration().Round(time.Second), speed)
+	stretched := audio.TimeStretch(pcm, speed)
+
+	log.Printf("Encoding %s\n", output)
+	if err := audio.EncodeFile(output, stretched, format); err != nil {
+		return 0, err
+	}
+	return stretched.Duration(), nil
+}
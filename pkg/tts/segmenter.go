@@ -0,0 +1,406 @@
+package tts
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Segmenter splits text into sentences and packs them into chunks for TTS.
+// It knows the abbreviations and quotation marks of one language, so "Mr. Smith" or
+// „Halt!“, rief er. are not cut in the middle.
+type Segmenter struct {
+	abbreviations map[string]bool // Lowercase, without the final full stop
+	numbers       map[string]bool // Abbreviations only when a number follows, "No. 5"
+	streets       map[string]bool // Abbreviations ending a sentence after a street name, "Baker St. Then"
+	pairs         map[rune]rune   // Opening quote or bracket to its closing mark
+}
+
+// segmenterAbbreviations lists words followed by a full stop that do not end a sentence.
+// Abbreviations that usually end a sentence when a capitalized word follows, like "a.m." or "etc.",
+// are left out, as are words like "no" that are ordinary words too.
+var segmenterAbbreviations = map[string][]string{
+	"en": {"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "capt", "gen", "lt", "col", "sgt", "rev",
+		"vs", "e.g", "i.e", "approx", "dept", "fig", "vol", "ave", "rd"},
+	"de": {"dr", "prof", "hr", "fr", "nr", "str", "bzw", "usw", "z.b", "d.h", "ca", "vgl", "evtl", "ggf", "u.a", "s.o", "sog", "inkl", "st"},
+	"lv": {"utt", "piem", "t.i", "u.c", "plkst", "prof", "dr", "sk", "resp", "nr", "t.sk", "u.t.t"},
+	"ru": {"т.е", "т.д", "т.п", "др", "г", "гг", "ул", "проф", "им", "см", "стр", "рис", "т.к", "тыс", "млн"},
+	"uk": {"т.д", "т.п", "ім", "вул", "проф", "див", "напр", "тис", "млн"},
+	"fr": {"m", "mme", "mlle", "dr", "st", "ste", "etc", "p.ex", "cf", "env"},
+	"es": {"sr", "sra", "srta", "dr", "dra", "etc", "ud", "uds", "p.ej"},
+	"it": {"sig", "sig.ra", "dott", "prof", "ecc"},
+	"pt": {"sr", "sra", "dr", "dra", "etc", "p.ex"},
+	"nl": {"dhr", "mevr", "dr", "prof", "bijv", "enz", "o.a", "d.w.z"},
+	"pl": {"p", "dr", "prof", "np", "itd", "itp", "tzn", "ul"},
+	"lt": {"p", "dr", "prof", "pvz", "t.y", "kt"},
+	"et": {"hr", "pr", "dr", "prof", "nt", "jne", "s.t"},
+	"el": {"κ", "κα", "δρ", "π.χ", "κλπ"},
+}
+
+// segmenterNumbers lists words that are abbreviations before a number and ordinary words otherwise:
+// "No. 5" but "Oh no. The dragon came.", Polish "ok. 5" (about), Lithuanian "g. 5" (street).
+var segmenterNumbers = map[string][]string{
+	"en": {"no"},
+	"pl": {"ok"},
+	"lt": {"g"},
+}
+
+// segmenterStreets lists abbreviations that follow a street name, there they may end a sentence.
+var segmenterStreets = map[string][]string{
+	"en": {"st", "ave", "rd"},
+}
+
+// segmenterPairs lists quotation marks per language. Brackets and straight quotes are added for all.
+// Single quotes are left out, they are mostly apostrophes.
+var segmenterPairs = map[string]map[rune]rune{
+	"en": {'“': '”'},
+	"de": {'„': '“', '»': '«'},
+	"lv": {'«': '»', '„': '”', '“': '”'},
+	"ru": {'«': '»', '„': '“'},
+	"uk": {'«': '»', '„': '“'},
+	"fr": {'«': '»', '“': '”'},
+	"es": {'«': '»', '“': '”'},
+	"it": {'«': '»', '“': '”'},
+	"pt": {'«': '»', '“': '”'},
+	"nl": {'„': '”', '“': '”'},
+	"pl": {'„': '”', '«': '»'},
+	"lt": {'„': '“'},
+	"et": {'„': '“'},
+	"el": {'«': '»', '“': '”'},
+	"ja": {'「': '」', '『': '』', '“': '”'},
+	"zh": {'「': '」', '『': '』', '“': '”', '《': '》'},
+}
+
+// NewSegmenter prepares sentence rules for the language. Unknown languages use the English abbreviations.
+func NewSegmenter(language utils.Language) *Segmenter {
+	s := &Segmenter{
+		abbreviations: make(map[string]bool),
+		numbers:       make(map[string]bool),
+		streets:       make(map[string]bool),
+		pairs:         map[rune]rune{'(': ')', '[': ']', '（': '）', '"': '"'},
+	}
+
+	iso := language.ISO1
+	if _, ok := segmenterAbbreviations[iso]; !ok {
+		iso = "en"
+	}
+	for _, a := range segmenterAbbreviations[iso] {
+		s.abbreviations[a] = true
+	}
+	for _, a := range segmenterNumbers[iso] {
+		s.numbers[a] = true
+	}
+	for _, a := range segmenterStreets[iso] {
+		s.streets[a] = true
+	}
+
+	pairs, ok := segmenterPairs[language.ISO1]
+	if !ok {
+		pairs = segmenterPairs["en"]
+	}
+	for open, closing := range pairs {
+		s.pairs[open] = closing
+	}
+
+	return s
+}
+
+// span is a [start, end) range of runes.
+type span struct {
+	start, end int
+}
+
+func (sp span) len() int {
+	return sp.end - sp.start
+}
+
+// isSentenceEnd reports whether r ends a sentence in any of the supported scripts.
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '‼', '⁇', '⁈', '⁉', '。', '！', '？', '｡':
+		return true
+	}
+	return false
+}
+
+// isFullWidthEnd reports whether r ends a sentence without needing whitespace after it.
+func isFullWidthEnd(r rune) bool {
+	return r == '。' || r == '！' || r == '？' || r == '｡'
+}
+
+func isClauseEnd(r rune) bool {
+	switch r {
+	case ',', ';', ':', '—', '–', '、', '，', '；', '：':
+		return true
+	}
+	return false
+}
+
+// boundaries returns the positions after every sentence end in runes[sp.start:sp.end].
+// Sentence ends inside quotes or brackets are returned as weak, they are only used to
+// break sentences longer than a chunk.
+func (s *Segmenter) boundaries(runes []rune, sp span) (strong, weak []int) {
+	closers := make([]rune, 0) // Stack of expected closing marks
+
+	for i := sp.start; i < sp.end; i++ {
+		r := runes[i]
+		if len(closers) > 0 && r == closers[len(closers)-1] {
+			closers = closers[:len(closers)-1]
+			continue
+		}
+		if closing, ok := s.pairs[r]; ok && (r != '"' || !s.closesStraightQuote(runes, i, sp)) {
+			closers = append(closers, closing)
+			continue
+		}
+		if !isSentenceEnd(r) {
+			continue
+		}
+
+		// Take the whole run of terminators, "?!" or "...", and the closing marks after it
+		end := i + 1
+		for end < sp.end && isSentenceEnd(runes[end]) {
+			end++
+		}
+		terminators := end
+		depth := len(closers)
+		for end < sp.end && depth > 0 && runes[end] == closers[depth-1] {
+			depth--
+			end++
+		}
+		if !s.endsSentence(runes, i, terminators, end, sp) {
+			i = end - 1
+			closers = closers[:depth]
+			continue
+		}
+
+		if depth == 0 {
+			strong = append(strong, end)
+		} else {
+			weak = append(weak, end)
+		}
+		closers = closers[:depth]
+		i = end - 1
+	}
+
+	return strong, weak
+}
+
+// closesStraightQuote reports whether a straight double quote at i closes an open one.
+// Straight quotes open after whitespace or an opening bracket and close otherwise.
+func (s *Segmenter) closesStraightQuote(runes []rune, i int, sp span) bool {
+	if i == sp.start {
+		return false
+	}
+	prev := runes[i-1]
+	_, opening := s.pairs[prev]
+	return !unicode.IsSpace(prev) && !opening
+}
+
+// endsSentence decides whether the terminators runes[i:terminators] and the closing marks up to end
+// end a sentence.
+func (s *Segmenter) endsSentence(runes []rune, i, terminators, end int, sp span) bool {
+	if end >= sp.end {
+		return true
+	}
+	if isFullWidthEnd(runes[terminators-1]) {
+		// 「はい。」と言った。 goes on after the closing quote
+		return end == terminators || !unicode.IsLetter(runes[end])
+	}
+	if !unicode.IsSpace(runes[end]) {
+		// "3.14", "e.g.", "Hello!Max" are not boundaries
+		return false
+	}
+
+	next := end
+	for next < sp.end && unicode.IsSpace(runes[next]) {
+		if runes[next] == '\n' {
+			return true
+		}
+		next++
+	}
+	if next < sp.end && unicode.IsLower(runes[next]) {
+		// "Wait... what?" and “Run!” she shouted. continue the sentence
+		return false
+	}
+
+	if runes[i] == '.' && end == i+1 {
+		word := s.wordBefore(runes, i, sp)
+		lower := strings.ToLower(word)
+		if s.numbers[lower] {
+			return next >= sp.end || !unicode.IsDigit(runes[next])
+		}
+		if s.streets[lower] && streetName(string(runes[sp.start:i-len([]rune(word))])) {
+			return true
+		}
+		if s.abbreviations[lower] {
+			return false
+		}
+		// Initials like "J. R. R. Tolkien"
+		if w := []rune(word); len(w) == 1 && unicode.IsUpper(w[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// wordBefore returns the letters and inner full stops right before position i, "e.g" for "e.g.".
+func (s *Segmenter) wordBefore(runes []rune, i int, sp span) string {
+	start := i
+	for start > sp.start && (unicode.IsLetter(runes[start-1]) || runes[start-1] == '.' || runes[start-1] == '-') {
+		start--
+	}
+	return strings.Trim(string(runes[start:i]), ".")
+}
+
+// Sentences splits text into sentences.
+func (s *Segmenter) Sentences(text string) []string {
+	runes := []rune(text)
+	sentences := make([]string, 0)
+	for _, p := range paragraphs(runes) {
+		for _, sp := range s.sentenceSpans(runes, p) {
+			sentences = append(sentences, string(runes[sp.start:sp.end]))
+		}
+	}
+	return sentences
+}
+
+func (s *Segmenter) sentenceSpans(runes []rune, p span) []span {
+	strong, _ := s.boundaries(runes, p)
+	return splitAt(runes, p, strong)
+}
+
+// Chunks packs text into chunks of at most size runes. Whole paragraphs are preferred,
+// then whole sentences. Longer sentences are split at sentence ends inside quotes,
+// then at clause punctuation, then between words.
+func (s *Segmenter) Chunks(text string, size int) []string {
+	if size <= 0 {
+		return nil
+	}
+	runes := []rune(text)
+	chunks := make([]string, 0)
+
+	current := span{-1, -1}
+	add := func(sp span) {
+		switch {
+		case current.start < 0:
+			current = sp
+		case sp.end-current.start <= size:
+			current.end = sp.end
+		default:
+			chunks = append(chunks, strings.TrimSpace(string(runes[current.start:current.end])))
+			current = sp
+		}
+	}
+
+	for _, p := range paragraphs(runes) {
+		if p.len() <= size {
+			// A paragraph that does not fit the current chunk starts the next one
+			add(p)
+			continue
+		}
+
+		_, weak := s.boundaries(runes, p)
+		for _, sentence := range s.sentenceSpans(runes, p) {
+			if sentence.len() <= size {
+				add(sentence)
+				continue
+			}
+			for _, piece := range splitLong(runes, sentence, weak, size) {
+				add(piece)
+			}
+		}
+	}
+	if current.start >= 0 {
+		chunks = append(chunks, strings.TrimSpace(string(runes[current.start:current.end])))
+	}
+
+	return chunks
+}
+
+// paragraphs returns the non-empty lines of the text, trimmed.
+func paragraphs(runes []rune) []span {
+	spans := make([]span, 0)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && runes[i] != '\n' {
+			continue
+		}
+		if sp := trimSpan(runes, span{start, i}); sp.len() > 0 {
+			spans = append(spans, sp)
+		}
+		start = i + 1
+	}
+	return spans
+}
+
+// splitAt cuts sp at the given positions, dropping surrounding whitespace.
+func splitAt(runes []rune, sp span, positions []int) []span {
+	spans := make([]span, 0, len(positions)+1)
+	start := sp.start
+	for _, pos := range append(positions, sp.end) {
+		if part := trimSpan(runes, span{start, pos}); part.len() > 0 {
+			spans = append(spans, part)
+		}
+		start = pos
+	}
+	return spans
+}
+
+func trimSpan(runes []rune, sp span) span {
+	for sp.start < sp.end && unicode.IsSpace(runes[sp.start]) {
+		sp.start++
+	}
+	for sp.end > sp.start && unicode.IsSpace(runes[sp.end-1]) {
+		sp.end--
+	}
+	return sp
+}
+
+// splitLong breaks a sentence longer than size into pieces.
+func splitLong(runes []rune, sentence span, weak []int, size int) []span {
+	pieces := make([]span, 0)
+	start := sentence.start
+	for sentence.end-start > size {
+		limit := start + size
+		cut := lastPosition(start, limit, func(pos int) bool {
+			for _, w := range weak {
+				if w == pos {
+					return true
+				}
+			}
+			return false
+		})
+		if cut < 0 {
+			cut = lastPosition(start, limit, func(pos int) bool {
+				return isClauseEnd(runes[pos-1]) && pos < len(runes) && unicode.IsSpace(runes[pos])
+			})
+		}
+		if cut < 0 {
+			cut = lastPosition(start, limit, func(pos int) bool {
+				return unicode.IsSpace(runes[pos-1])
+			})
+		}
+		if cut < 0 {
+			cut = limit
+		}
+		if piece := trimSpan(runes, span{start, cut}); piece.len() > 0 {
+			pieces = append(pieces, piece)
+		}
+		start = cut
+	}
+	if piece := trimSpan(runes, span{start, sentence.end}); piece.len() > 0 {
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+// lastPosition returns the last position in (start, limit] matching ok, or -1.
+func lastPosition(start, limit int, ok func(pos int) bool) int {
+	for pos := limit; pos > start; pos-- {
+		if ok(pos) {
+			return pos
+		}
+	}
+	return -1
+}
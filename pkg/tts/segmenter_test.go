@@ -0,0 +1,218 @@
+package tts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+func TestSegmenterSentences(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		text     string
+		want     []string
+	}{
+		{
+			name:     "english abbreviations",
+			language: "english",
+			text:     "Mr. Smith met Dr. Brown at 3.15 today. They talked about cats, dogs, etc. and left.",
+			want:     []string{"Mr. Smith met Dr. Brown at 3.15 today.", "They talked about cats, dogs, etc. and left."},
+		},
+		{
+			name:     "english initials",
+			language: "english",
+			text:     "J. R. R. Tolkien wrote books. Max read them.",
+			want:     []string{"J. R. R. Tolkien wrote books.", "Max read them."},
+		},
+		{
+			name:     "english ellipsis",
+			language: "english",
+			text:     "Wait... what was that? Nothing... It was nothing.",
+			want:     []string{"Wait... what was that?", "Nothing...", "It was nothing."},
+		},
+		{
+			name:     "english quotes stay with the speaker",
+			language: "english",
+			text:     `Max shouted "Run! Now!" and everybody ran. “Stop!” she cried. Then it was quiet.`,
+			want:     []string{`Max shouted "Run! Now!" and everybody ran.`, "“Stop!” she cried.", "Then it was quiet."},
+		},
+		{
+			name:     "english quote ending a sentence",
+			language: "english",
+			text:     `Max said "I am hungry." Mia laughed.`,
+			want:     []string{`Max said "I am hungry."`, "Mia laughed."},
+		},
+		{
+			name:     "paragraphs",
+			language: "english",
+			text:     "First paragraph without a full stop\nSecond paragraph.",
+			want:     []string{"First paragraph without a full stop", "Second paragraph."},
+		},
+		{
+			name:     "german quotes and abbreviations",
+			language: "german",
+			text:     "Max rief „Halt! Bleib stehen!“, dann lief er weg. Dr. Müller kam z.B. später. Er sagte »Gut.« Alle gingen.",
+			want: []string{
+				"Max rief „Halt! Bleib stehen!“, dann lief er weg.",
+				"Dr. Müller kam z.B. später.",
+				"Er sagte »Gut.«",
+				"Alle gingen.",
+			},
+		},
+		{
+			name:     "russian guillemets",
+			language: "russian",
+			text:     "Макс крикнул «Стой! Кто идёт?» и замер. Было темно, т.е. ничего не видно. Утро пришло.",
+			want:     []string{"Макс крикнул «Стой! Кто идёт?» и замер.", "Было темно, т.е. ничего не видно.", "Утро пришло."},
+		},
+		{
+			name:     "french guillemets",
+			language: "french",
+			text:     "Il dit « Bonjour ! Ça va ? » et sourit. M. Dupont arriva.",
+			want:     []string{"Il dit « Bonjour ! Ça va ? » et sourit.", "M. Dupont arriva."},
+		},
+		{
+			name:     "latvian abbreviations",
+			language: "latvian",
+			text:     "Plkst. desmitos sākās skola, piem. matemātika. Māris nāca vēlāk.",
+			want:     []string{"Plkst. desmitos sākās skola, piem. matemātika.", "Māris nāca vēlāk."},
+		},
+		{
+			name:     "english no ending a sentence",
+			language: "english",
+			text:     "Oh no. The dragon came. She said no. Then she left.",
+			want:     []string{"Oh no.", "The dragon came.", "She said no.", "Then she left."},
+		},
+		{
+			name:     "english no before a number",
+			language: "english",
+			text:     "He lived at No. 5 and took bus No. 12. It was late.",
+			want:     []string{"He lived at No. 5 and took bus No. 12.", "It was late."},
+		},
+		{
+			name:     "english a.m. and p.m.",
+			language: "english",
+			text:     "We left at 9 a.m. The sun rose. At 5 p.m. we were home. Dinner was at 7 p.m.",
+			want:     []string{"We left at 9 a.m.", "The sun rose.", "At 5 p.m. we were home.", "Dinner was at 7 p.m."},
+		},
+		{
+			name:     "english saint and street",
+			language: "english",
+			text:     "They prayed to St. Nicholas at St. Mary's. Then they walked down Baker St. It was cold.",
+			want:     []string{"They prayed to St. Nicholas at St. Mary's.", "Then they walked down Baker St.", "It was cold."},
+		},
+		{
+			name:     "ordinary words of other languages",
+			language: "italian",
+			text:     "Il gatto dorme sempre, es. Poi mangia.",
+			want:     []string{"Il gatto dorme sempre, es.", "Poi mangia."},
+		},
+		{
+			name:     "polish ok before a number",
+			language: "polish",
+			text:     "Było ok. 5 kotów. Wszystko było ok. Potem poszli.",
+			want:     []string{"Było ok. 5 kotów.", "Wszystko było ok.", "Potem poszli."},
+		},
+		{
+			name:     "latvian g is a word",
+			language: "latvian",
+			text:     "Tas bija 2024. g. Tad nāca ziema.",
+			want:     []string{"Tas bija 2024. g.", "Tad nāca ziema."},
+		},
+		{
+			name:     "japanese full width",
+			language: "japanese",
+			text:     "今日は晴れです。「行こう！」と言った。みんな笑った！",
+			want:     []string{"今日は晴れです。", "「行こう！」と言った。", "みんな笑った！"},
+		},
+		{
+			name:     "chinese full width",
+			language: "chinese",
+			text:     "他来了。你好吗？我很好！",
+			want:     []string{"他来了。", "你好吗？", "我很好！"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSegmenter(utils.FindLanguage(tt.language))
+			got := s.Sentences(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sentences()\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmenterChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		text     string
+		size     int
+		want     []string
+	}{
+		{
+			name:     "fits in one chunk",
+			language: "english",
+			text:     "One. Two.\nThree.",
+			size:     100,
+			want:     []string{"One. Two.\nThree."},
+		},
+		{
+			name:     "paragraph is not split when it fits a chunk of its own",
+			language: "english",
+			text:     "Short first paragraph.\nSecond paragraph. It has two sentences.",
+			size:     45,
+			want:     []string{"Short first paragraph.", "Second paragraph. It has two sentences."},
+		},
+		{
+			name:     "long paragraph is packed by sentences",
+			language: "english",
+			text:     "Mr. Smith went home. He was tired. Mrs. Smith was not.",
+			size:     36,
+			want:     []string{"Mr. Smith went home. He was tired.", "Mrs. Smith was not."},
+		},
+		{
+			name:     "long sentence breaks inside the quote first",
+			language: "english",
+			text:     `Max shouted "Run to the hills! Hide in the cave!" and ran.`,
+			size:     40,
+			want:     []string{`Max shouted "Run to the hills!`, `Hide in the cave!" and ran.`},
+		},
+		{
+			name:     "long sentence breaks at a comma",
+			language: "english",
+			text:     "The dragon flew over the mountains, over the sea and over the town.",
+			size:     40,
+			want:     []string{"The dragon flew over the mountains,", "over the sea and over the town."},
+		},
+		{
+			name:     "long word is cut",
+			language: "english",
+			text:     strings.Repeat("a", 25),
+			size:     10,
+			want:     []string{strings.Repeat("a", 10), strings.Repeat("a", 10), strings.Repeat("a", 5)},
+		},
+		{
+			name:     "russian chunks",
+			language: "russian",
+			text:     "Макс крикнул «Стой!» и замер. Было темно.",
+			size:     30,
+			want:     []string{"Макс крикнул «Стой!» и замер.", "Было темно."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSegmenter(utils.FindLanguage(tt.language))
+			got := s.Chunks(tt.text, tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chunks()\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
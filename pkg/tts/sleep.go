@@ -0,0 +1,93 @@
+package tts
+
+import (
+	"math"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// Sleep is a bedtime narration mode. Speech gets slower and calmer across the last chapters
+// and "The End." is followed by a fade-out of a quiet ambient tail or the music bed, when there is one.
+type Sleep struct {
+	Enabled    bool
+	Chapters   int           // Number of final chapters the ramp-down spreads across
+	MinSpeed   float64       // Speed factor reached at the end of the story, e.g. 0.8 of the configured speed
+	FadeOut    time.Duration // Fade-out of the tail or bed after "The End.", without either the story just ends
+	Tail       string        // Ambient sound looped after "The End.", empty for silence
+	TailLength time.Duration
+	TailVolume float64 // Ambient tail level in dB
+}
+
+func DefaultSleep() Sleep {
+	return Sleep{
+		Chapters:   2,
+		MinSpeed:   0.8,
+		FadeOut:    10 * time.Second,
+		TailLength: time.Minute,
+		TailVolume: -28,
+	}
+}
+
+// progress returns how far into the ramp-down chunk k of K in chapter n of total is, from 0 to 1.
+func (s Sleep) progress(n, total, k, chunks int) float64 {
+	if !s.Enabled || s.Chapters <= 0 || chunks == 0 {
+		return 0
+	}
+	chapters := min(s.Chapters, total)
+	start := total - chapters
+	if n < start {
+		return 0
+	}
+	return math.Min(1, (float64(n-start)+float64(k+1)/float64(chunks))/float64(chapters))
+}
+
+// apply slows voice down and adds bedtime instructions for the given ramp-down progress.
+func (s Sleep) apply(voice story.Voice, progress float64) story.Voice {
+	if progress <= 0 {
+		return voice
+	}
+	voice.Provider.Speed *= 1 - (1-s.MinSpeed)*progress
+	switch {
+	case progress < 0.34:
+		voice.Instruction.Bedtime = "Start winding down. A little calmer, softer and slower than before."
+	case progress < 0.67:
+		voice.Instruction.Bedtime = "Calm, soft and slow. Low energy, gentle voice, longer pauses between sentences."
+	default:
+		voice.Instruction.Bedtime = "Very soft and sleepy, slow, almost whispering. Long gentle pauses. The listener is falling asleep."
+	}
+	return voice
+}
+
+// tailLength is how much audio is added after "The End.". Without an ambient tail or a music bed
+// to fade out nothing is added, silence would only delay the end.
+func (s Sleep) tailLength(bed bool) time.Duration {
+	switch {
+	case !s.Enabled:
+		return 0
+	case s.Tail != "":
+		return max(s.TailLength, s.FadeOut)
+	case bed:
+		return s.FadeOut
+	}
+	return 0
+}
+
+// addTail mixes the ambient tail into the audio added after the narration ending at frame end
+// and fades out over it. The narration itself is never faded, without a tail or bed to fade
+// nothing was added and nothing is done. The tail sound must already be converted to the narration format.
+func (s Sleep) addTail(joined *audio.PCM, tail *audio.PCM, end int) error {
+	added := joined.Frames() - end
+	if added <= 0 {
+		return nil
+	}
+	if tail != nil {
+		err := audio.MixBed(joined, tail, end, joined.Frames(), audio.Ducking{Volume: s.TailVolume})
+		if err != nil {
+			return err
+		}
+	}
+	audio.FadeOut(joined, min(s.FadeOut, audio.FramesToDuration(added, joined.SampleRate)))
+	return nil
+}
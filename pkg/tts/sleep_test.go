@@ -0,0 +1,97 @@
+package tts
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+)
+
+const sleepTestRate = 8000
+
+func sleepTone(d time.Duration, level float64) *audio.PCM {
+	p := audio.New(sleepTestRate, 1)
+	for i := 0; i < audio.DurationToFrames(d, sleepTestRate); i++ {
+		p.Samples = append(p.Samples, level*math.Sin(2*math.Pi*220*float64(i)/sleepTestRate))
+	}
+	return p
+}
+
+func peak(samples []float64) float64 {
+	m := 0.0
+	for _, s := range samples {
+		m = math.Max(m, math.Abs(s))
+	}
+	return m
+}
+
+func TestSleepTailLeavesTheLastSentenceAlone(t *testing.T) {
+	s := DefaultSleep()
+	s.Enabled = true
+
+	// The last sentence, "The End.", ends the narration
+	joined := sleepTone(12*time.Second, 0.5)
+	narration := slices.Clone(joined.Samples)
+	end := joined.Frames()
+
+	joined.AppendSilence(s.tailLength(false))
+	if err := s.addTail(joined, nil, end); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(joined.Samples, narration) {
+		t.Errorf("without tail or bed the narration changed, %d samples, want the %d samples untouched", len(joined.Samples), len(narration))
+	}
+}
+
+func TestSleepTailFadesOnlyWhatFollowsTheEnd(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		bed  bool
+		tail bool
+	}{
+		{name: "ambient tail", tail: true},
+		{name: "music bed", bed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultSleep()
+			s.Enabled = true
+			s.TailLength = 20 * time.Second
+			var tail *audio.PCM
+			if tt.tail {
+				s.Tail = "rain.mp3"
+				tail = sleepTone(3*time.Second, 0.5)
+			}
+
+			joined := sleepTone(12*time.Second, 0.5)
+			narration := slices.Clone(joined.Samples)
+			end := joined.Frames()
+			joined.AppendSilence(s.tailLength(tt.bed))
+			if tt.bed {
+				// The bed keeps playing after the narration
+				for i := end; i < joined.Frames(); i++ {
+					joined.Samples[i] = 0.1
+				}
+			}
+			if err := s.addTail(joined, tail, end); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(joined.Samples[:end], narration) {
+				t.Error("the narration was changed by the fade-out")
+			}
+			added := joined.Samples[end:]
+			if len(added) == 0 {
+				t.Fatal("nothing was added after the narration")
+			}
+			second := audio.DurationToFrames(time.Second, sleepTestRate)
+			if peak(added[:second]) < 0.01 {
+				t.Errorf("the tail starts silent, peak %.4f", peak(added[:second]))
+			}
+			if last := peak(added[len(added)-second/10:]); last > 0.01 {
+				t.Errorf("the tail does not fade out, last peak %.4f", last)
+			}
+		})
+	}
+}
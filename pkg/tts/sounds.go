@@ -0,0 +1,95 @@
+package tts
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
+)
+
+// Sounds are optional assets from a local sound library mixed into the narration.
+type Sounds struct {
+	Intro      string // Played before the story
+	Outro      string // Played after the story
+	Transition string // Played between chapters
+	Bed        string // Quiet ambient music looped under the narration
+	Ducking    audio.Ducking
+}
+
+// ResolveSound returns the path of a sound library asset.
+// Empty names stay empty, absolute paths are kept as they are.
+func ResolveSound(dir, name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// NewSounds resolves asset names relative to the sound library directory.
+func NewSounds(dir, intro, outro, transition, bed string) Sounds {
+	return Sounds{
+		Intro:      ResolveSound(dir, intro),
+		Outro:      ResolveSound(dir, outro),
+		Transition: ResolveSound(dir, transition),
+		Bed:        ResolveSound(dir, bed),
+		Ducking: audio.Ducking{
+			Volume:  -24,
+			Depth:   12,
+			Attack:  80 * time.Millisecond,
+			Release: 600 * time.Millisecond,
+		},
+	}
+}
+
+func (s Sounds) any() bool {
+	return s.Intro != "" || s.Outro != "" || s.Transition != "" || s.Bed != ""
+}
+
+// loadedSounds holds the decoded assets converted to the narration format.
+type loadedSounds struct {
+	intro, outro, transition, bed *audio.PCM
+	tail                          *audio.PCM // Sleep mode ambient tail
+}
+
+func (s Sounds) load(sampleRate, channels int, opts Options) (loadedSounds, error) {
+	var loaded loadedSounds
+	var err error
+
+	// Jingles are normalized like speech so they sit at the same level, the bed keeps its own level
+	// and is only scaled by the ducking volume.
+	if loaded.intro, err = loadSound(s.Intro, sampleRate, channels, opts); err != nil {
+		return loaded, err
+	}
+	if loaded.outro, err = loadSound(s.Outro, sampleRate, channels, opts); err != nil {
+		return loaded, err
+	}
+	if loaded.transition, err = loadSound(s.Transition, sampleRate, channels, opts); err != nil {
+		return loaded, err
+	}
+	opts.Normalize = false
+	if loaded.bed, err = loadSound(s.Bed, sampleRate, channels, opts); err != nil {
+		return loaded, err
+	}
+
+	return loaded, nil
+}
+
+func loadSound(file string, sampleRate, channels int, opts Options) (*audio.PCM, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	log.Printf("Loading sound %s\n", file)
+	pcm, err := audio.DecodeFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sound %q: %w", file, err)
+	}
+	pcm = audio.Convert(pcm, sampleRate, channels)
+	if opts.Normalize {
+		audio.Normalize(pcm, opts.LoudnessTarget, opts.TruePeakLimit)
+	}
+
+	return pcm, nil
+}
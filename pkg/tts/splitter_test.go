@@ -0,0 +1,45 @@
+package tts
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+func TestSplitByChapters(t *testing.T) {
+	s := story.Story{Title: "Fox", Chapters: []story.Chapter{
+		{Number: 1, Text: "One."},
+		{Number: 2, Text: "Two."},
+		{Number: 3, Text: "Three."},
+	}}
+
+	tests := []struct {
+		name  string
+		text  string
+		label string
+		want  int
+	}{
+		{name: "english narration", text: s.BuildNarration(story.TextChapter, story.TextTheEnd), want: 3},
+		{name: "translated label", text: s.BuildNarration("Nodaļa", "Beigas."), label: "Nodaļa", want: 3},
+		{name: "label with regexp characters", text: s.BuildNarration("Ch.", "End."), label: "Ch.", want: 3},
+		{name: "other label is one chapter", text: s.BuildNarration("Nodaļa", "Beigas."), want: 1},
+		{name: "ellipsis is not any three characters", text: "Chapter 1.\nOne.\nabc\nChapter 2x.", want: 1},
+		{name: "empty", text: " \n", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitByChapters(tt.text, tt.label); len(got) != tt.want {
+				t.Errorf("splitByChapters() = %d chapters %q, want %d", len(got), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitByChaptersKeepsHeadersWithTheirChapter(t *testing.T) {
+	text := "Fox\n...\nКапитул 1.\nOne.\n...\nКапитул 2.\nTwo.\n...\nКапитул 10.\nTen."
+	want := []string{"Fox\n...\nКапитул 1.\nOne.", "...\nКапитул 2.\nTwo.", "...\nКапитул 10.\nTen."}
+	if got := splitByChapters(text, "Капитул"); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitByChapters() = %q, want %q", got, want)
+	}
+}
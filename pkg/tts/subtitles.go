@@ -0,0 +1,304 @@
+package tts
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// Subtitle layout, the usual limits for readable captions.
+const (
+	subtitleLineLength = 42
+	subtitleLines      = 2
+	subtitleMinLength  = 0.8 // Seconds a cue stays on screen at least
+)
+
+// BuildTimings places every sentence and word of the narration in time. Transcribed words keep
+// their timestamps, the other words are spread over the rest of the chunk by their length.
+// Narrations without chunk positions give empty timings.
+func BuildTimings(n *story.Narration, segmenter *Segmenter) story.Timings {
+	timings := story.Timings{Audio: n.File, Duration: n.Duration, Chunks: make([]story.ChunkTiming, 0, len(n.Chunks))}
+	if !n.HasTimings() {
+		return timings
+	}
+
+	for _, c := range n.Chunks {
+		if c.End <= c.Start {
+			continue
+		}
+		chunk := story.ChunkTiming{Chapter: c.Chapter, Index: c.Index, Speaker: c.Speaker, Start: c.Start, End: c.End}
+
+		sentences := segmenter.Sentences(c.Text)
+		words := make([]story.WordTiming, 0)
+		ends := make([]int, len(sentences)) // Index after the last word of every sentence
+		for i, sentence := range sentences {
+			for _, w := range strings.Fields(sentence) {
+				words = append(words, story.WordTiming{Word: w, Estimated: true})
+			}
+			ends[i] = len(words)
+		}
+		placeWords(words, c.Words, c.Start, c.End)
+
+		first := 0
+		for i, sentence := range sentences {
+			if ends[i] == first {
+				continue
+			}
+			sentenceWords := words[first:ends[i]]
+			chunk.Sentences = append(chunk.Sentences, story.SentenceTiming{
+				Text:  strings.Join(strings.Fields(sentence), " "),
+				Start: sentenceWords[0].Start,
+				End:   sentenceWords[len(sentenceWords)-1].End,
+				Words: sentenceWords,
+			})
+			first = ends[i]
+		}
+		timings.Chunks = append(timings.Chunks, chunk)
+	}
+
+	return timings
+}
+
+// AlignSentences places sentences of a differently written text, like the story text a narration
+// was built from, in time by matching their words to timed words.
+func AlignSentences(sentences []string, timed []story.WordTiming) []story.SentenceTiming {
+	aligned := make([]story.SentenceTiming, 0, len(sentences))
+	if len(timed) == 0 {
+		return aligned
+	}
+
+	words := make([]story.WordTiming, 0)
+	ends := make([]int, len(sentences))
+	for i, sentence := range sentences {
+		for _, w := range strings.Fields(sentence) {
+			words = append(words, story.WordTiming{Word: w, Estimated: true})
+		}
+		ends[i] = len(words)
+	}
+	heard := make([]story.TranscriptWord, len(timed))
+	for i, w := range timed {
+		heard[i] = story.TranscriptWord{Word: w.Word, Start: w.Start, End: w.End}
+	}
+	placeWords(words, heard, timed[0].Start, timed[len(timed)-1].End)
+
+	first := 0
+	for i, sentence := range sentences {
+		s := story.SentenceTiming{Text: strings.Join(strings.Fields(sentence), " ")}
+		if ends[i] > first {
+			s.Words = words[first:ends[i]]
+			s.Start, s.End = s.Words[0].Start, s.Words[len(s.Words)-1].End
+		}
+		aligned = append(aligned, s)
+		first = ends[i]
+	}
+	return aligned
+}
+
+// placeWords sets the times of words from matching transcribed words and interpolates the rest
+// between them, weighted by word length and the pause punctuation adds.
+func placeWords(words []story.WordTiming, transcript []story.TranscriptWord, start, end float64) {
+	for text, heard := range alignWords(words, transcript) {
+		words[text].Start = math.Max(transcript[heard].Start, start)
+		words[text].End = math.Min(math.Max(transcript[heard].End, words[text].Start), end)
+		words[text].Estimated = false
+	}
+
+	from, fromTime := 0, start
+	for i := 0; i <= len(words); i++ {
+		if i < len(words) && words[i].Estimated {
+			continue
+		}
+		toTime := end
+		if i < len(words) {
+			toTime = words[i].Start
+		}
+		spread(words[from:i], fromTime, math.Max(toTime, fromTime))
+		if i < len(words) {
+			fromTime = words[i].End
+		}
+		from = i + 1
+	}
+
+	round := func(seconds float64) float64 { return math.Round(seconds*1000) / 1000 }
+	for i := range words {
+		words[i].Start, words[i].End = round(words[i].Start), round(words[i].End)
+	}
+}
+
+func spread(words []story.WordTiming, start, end float64) {
+	total := 0.0
+	for _, w := range words {
+		total += wordWeight(w.Word)
+	}
+	at := start
+	for i := range words {
+		length := (end - start) * wordWeight(words[i].Word) / total
+		words[i].Start, words[i].End = at, at+length
+		at += length
+	}
+}
+
+// wordWeight approximates how long a word takes to say, punctuation stands for the pause after it.
+func wordWeight(word string) float64 {
+	weight := 1.0
+	for _, r := range word {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			weight++
+		}
+	}
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimRight(word, `"'»«“”„)]`))
+	switch {
+	case isSentenceEnd(last):
+		weight += 4
+	case isClauseEnd(last):
+		weight += 2
+	}
+	return weight
+}
+
+// alignWords matches text words to transcribed words by their longest common subsequence,
+// so numbers spelled differently or missed words do not shift the rest.
+func alignWords(words []story.WordTiming, transcript []story.TranscriptWord) map[int]int {
+	matches := make(map[int]int)
+	if len(transcript) == 0 {
+		return matches
+	}
+
+	key := func(w string) string {
+		return strings.Join(normalizeWords(w), "")
+	}
+	a := make([]string, len(words))
+	for i, w := range words {
+		a[i] = key(w.Word)
+	}
+	b := make([]string, len(transcript))
+	for j, w := range transcript {
+		b[j] = key(w.Word)
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] != "" && a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] != "" && a[i] == b[j]:
+			matches[i] = j
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// subtitleCue is one caption on screen.
+type subtitleCue struct {
+	Start, End float64
+	Speaker    string
+	Lines      []string
+}
+
+// subtitleCues splits sentences into cues of at most two lines.
+func subtitleCues(t story.Timings) []subtitleCue {
+	cues := make([]subtitleCue, 0)
+	for _, c := range t.Chunks {
+		for _, s := range c.Sentences {
+			var cue *subtitleCue
+			line := ""
+			for _, w := range s.Words {
+				if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(w.Word) > subtitleLineLength {
+					cue.Lines = append(cue.Lines, line)
+					line = ""
+					if len(cue.Lines) == subtitleLines {
+						cues = append(cues, *cue)
+						cue = nil
+					}
+				}
+				if cue == nil {
+					cue = &subtitleCue{Start: w.Start, Speaker: c.Speaker}
+				}
+				if line != "" {
+					line += " "
+				}
+				line += w.Word
+				cue.End = w.End
+			}
+			if cue != nil {
+				cue.Lines = append(cue.Lines, line)
+				cues = append(cues, *cue)
+			}
+		}
+	}
+
+	for i := range cues {
+		cues[i].End = math.Max(cues[i].End, cues[i].Start+subtitleMinLength)
+		if i+1 < len(cues) {
+			cues[i].End = math.Min(cues[i].End, cues[i+1].Start)
+		}
+	}
+	return cues
+}
+
+func subtitleTime(seconds float64, separator string) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+// SRT renders the timings as SubRip subtitles.
+func SRT(t story.Timings) string {
+	var b strings.Builder
+	for i, cue := range subtitleCues(t) {
+		b.WriteString(fmt.Sprintf("%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(cue.Start, ","), subtitleTime(cue.End, ","), strings.Join(cue.Lines, "\n")))
+	}
+	return b.String()
+}
+
+// WebVTT renders the timings as WebVTT subtitles, character lines carry a voice tag.
+func WebVTT(t story.Timings) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range subtitleCues(t) {
+		text := html.EscapeString(strings.Join(cue.Lines, "\n"))
+		if cue.Speaker != "" {
+			text = fmt.Sprintf("<v %s>%s", html.EscapeString(cue.Speaker), text)
+		}
+		b.WriteString(fmt.Sprintf("%s --> %s\n%s\n\n", subtitleTime(cue.Start, "."), subtitleTime(cue.End, "."), text))
+	}
+	return b.String()
+}
+
+// WriteSubtitles writes base.srt, base.vtt and the base.timings.json word timing file.
+func WriteSubtitles(base string, t story.Timings) ([]string, error) {
+	files := map[string]string{
+		base + ".srt":          SRT(t),
+		base + ".vtt":          WebVTT(t),
+		base + ".timings.json": t.ToJson(),
+	}
+	written := make([]string, 0, len(files))
+	for _, file := range []string{base + ".srt", base + ".vtt", base + ".timings.json"} {
+		if err := os.WriteFile(file, []byte(files[file]), 0644); err != nil {
+			return written, fmt.Errorf("failed to write %q: %w", file, err)
+		}
+		written = append(written, file)
+	}
+	return written, nil
+}
@@ -0,0 +1,130 @@
+package tts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+func TestBuildTimings(t *testing.T) {
+	n := &story.Narration{File: "story.mp3", Duration: 10, Chunks: []story.NarrationChunk{
+		{Chapter: 1, Index: 0, Text: "Once upon a time. The fox slept.", Start: 1, End: 5, Words: []story.TranscriptWord{
+			{Word: "once", Start: 1.2, End: 1.5},
+			{Word: "fox", Start: 3.1, End: 3.4},
+		}},
+		{Chapter: 1, Index: 1, Speaker: "Max", Text: "Hello there!", Start: 6, End: 8},
+	}}
+
+	timings := BuildTimings(n, NewSegmenter(utils.FindLanguage("English")))
+	if len(timings.Chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(timings.Chunks))
+	}
+
+	first := timings.Chunks[0]
+	if len(first.Sentences) != 2 || first.Sentences[0].Text != "Once upon a time." || first.Sentences[1].Text != "The fox slept." {
+		t.Fatalf("sentences = %+v", first.Sentences)
+	}
+	once := first.Sentences[0].Words[0]
+	if once.Start != 1.2 || once.End != 1.5 || once.Estimated {
+		t.Errorf("transcribed word once = %+v, want 1.2-1.5 not estimated", once)
+	}
+	fox := first.Sentences[1].Words[1]
+	if fox.Start != 3.1 || fox.End != 3.4 || fox.Estimated {
+		t.Errorf("transcribed word fox = %+v, want 3.1-3.4 not estimated", fox)
+	}
+	if first.Sentences[1].End != 5 {
+		t.Errorf("last sentence ends at %v, want the chunk end 5", first.Sentences[1].End)
+	}
+
+	second := timings.Chunks[1]
+	words := second.Sentences[0].Words
+	if second.Speaker != "Max" || words[0].Start != 6 || words[len(words)-1].End != 8 || !words[0].Estimated {
+		t.Errorf("untranscribed chunk = %+v, want words estimated over 6-8", second)
+	}
+
+	previous := 0.0
+	for _, w := range timings.Words(0) {
+		if w.Start < previous || w.End < w.Start {
+			t.Errorf("word %+v is out of order after %v", w, previous)
+		}
+		previous = w.End
+	}
+}
+
+func TestBuildTimingsWithoutChunkPositions(t *testing.T) {
+	n := &story.Narration{File: "story.mp3", Chunks: []story.NarrationChunk{{Chapter: 1, Text: "Once."}}}
+	if timings := BuildTimings(n, NewSegmenter(utils.FindLanguage("English"))); len(timings.Chunks) != 0 {
+		t.Errorf("BuildTimings() = %+v, want no chunks", timings)
+	}
+}
+
+func subtitleWords(start, step float64, text string) []story.WordTiming {
+	words := make([]story.WordTiming, 0)
+	for i, w := range strings.Fields(text) {
+		words = append(words, story.WordTiming{Word: w, Start: start + float64(i)*step, End: start + float64(i+1)*step})
+	}
+	return words
+}
+
+func TestSubtitleCues(t *testing.T) {
+	long := "The little fox ran through the dark forest looking for the moon that had fallen behind the hills and the river last night"
+	timings := story.Timings{Chunks: []story.ChunkTiming{
+		{Speaker: "", Sentences: []story.SentenceTiming{{Text: long, Words: subtitleWords(0, 0.3, long)}}},
+		{Speaker: "Max", Sentences: []story.SentenceTiming{{Text: "Hi!", Words: subtitleWords(8, 0.1, "Hi!")}}},
+		{Speaker: "", Sentences: []story.SentenceTiming{{Text: "Yes.", Words: subtitleWords(8.5, 0.2, "Yes.")}}},
+	}}
+
+	cues := subtitleCues(timings)
+	if len(cues) != 4 {
+		t.Fatalf("got %d cues, want 4: %+v", len(cues), cues)
+	}
+
+	words := make([]string, 0)
+	for _, cue := range cues[:2] {
+		if len(cue.Lines) > subtitleLines {
+			t.Errorf("cue has %d lines: %q", len(cue.Lines), cue.Lines)
+		}
+		for _, line := range cue.Lines {
+			if len(line) > subtitleLineLength {
+				t.Errorf("line %q is longer than %d", line, subtitleLineLength)
+			}
+			words = append(words, line)
+		}
+	}
+	if got := strings.Join(words, " "); got != long {
+		t.Errorf("cues read %q, want %q", got, long)
+	}
+	if cues[1].Start != cues[0].End {
+		t.Errorf("second cue starts at %v, want where the first ends %v", cues[1].Start, cues[0].End)
+	}
+
+	hi := cues[2]
+	if hi.Speaker != "Max" || hi.Start != 8 || hi.End != 8.5 {
+		t.Errorf("short cue = %+v, want 8-8.5 by Max, stretched but not over the next cue", hi)
+	}
+	if yes := cues[3]; yes.End != 8.5+subtitleMinLength {
+		t.Errorf("last cue ends at %v, want %v", yes.End, 8.5+subtitleMinLength)
+	}
+}
+
+func TestSubtitleFormats(t *testing.T) {
+	timings := story.Timings{Chunks: []story.ChunkTiming{
+		{Sentences: []story.SentenceTiming{{Text: "Once upon a time.", Words: subtitleWords(61.5, 0.25, "Once upon a time.")}}},
+		{Speaker: "Max & Mia", Sentences: []story.SentenceTiming{{Text: "Look <here>!", Words: subtitleWords(3600, 0.5, "Look <here>!")}}},
+	}}
+
+	wantSRT := "1\n00:01:01,500 --> 00:01:02,500\nOnce upon a time.\n\n" +
+		"2\n01:00:00,000 --> 01:00:01,000\nLook <here>!\n\n"
+	if got := SRT(timings); got != wantSRT {
+		t.Errorf("SRT() =\n%s\nwant\n%s", got, wantSRT)
+	}
+
+	wantVTT := "WEBVTT\n\n" +
+		"00:01:01.500 --> 00:01:02.500\nOnce upon a time.\n\n" +
+		"01:00:00.000 --> 01:00:01.000\n<v Max &amp; Mia>Look &lt;here&gt;!\n\n"
+	if got := WebVTT(timings); got != wantVTT {
+		t.Errorf("WebVTT() =\n%s\nwant\n%s", got, wantVTT)
+	}
+}
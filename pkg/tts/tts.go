@@ -10,7 +10,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/andrejsstepanovs/storygen/pkg/audio"
 	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
 )
 
 // TTSConverter interface for text-to-speech conversion
@@ -18,24 +20,124 @@ type TTSConverter interface {
 	Convert(text, voice, instructions string, speed float64) (string, error)
 }
 
-func TextToSpeech(dir, outputFilePath, textToSpeech string, voice story.Voice, splitLen int, postProcess bool, converter TTSConverter) (string, error) {
+const (
+	BackendNative = "native"
+	BackendFFmpeg = "ffmpeg"
+)
+
+// Options controls how TextToSpeech splits text and processes the resulting audio.
+type Options struct {
+	SplitLen       int
+	PostProcess    bool
+	Backend        string // BackendNative (default) or BackendFFmpeg
+	Cleanup        Cleanup
+	Normalize      bool
+	LoudnessTarget float64 // Integrated loudness in LUFS every chunk and the final file is normalized to
+	TruePeakLimit  float64 // Maximum true peak in dBTP after normalization
+	QA             QA
+	Sounds         Sounds
+	Cast           *Cast // Full-cast narration, nil reads everything with the narrator voice
+	// Annotate returns the mood of every chunk of a chapter, nil reads every chunk with the global instructions
+	Annotate func(chapter string, chunks []string) ([]story.Mood, error)
+	Sleep    Sleep
+	// Normalizer spells out numbers, abbreviations and lexicon words, nil sends the text as written
+	Normalizer *Normalizer
+	Segmenter  *Segmenter   // Splits chapters into chunks, nil uses English rules
+	Markup     string       // MarkupSSML, MarkupInstructions or MarkupSilence (default)
+	Format     audio.Format // Output container and encoding, empty is MP3
+	// Transcribe gives word timestamps of every chunk for subtitles, QA transcripts are reused
+	Transcribe func(file string) (story.Transcript, error)
+	ChunkDir   string // Where chunk audio is written until it is joined, empty is the output directory
+	// ChapterLabel is the word for chapter the text was built with, the chapters are split at it. Empty is story.TextChapter
+	ChapterLabel string
+}
+
+// Result describes the narration produced by TextToSpeech.
+type Result struct {
+	File     string
+	Loudness *audio.Loudness // Final file, only measured when joined natively
+	Duration time.Duration   // Final file, only known when joined natively
+	Chunks   []Chunk
+}
+
+// Chunk is one piece of text sent to the TTS converter.
+type Chunk struct {
+	Chapter  int
+	Index    int
+	Speaker  string // Character reading the chunk, empty for the narrator
+	Text     string
+	File     string
+	Format   audio.Format // As returned by the converter
+	Mood     *story.Mood
+	Loudness *audio.Loudness // As returned by TTS, before normalization
+	Attempts int
+	Problems []string // QA problems left in the accepted audio
+	// Silence inserted around the chunk audio while joining
+	PauseBefore time.Duration
+	PauseAfter  time.Duration
+	// Position of the chunk audio in the final file, only known when joined natively
+	Start time.Duration
+	End   time.Duration
+	Words []story.TranscriptWord // Transcribed words, seconds from the chunk start
+}
+
+func (o Options) format() audio.Format {
+	if o.Format == "" {
+		return audio.FormatMP3
+	}
+	return o.Format
+}
 
-	files := make([]string, 0)
+// cleanNatively reports whether spikes and silences are removed while joining,
+// the ffmpeg backend only handles MP3 output.
+func (o Options) cleanNatively() bool {
+	return o.PostProcess && (o.Backend != BackendFFmpeg || o.format() != audio.FormatMP3)
+}
+
+// joinNatively reports whether chunks are decoded and joined as PCM, plain MP3 frame
+// concatenation only works when every chunk and the output are MP3 and nothing is added.
+func (o Options) joinNatively(chunks []Chunk) bool {
+	for _, c := range chunks {
+		if c.PauseBefore > 0 || c.PauseAfter > 0 || c.Format != audio.FormatMP3 {
+			return true
+		}
+	}
+	return o.format() != audio.FormatMP3 || o.Normalize || o.Sounds.any() || o.Sleep.Enabled || o.cleanNatively()
+}
 
-	chapterTexts := splitByChapters(textToSpeech)
+func TextToSpeech(dir, outputFilePath, textToSpeech string, voice story.Voice, opts Options, converter TTSConverter) (*Result, error) {
+
+	chunks := make([]Chunk, 0)
+
+	chapterTexts := splitByChapters(textToSpeech, opts.ChapterLabel)
 
 	if len(chapterTexts) == 0 {
 		fmt.Println("Input text resulted in zero chapters after splitting.")
-		return "", nil
+		return nil, fmt.Errorf("input text resulted in zero chapters")
 	}
 
+	segmenter := opts.Segmenter
+	if segmenter == nil {
+		segmenter = NewSegmenter(utils.FindLanguage("english"))
+	}
+	cast := opts.Cast.normalized(opts.Normalizer)
+	chunkDir := dir
+	if opts.ChunkDir != "" {
+		chunkDir = opts.ChunkDir
+		if err := os.MkdirAll(chunkDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	var pause time.Duration // Pause waiting for the next chunk
 	for n, chapterText := range chapterTexts {
 		if chapterText == "" {
 			continue
 		}
 
-		chunks := chunkText(chapterText, splitLen)
-		for k, chunk := range chunks {
+		chapterText = normalizeMarkup(opts.Normalizer, chapterText)
+		textChunks := segmenter.Chunks(chapterText, opts.SplitLen)
+		cleanChunks := make([]string, 0, len(textChunks))
+		for _, chunk := range textChunks {
 			trimmedChunk := strings.TrimSpace(chunk)
 			trimmedChunk = strings.TrimLeft(trimmedChunk, "...")
 			trimmedChunk = strings.TrimSpace(trimmedChunk)
@@ -48,77 +150,206 @@ func TextToSpeech(dir, outputFilePath, textToSpeech string, voice story.Voice, s
 			for _, line := range lines {
 				cleanLines = append(cleanLines, strings.TrimSpace(line))
 			}
-			cleanContent := strings.Join(cleanLines, "\n")
-
-			file := fmt.Sprintf("%d_%d_%s", n, k, outputFilePath) // n=segment index, k=chunk index
-			targetFile := path.Join(dir, file)
-
-			fmt.Printf(">>> %s\n%s\n<<<\n", targetFile, cleanContent)
-
-			// Use the converter interface to generate speech
-			audioFilePath, err := converter.Convert(cleanContent, voice.Provider.Voice, voice.Instruction.String(), voice.Provider.Speed)
-			if err != nil {
-				return "", fmt.Errorf("failed to convert text to speech: %w", err)
-			}
-
-			// Copy the generated file to the target location
-			err = copyFile(audioFilePath, targetFile)
-			if err != nil {
-				return "", fmt.Errorf("failed to copy audio file: %w", err)
+			cleanChunks = append(cleanChunks, strings.Join(cleanLines, "\n"))
+		}
+		plainChunks := make([]string, len(cleanChunks))
+		for k, chunk := range cleanChunks {
+			plainChunks[k] = story.StripMarkup(chunk)
+		}
+		moods := annotateChunks(opts.Annotate, story.StripMarkup(chapterText), plainChunks)
+
+		for k, cleanContent := range cleanChunks {
+			chunkVoice := opts.Sleep.apply(voice, opts.Sleep.progress(n, len(chapterTexts), k, len(cleanChunks)))
+			chunkVoice.Instruction.Mood = moods[k]
+
+			// With a cast every quote becomes its own segment read by the character voice
+			part := 0
+			for _, segment := range cast.Split(cleanContent) {
+				segmentVoice := cast.VoiceFor(segment.Speaker, chunkVoice)
+				for _, rendered := range renderMarkup(segment.Text, opts.Markup) {
+					if rendered.Text == "" {
+						// Only a pause, it goes after the previous chunk or before the next one
+						if len(chunks) > 0 {
+							chunks[len(chunks)-1].PauseAfter += rendered.PauseBefore
+						} else {
+							pause += rendered.PauseBefore
+						}
+						continue
+					}
+
+					file := fmt.Sprintf("%d_%d_%d_%s", n, k, part, outputFilePath) // n=segment index, k=chunk index
+					targetFile := path.Join(chunkDir, file)
+					part++
+
+					fmt.Printf(">>> %s %s\n%s\n<<<\n", targetFile, segment.Speaker, rendered.Input)
+
+					chunk, err := generateChunk(converter, opts.QA, opts.Transcribe, rendered, targetFile, segmentVoice)
+					if err != nil {
+						return nil, err
+					}
+					chunk.Chapter, chunk.Index, chunk.Speaker, chunk.Mood = n+1, k, cast.Character(segment.Speaker), moods[k]
+					chunk.PauseBefore += pause
+					pause = 0
+
+					chunks = append(chunks, chunk)
+				}
 			}
-
-			// Clean up the temporary file
-			_ = os.Remove(audioFilePath)
-
-			time.Sleep(time.Second * 1) // Rate limiting
-
-			files = append(files, targetFile)
 		}
 	}
 
-	if len(files) == 0 {
+	if len(chunks) == 0 {
 		fmt.Println("No audio files were generated.")
-		return "", fmt.Errorf("no audio files generated, cannot join")
+		return nil, fmt.Errorf("no audio files generated, cannot join")
 	}
 
-	fmt.Printf("\nJoining %d audio segments...\n", len(files))
-	finalFile := path.Join(dir, outputFilePath)
-	err := JoinMp3Files(files, finalFile, "")
-	if err != nil {
-		return "", fmt.Errorf("failed to join MP3 files: %w", err)
+	files := make([]string, len(chunks))
+	for i, c := range chunks {
+		files[i] = c.File
 	}
 
-	fmt.Println("\nCleaning up temporary files...")
-	err = Remove(files)
-	if err != nil {
-		fmt.Printf("Warning: Failed to remove temporary files: %v\n", err)
+	result := &Result{File: path.Join(dir, outputFilePath), Chunks: chunks}
+	if opts.joinNatively(chunks) {
+		fmt.Printf("\nJoining and processing %d audio segments...\n", len(files))
+		loudness, duration, err := joinNative(result.Chunks, result.File, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join audio: %w", err)
+		}
+		result.Loudness, result.Duration = loudness, duration
+	} else {
+		fmt.Printf("\nJoining %d audio segments...\n", len(files))
+		err := JoinMp3Files(files, result.File, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to join MP3 files: %w", err)
+		}
 	}
 
-	fmt.Println("\nTextToSpeech process completed successfully.")
+	removeChunks(files)
 
 	// OpenAI creates big pauses and silences in files.
-	// Tried everything to remove them, but no luck.
-	// So, we are using ffmpeg to remove them.
-	if postProcess {
+	// The native backend handles them while joining, ffmpeg is kept as an alternative.
+	if opts.PostProcess && !opts.cleanNatively() {
 		unnoisedFile := path.Join(dir, "unnoised_"+outputFilePath)
-		err = postProcessNoiseRemoval(finalFile, unnoisedFile)
+		err := postProcessNoiseRemoval(result.File, unnoisedFile)
 		if err != nil {
-			return "", fmt.Errorf("failed to post-process noise removal: %w", err)
+			return nil, fmt.Errorf("failed to post-process noise removal: %w", err)
 		}
 
 		cleanFile := path.Join(dir, "clean_"+outputFilePath)
 		err = postProcessSilenceRemoval(unnoisedFile, cleanFile)
 		if err != nil {
-			return "", fmt.Errorf("failed to post-process silence removal: %w", err)
+			return nil, fmt.Errorf("failed to post-process silence removal: %w", err)
 		}
 		fmt.Printf("Cleaned file saved as: %s\n", cleanFile)
-		os.Remove(finalFile)
+		os.Remove(result.File)
 		os.Remove(unnoisedFile)
 
-		return cleanFile, nil
+		// Removed silences moved everything, the positions are unknown now
+		result.File, result.Duration = cleanFile, 0
+		for i := range result.Chunks {
+			result.Chunks[i].Start, result.Chunks[i].End = 0, 0
+		}
+	}
+
+	return result, nil
+}
+
+// annotateChunks returns the mood of every chunk, nil entries when annotation is off or fails.
+func annotateChunks(annotate func(chapter string, chunks []string) ([]story.Mood, error), chapter string, chunks []string) []*story.Mood {
+	moods := make([]*story.Mood, len(chunks))
+	if annotate == nil {
+		return moods
+	}
+
+	annotated, err := annotate(chapter, chunks)
+	if err != nil {
+		fmt.Printf("Warning: failed to annotate chunk moods, using global voice instructions: %v\n", err)
+		return moods
+	}
+	for k := range annotated {
+		if k < len(moods) {
+			moods[k] = &annotated[k]
+		}
+	}
+	return moods
+}
+
+// generateChunk converts the rendered part into targetFile, regenerating it while QA finds problems.
+// With transcribe the accepted audio is transcribed for word timings unless QA already did it.
+func generateChunk(converter TTSConverter, qa QA, transcribe func(string) (story.Transcript, error), part markupPart, targetFile string, voice story.Voice) (Chunk, error) {
+	text := part.Text
+	chunk := Chunk{Text: text, File: targetFile, PauseBefore: part.PauseBefore, PauseAfter: part.PauseAfter}
+	voice.Instruction.Delivery = part.Delivery
+	for {
+		chunk.Attempts++
+
+		// Use the converter interface to generate speech
+		audioFilePath, err := converter.Convert(part.Input, voice.Provider.Voice, voice.Instruction.String(), voice.Provider.Speed)
+		if err != nil {
+			return chunk, fmt.Errorf("failed to convert text to speech: %w", err)
+		}
+
+		// Providers do not always return what was asked for, the content tells the real format
+		chunk.Format, err = audio.DetectFile(audioFilePath)
+		if err != nil {
+			return chunk, fmt.Errorf("failed to detect audio format: %w", err)
+		}
+		chunk.File = strings.TrimSuffix(targetFile, path.Ext(targetFile)) + chunk.Format.Extension()
+
+		// Copy the generated file to the target location
+		err = copyFile(audioFilePath, chunk.File)
+		if err != nil {
+			return chunk, fmt.Errorf("failed to copy audio file: %w", err)
+		}
+
+		// Clean up the temporary file
+		_ = os.Remove(audioFilePath)
+
+		time.Sleep(time.Second * 1) // Rate limiting
+
+		if !qa.Enabled {
+			return transcribeChunk(chunk, transcribe, nil), nil
+		}
+
+		var transcript *story.Transcript
+		chunk.Problems, transcript = qa.check(chunk.File, text, voice.Provider.Speed)
+		if len(chunk.Problems) == 0 {
+			return transcribeChunk(chunk, transcribe, transcript), nil
+		}
+		fmt.Printf("QA problems in %s: %s\n", chunk.File, strings.Join(chunk.Problems, "; "))
+		if chunk.Attempts > qa.MaxRegenerations {
+			fmt.Printf("Warning: keeping %s after %d attempts\n", chunk.File, chunk.Attempts)
+			return transcribeChunk(chunk, transcribe, transcript), nil
+		}
+		_ = os.Remove(chunk.File)
+		fmt.Printf("Regenerating %s (attempt %d)...\n", chunk.File, chunk.Attempts+1)
+	}
+}
+
+// transcribeChunk sets the chunk words from the QA transcript or a new transcription.
+// Failures only cost the word timings, they are estimated from the text then.
+func transcribeChunk(chunk Chunk, transcribe func(string) (story.Transcript, error), transcript *story.Transcript) Chunk {
+	if transcript == nil && transcribe != nil {
+		t, err := transcribe(chunk.File)
+		if err != nil {
+			fmt.Printf("Warning: failed to transcribe %s, word timings will be estimated: %v\n", chunk.File, err)
+			return chunk
+		}
+		transcript = &t
+	}
+	if transcript != nil {
+		chunk.Words = transcript.Words
+	}
+	return chunk
+}
+
+func removeChunks(files []string) {
+	fmt.Println("\nCleaning up temporary files...")
+	err := Remove(files)
+	if err != nil {
+		fmt.Printf("Warning: Failed to remove temporary files: %v\n", err)
 	}
 
-	return finalFile, nil
+	fmt.Println("\nTextToSpeech process completed successfully.")
 }
 
 func postProcessNoiseRemoval(inputFile, outputFile string) error {
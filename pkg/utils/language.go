@@ -0,0 +1,55 @@
+package utils
+
+import "strings"
+
+// Language maps a STORYGEN_LANGUAGE name to its ISO 639 codes.
+type Language struct {
+	Name  string
+	ISO1  string // ISO 639-1, e.g. "en"
+	ISO2  string // ISO 639-2, e.g. "eng"
+	Label string // Native name, e.g. "English"
+}
+
+var languages = []Language{
+	{Name: "english", ISO1: "en", ISO2: "eng", Label: "English"},
+	{Name: "latvian", ISO1: "lv", ISO2: "lav", Label: "Latviešu"},
+	{Name: "russian", ISO1: "ru", ISO2: "rus", Label: "Русский"},
+	{Name: "german", ISO1: "de", ISO2: "deu", Label: "Deutsch"},
+	{Name: "french", ISO1: "fr", ISO2: "fra", Label: "Français"},
+	{Name: "spanish", ISO1: "es", ISO2: "spa", Label: "Español"},
+	{Name: "italian", ISO1: "it", ISO2: "ita", Label: "Italiano"},
+	{Name: "portuguese", ISO1: "pt", ISO2: "por", Label: "Português"},
+	{Name: "dutch", ISO1: "nl", ISO2: "nld", Label: "Nederlands"},
+	{Name: "polish", ISO1: "pl", ISO2: "pol", Label: "Polski"},
+	{Name: "lithuanian", ISO1: "lt", ISO2: "lit", Label: "Lietuvių"},
+	{Name: "estonian", ISO1: "et", ISO2: "est", Label: "Eesti"},
+	{Name: "ukrainian", ISO1: "uk", ISO2: "ukr", Label: "Українська"},
+	{Name: "greek", ISO1: "el", ISO2: "ell", Label: "Ελληνικά"},
+	{Name: "japanese", ISO1: "ja", ISO2: "jpn", Label: "日本語"},
+	{Name: "chinese", ISO1: "zh", ISO2: "zho", Label: "中文"},
+}
+
+// Languages returns all known languages.
+func Languages() []Language {
+	return append([]Language(nil), languages...)
+}
+
+// FindLanguage looks up a language by name or ISO code.
+// Unknown languages fall back to English.
+func FindLanguage(name string) Language {
+	if l, ok := LookupLanguage(name); ok {
+		return l
+	}
+	return languages[0]
+}
+
+// LookupLanguage looks up a language by name or ISO code and reports whether it is known.
+func LookupLanguage(name string) (Language, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, l := range languages {
+		if l.Name == name || l.ISO1 == name || l.ISO2 == name {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/gosimple/slug"
+)
+
+// maxSlugLength keeps slugged names short enough to leave room for a language prefix and the story ID.
+const maxSlugLength = 80
+
+// Slug transliterates a title in the given language (name or ISO code) to a lowercase
+// ASCII file name part, "Лиса и Луна" becomes "lisa-i-luna". When nothing of the title
+// is left, emoji or punctuation only, the fallback is returned.
+func Slug(title, language, fallback string) string {
+	s := slug.MakeLang(title, FindLanguage(language).ISO1)
+	if len(s) > maxSlugLength {
+		s = s[:maxSlugLength]
+		if i := strings.LastIndexByte(s, '-'); i > 0 {
+			s = s[:i]
+		}
+		s = strings.Trim(s, "-")
+	}
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	long := strings.Repeat("sleepy fox ", 12)
+	tests := []struct {
+		name     string
+		title    string
+		language string
+		want     string
+	}{
+		{name: "latin", title: "The Fox and the Moon", language: "english", want: "the-fox-and-the-moon"},
+		{name: "cyrillic", title: "Лиса и Луна", language: "russian", want: "lisa-i-luna"},
+		{name: "greek", title: "Η αλεπού", language: "greek", want: "i-alepoy"},
+		{name: "language substitutions", title: "Fuchs & Mond", language: "german", want: "fuchs-und-mond"},
+		{name: "emoji only falls back to the id", title: "🦊🌙", language: "english", want: "1a2b3c4d5e6f"},
+		{name: "empty falls back to the id", title: "", language: "", want: "1a2b3c4d5e6f"},
+		{name: "truncated at a word", title: long, language: "english", want: strings.TrimSuffix(strings.Repeat("sleepy-fox-", 7), "-")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Slug(tt.title, tt.language, "1a2b3c4d5e6f")
+			if got != tt.want {
+				t.Errorf("Slug(%q, %q) = %q, want %q", tt.title, tt.language, got, tt.want)
+			}
+			if len(got) > maxSlugLength {
+				t.Errorf("Slug(%q) is %d bytes long, more than %d", tt.title, len(got), maxSlugLength)
+			}
+		})
+	}
+}
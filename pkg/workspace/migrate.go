@@ -0,0 +1,304 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Migration reports what MigrateLegacy moved.
+type Migration struct {
+	Workspace *Workspace
+	Files     []string // The legacy files moved into the workspace
+}
+
+// legacyFile is a story JSON of the flat layout, named after the sanitized title:
+// <title>.json, <n>_groomed_<title>.json, final_groomed_<title>.json, final_<title>.json
+// and translations <language>_<title>.json, voiced as <language>_final_groomed_<title>.json.
+type legacyFile struct {
+	path     string
+	title    string // Sanitized title the file name ends with
+	language string
+	order    int // Written 0, groomed loops 1.., final last
+	stage    string
+	story    story.Story
+}
+
+var groomedName = regexp.MustCompile(`^(\d+)_groomed_(.+)$`)
+
+// chunkName matches chunk audio left behind by an interrupted run, <segment>_<chunk>_<part>_<file>
+// and <segment>_<chunk>_<file> from before chunks were split into parts.
+var chunkName = regexp.MustCompile(`^\d+_\d+_(?:(\d+)_)?(.+)$`)
+
+// chunkOf reports whether a chunk file was narrated from a story with the sanitized title.
+// Titles starting with a number read like a part, so both readings of the name are tried.
+func chunkOf(name, title string) bool {
+	m := chunkName.FindStringSubmatch(name)
+	if m == nil {
+		return false
+	}
+	files := []string{m[2]}
+	if m[1] != "" {
+		files = append(files, m[1]+"_"+m[2])
+	}
+	for _, file := range files {
+		if parseLegacyName(strings.TrimSuffix(file, filepath.Ext(file))).title == title {
+			return true
+		}
+	}
+	return false
+}
+
+func parseLegacyName(name string) legacyFile {
+	f := legacyFile{title: name, stage: StageWritten}
+	for _, l := range utils.Languages() {
+		if strings.HasPrefix(f.title, l.Name+"_") {
+			f.language, f.title = l.Name, strings.TrimPrefix(f.title, l.Name+"_")
+			break
+		}
+	}
+	switch {
+	case strings.HasPrefix(f.title, "final_groomed_"):
+		f.title, f.stage, f.order = strings.TrimPrefix(f.title, "final_groomed_"), StageFinal, 1<<30
+	case strings.HasPrefix(f.title, "final_"):
+		f.title, f.stage, f.order = strings.TrimPrefix(f.title, "final_"), StageFinal, 1<<30
+	default:
+		if m := groomedName.FindStringSubmatch(f.title); m != nil {
+			f.order, _ = strconv.Atoi(m[1])
+			f.title, f.stage = m[2], StageGroomed
+		}
+	}
+	return f
+}
+
+// MigrateLegacy moves the story JSONs of the flat layout in storyDir into workspaces under root,
+// one per title, together with chunk audio left in audioDir. Revisions keep their order, the last
+// one becomes the current story, narrated translations win over plain ones. Invalid files are
+// left where they are and reported. Narration audio stays in audioDir, chunks that could not be moved
+// are moved by the next run.
+func MigrateLegacy(root, storyDir, audioDir string) ([]Migration, []error) {
+	files, err := filepath.Glob(filepath.Join(storyDir, "*.json"))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	problems := make([]error, 0)
+	groups := make(map[string][]legacyFile)
+	titles := make([]string, 0)
+	for _, file := range files {
+		f := parseLegacyName(strings.TrimSuffix(filepath.Base(file), ".json"))
+		f.path = file
+		if f.story, err = story.Load(file); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if _, ok := groups[f.title]; !ok {
+			titles = append(titles, f.title)
+		}
+		groups[f.title] = append(groups[f.title], f)
+	}
+
+	migrations := make([]Migration, 0, len(titles))
+	for _, title := range titles {
+		m, err := migrateGroup(root, groups[title])
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", title, err))
+			continue
+		}
+		moved, errs := moveChunks(m.Workspace, audioDir, title)
+		m.Files = append(m.Files, moved...)
+		for _, err := range errs {
+			problems = append(problems, fmt.Errorf("%s: %w", title, err))
+		}
+		migrations = append(migrations, m)
+	}
+
+	// Chunks that could not be moved before are moved to the workspace their stories went to
+	workspaces, err := List(root)
+	if err != nil {
+		return migrations, append(problems, err)
+	}
+	for _, w := range workspaces {
+		m := Migration{Workspace: w}
+		for _, title := range migratedTitles(w) {
+			if _, ok := groups[title]; ok {
+				continue
+			}
+			moved, errs := moveChunks(w, audioDir, title)
+			m.Files = append(m.Files, moved...)
+			for _, err := range errs {
+				problems = append(problems, fmt.Errorf("%s: %w", title, err))
+			}
+		}
+		if len(m.Files) > 0 {
+			migrations = append(migrations, m)
+		}
+	}
+	return migrations, problems
+}
+
+// migratedTitles returns the sanitized titles of the legacy files moved into the workspace.
+func migratedTitles(w *Workspace) []string {
+	titles := make([]string, 0, 1)
+	for _, file := range w.State.Migrated {
+		title := parseLegacyName(strings.TrimSuffix(file, filepath.Ext(file))).title
+		if !slices.Contains(titles, title) {
+			titles = append(titles, title)
+		}
+	}
+	return titles
+}
+
+// LegacyOriginalID returns the ID of the original story a translation of the flat layout was
+// made from, its latest revision is found next to the file by title. Files that already had
+// an ID, originals and files in a workspace are not looked up.
+func LegacyOriginalID(file string) (string, bool) {
+	if _, ok := For(file); ok {
+		return "", false
+	}
+	f := parseLegacyName(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	if f.language == "" {
+		return "", false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	var stored struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil || stored.ID != "" {
+		return "", false
+	}
+
+	siblings, err := filepath.Glob(filepath.Join(filepath.Dir(file), "*.json"))
+	if err != nil {
+		return "", false
+	}
+	var original *legacyFile
+	for _, sibling := range siblings {
+		o := parseLegacyName(strings.TrimSuffix(filepath.Base(sibling), ".json"))
+		if o.language != "" || o.title != f.title || (original != nil && o.order < original.order) {
+			continue
+		}
+		if o.story, err = story.Load(sibling); err != nil {
+			continue
+		}
+		original = &o
+	}
+	if original == nil {
+		return "", false
+	}
+	return original.story.ID, true
+}
+
+func migrateGroup(root string, files []legacyFile) (Migration, error) {
+	originals, translations := make([]legacyFile, 0), make(map[string]legacyFile)
+	for _, f := range files {
+		if f.language == "" {
+			originals = append(originals, f)
+			continue
+		}
+		// The voiced translation was saved next to the plain one
+		if current, ok := translations[f.language]; !ok || (current.story.Narration == nil && f.story.Narration != nil) {
+			translations[f.language] = f
+		}
+	}
+	sort.SliceStable(originals, func(i, j int) bool { return originals[i].order < originals[j].order })
+
+	id := ""
+	if len(originals) > 0 {
+		id = originals[len(originals)-1].story.ID
+	} else {
+		for _, f := range translations {
+			id = f.story.ID
+			break
+		}
+	}
+	w, err := Open(root, id)
+	if err != nil {
+		return Migration{}, err
+	}
+	m := Migration{Workspace: w}
+
+	for _, f := range originals {
+		f.story.ID = id
+		if f.story.Narration != nil {
+			_, err = w.SaveNarration("", f.story)
+		} else {
+			_, err = w.SaveStory(f.stage, f.story)
+		}
+		if err != nil {
+			return m, err
+		}
+		m.Files = append(m.Files, f.path)
+	}
+	languages := make([]string, 0, len(translations))
+	for language := range translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		f := translations[language]
+		f.story.ID = id
+		if _, err := w.SaveNarration(language, f.story); err != nil {
+			return m, err
+		}
+	}
+	for _, f := range files {
+		if f.language != "" {
+			m.Files = append(m.Files, f.path)
+		}
+	}
+
+	for _, file := range m.Files {
+		w.State.Migrated = append(w.State.Migrated, filepath.Base(file))
+	}
+	if err := w.Save(); err != nil {
+		return m, err
+	}
+	for _, file := range m.Files {
+		if err := os.Remove(file); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// moveChunks moves chunk audio of the title left in audioDir into the workspace.
+// It returns the moved files and why the others could not be moved.
+func moveChunks(w *Workspace, audioDir, title string) ([]string, []error) {
+	entries, err := os.ReadDir(audioDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+	moved, problems := make([]string, 0), make([]error, 0)
+	for _, e := range entries {
+		if e.IsDir() || !chunkOf(e.Name(), title) {
+			continue
+		}
+		from, to := filepath.Join(audioDir, e.Name()), w.Path(AudioDir, e.Name())
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if err := os.Rename(from, to); err != nil {
+			problems = append(problems, fmt.Errorf("failed to move %s: %w", from, err))
+			continue
+		}
+		moved = append(moved, from)
+	}
+	return moved, problems
+}
@@ -0,0 +1,219 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+)
+
+// legacyStory is a story JSON from before versions and IDs.
+func legacyStory(title, summary, text string) string {
+	return `{"title":"` + title + `","story_prompt":"a fox","summary":"` + summary + `","plan":"1. Night","chapters":[{"number":1,"title":"","text":"` + text + `"}]}`
+}
+
+func writeLegacy(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLegacyOriginalID(t *testing.T) {
+	dir := t.TempDir()
+	writeLegacy(t, dir, "The_Fox.json", legacyStory("The Fox", "A fox.", "Once."))
+	final := writeLegacy(t, dir, "final_groomed_The_Fox.json", legacyStory("The Fox", "A fox finds the moon.", "Once upon a time."))
+	translation := writeLegacy(t, dir, "latvian_final_groomed_The_Fox.json", legacyStory("Lapsa", "Lapsa.", "Reiz sen."))
+	orphan := writeLegacy(t, dir, "latvian_The_Owl.json", legacyStory("Pūce", "Pūce.", "Reiz."))
+	withID := writeLegacy(t, dir, "german_The_Fox.json", `{"version":3,"id":"own-id","title":"Fuchs","chapters":[{"number":1,"title":"","text":"Es war."}]}`)
+
+	original, err := story.Load(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := story.Load(translation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID == original.ID {
+		t.Fatalf("the legacy translation already has the id of its original")
+	}
+
+	tests := []struct {
+		name   string
+		file   string
+		wantID string
+		wantOK bool
+	}{
+		{name: "translation", file: translation, wantID: original.ID, wantOK: true},
+		{name: "original", file: final},
+		{name: "translation without its original", file: orphan},
+		{name: "translation with an id", file: withID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := LegacyOriginalID(tt.file)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("LegacyOriginalID() = %q, %v, want %q, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLegacyOriginalIDInWorkspace(t *testing.T) {
+	root := t.TempDir()
+	w, err := Open(root, "1a2b3c4d5e6f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLegacy(t, w.Dir, "The_Fox.json", legacyStory("The Fox", "A fox.", "Once."))
+	file := writeLegacy(t, w.Dir, filepath.Join(TranslationsDir, "latvian.json"), legacyStory("Lapsa", "Lapsa.", "Reiz sen."))
+
+	if id, ok := LegacyOriginalID(file); ok {
+		t.Errorf("LegacyOriginalID() of a workspace translation = %q", id)
+	}
+}
+
+func TestChunkOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  bool
+	}{
+		{name: "0_3_1_The_Fox.mp3", title: "The_Fox", want: true},
+		{name: "2_0_final_groomed_The_Fox.mp3", title: "The_Fox", want: true},
+		{name: "1_4_0_latvian_final_groomed_The_Fox.mp3", title: "The_Fox", want: true},
+		{name: "1_4_latvian_final_groomed_The_Fox.mp3", title: "The_Fox", want: true},
+		{name: "0_1_3_Little_Pigs.mp3", title: "3_Little_Pigs", want: true},
+		{name: "0_1_2_3_Little_Pigs.mp3", title: "3_Little_Pigs", want: true},
+		{name: "0_1_2_3_Little_Pigs.mp3", title: "Little_Pigs", want: false},
+		{name: "0_1_The_Owl.mp3", title: "The_Fox", want: false},
+		{name: "The_Fox.mp3", title: "The_Fox", want: false},
+		{name: "latvian_The_Fox.mp3", title: "The_Fox", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkOf(tt.name, tt.title); got != tt.want {
+				t.Errorf("chunkOf(%q, %q) = %v, want %v", tt.name, tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	root, storyDir, audioDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeLegacy(t, storyDir, "The_Fox.json", legacyStory("The Fox", "A fox.", "written"))
+	writeLegacy(t, storyDir, "2_groomed_The_Fox.json", legacyStory("The Fox", "A fox.", "groomed 2"))
+	writeLegacy(t, storyDir, "10_groomed_The_Fox.json", legacyStory("The Fox", "A fox.", "groomed 10"))
+	writeLegacy(t, storyDir, "final_groomed_The_Fox.json", legacyStory("The Fox", "A fox.", "final"))
+	writeLegacy(t, storyDir, "latvian_final_groomed_The_Fox.json",
+		`{"title":"Lapsa","chapters":[{"number":1,"title":"","text":"Reiz."}],"narration":{"file":"target/latvian_final_groomed_The_Fox.mp3","voice":"nova","speed":1,"chunks":[]}}`)
+	writeLegacy(t, storyDir, "latvian_The_Fox.json", legacyStory("Lapsa", "Lapsa.", "Reiz."))
+	writeLegacy(t, storyDir, "The_Owl.json", legacyStory("The Owl", "An owl.", "Hoo."))
+	broken := writeLegacy(t, storyDir, "The_Bear.json", `{"title":`)
+
+	for _, name := range []string{"0_0_0_final_groomed_The_Fox.mp3", "0_1_latvian_final_groomed_The_Fox.mp3", "1_0_The_Owl.mp3", "latvian_final_groomed_The_Fox.mp3"} {
+		writeLegacy(t, audioDir, name, "audio")
+	}
+
+	migrations, problems := MigrateLegacy(root, storyDir, audioDir)
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "The_Bear.json") {
+		t.Errorf("problems = %v, want only the broken bear", problems)
+	}
+	if _, err := os.Stat(broken); err != nil {
+		t.Errorf("broken file was not left in place: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want the fox and the owl", len(migrations))
+	}
+
+	fox := migrations[0].Workspace
+	if fox == migrations[1].Workspace || fox.State.ID == migrations[1].Workspace.State.ID {
+		t.Fatalf("fox and owl share workspace %s", fox.Dir)
+	}
+	wantRevisions := []string{"01_written.json", "02_groomed.json", "03_groomed.json", "04_final.json"}
+	if !reflect.DeepEqual(fox.State.Revisions, wantRevisions) {
+		t.Errorf("revisions = %q, want %q", fox.State.Revisions, wantRevisions)
+	}
+	for i, want := range []string{"written", "groomed 2", "groomed 10", "final"} {
+		s, err := story.Load(fox.Path(RevisionsDir, wantRevisions[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Chapters[0].Text != want || s.ID != fox.State.ID {
+			t.Errorf("revision %s = %q with id %s, want %q with id %s", wantRevisions[i], s.Chapters[0].Text, s.ID, want, fox.State.ID)
+		}
+	}
+	current, err := story.Load(fox.Path(StoryFile))
+	if err != nil || current.Chapters[0].Text != "final" {
+		t.Errorf("current story = %+v, %v, want the final revision", current.Chapters, err)
+	}
+
+	if !reflect.DeepEqual(fox.State.Translations, []string{"latvian"}) {
+		t.Errorf("translations = %q", fox.State.Translations)
+	}
+	lv, err := story.Load(fox.TranslationFile("latvian"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lv.Narration == nil || lv.ID != fox.State.ID {
+		t.Errorf("translation = %+v with id %s, want the voiced one with id %s", lv.Narration, lv.ID, fox.State.ID)
+	}
+
+	for _, name := range []string{"0_0_0_final_groomed_The_Fox.mp3", "0_1_latvian_final_groomed_The_Fox.mp3"} {
+		if _, err := os.Stat(fox.Path(AudioDir, name)); err != nil {
+			t.Errorf("chunk %s was not moved: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(migrations[1].Workspace.Path(AudioDir, "1_0_The_Owl.mp3")); err != nil {
+		t.Errorf("owl chunk was not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(audioDir, "latvian_final_groomed_The_Fox.mp3")); err != nil {
+		t.Errorf("narration audio was moved: %v", err)
+	}
+
+	left, _ := filepath.Glob(filepath.Join(storyDir, "*.json"))
+	if !reflect.DeepEqual(left, []string{broken}) {
+		t.Errorf("left in the story directory: %q", left)
+	}
+	if len(fox.State.Migrated) != 6 {
+		t.Errorf("migrated = %q, want the 6 fox files", fox.State.Migrated)
+	}
+}
+
+func TestMigrateLegacyRetriesChunks(t *testing.T) {
+	root, storyDir, audioDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeLegacy(t, storyDir, "The_Fox.json", legacyStory("The Fox", "A fox.", "Once."))
+	migrations, problems := MigrateLegacy(root, storyDir, audioDir)
+	if len(problems) > 0 || len(migrations) != 1 {
+		t.Fatalf("MigrateLegacy() = %v, %v", migrations, problems)
+	}
+
+	// A chunk that could not be moved by the first run
+	writeLegacy(t, audioDir, "0_2_1_The_Fox.mp3", "audio")
+	migrations, problems = MigrateLegacy(root, storyDir, audioDir)
+	if len(problems) > 0 || len(migrations) != 1 {
+		t.Fatalf("MigrateLegacy() again = %v, %v", migrations, problems)
+	}
+	w := migrations[0].Workspace
+	if want := []string{filepath.Join(audioDir, "0_2_1_The_Fox.mp3")}; !reflect.DeepEqual(migrations[0].Files, want) {
+		t.Errorf("moved %q, want %q", migrations[0].Files, want)
+	}
+	if _, err := os.Stat(w.Path(AudioDir, "0_2_1_The_Fox.mp3")); err != nil {
+		t.Errorf("chunk was not moved into %s: %v", w.Dir, err)
+	}
+
+	// Nothing left to do
+	if migrations, problems := MigrateLegacy(root, storyDir, audioDir); len(migrations) != 0 || len(problems) != 0 {
+		t.Errorf("MigrateLegacy() with nothing to move = %v, %v", migrations, problems)
+	}
+}
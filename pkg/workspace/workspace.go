@@ -0,0 +1,268 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andrejsstepanovs/storygen/pkg/story"
+	"github.com/andrejsstepanovs/storygen/pkg/utils"
+)
+
+// Files and directories inside a story workspace.
+const (
+	StoryFile       = "story.json" // The current revision in the original language
+	StateFile       = "state.json"
+	RevisionsDir    = "revisions" // Every saved revision, numbered in the order they were written
+	TranslationsDir = "translations"
+	AudioDir        = "audio" // Chunk audio while the story is voiced
+	TranscriptsDir  = "transcripts"
+	ImagesDir       = "images"
+)
+
+// Stages a story goes through, the state records the latest.
+const (
+	StageWritten    = "written"
+	StageGroomed    = "groomed"
+	StageFinal      = "final"
+	StageTranslated = "translated"
+	StageVoiced     = "voiced"
+	StageImported   = "imported"
+)
+
+// State is what the workspace knows about its story without loading the JSONs.
+type State struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Stage        string            `json:"stage"`
+	Created      time.Time         `json:"created"`
+	Updated      time.Time         `json:"updated"`
+	Revisions    []string          `json:"revisions"`
+	Translations []string          `json:"translations,omitempty"` // Language names
+	Titles       map[string]string `json:"titles,omitempty"`       // Translated titles by language, file names only carry their slug
+	Narrations   map[string]string `json:"narrations,omitempty"`   // Audio files by story file, story.json or translations/<language>.json
+	Migrated     []string          `json:"migrated,omitempty"`     // Story files moved here from the flat layout
+}
+
+// Workspace is the directory holding everything about one story: its JSON revisions,
+// translations, chunk audio, transcripts and state. It is named by the story ID.
+type Workspace struct {
+	Dir   string
+	State State
+}
+
+// Open opens the workspace of the story ID under root, creating it when missing.
+func Open(root, id string) (*Workspace, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid story id %q", id)
+	}
+	w := &Workspace{Dir: filepath.Join(root, id)}
+	data, err := os.ReadFile(w.Path(StateFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &w.State); err != nil {
+			return nil, fmt.Errorf("%s: invalid state: %w", w.Path(StateFile), err)
+		}
+	case os.IsNotExist(err):
+		now := time.Now().UTC().Truncate(time.Second)
+		w.State = State{ID: id, Created: now, Updated: now, Revisions: make([]string, 0)}
+		if err := os.MkdirAll(w.Dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := w.Save(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	return w, nil
+}
+
+// For returns the workspace a story file is in.
+func For(file string) (*Workspace, bool) {
+	dir := filepath.Dir(file)
+	if base := filepath.Base(dir); base == RevisionsDir || base == TranslationsDir {
+		dir = filepath.Dir(dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StateFile)); err != nil {
+		return nil, false
+	}
+	w, err := Open(filepath.Dir(dir), filepath.Base(dir))
+	if err != nil {
+		return nil, false
+	}
+	return w, true
+}
+
+// List returns the workspaces under root, oldest first.
+func List(root string) ([]*Workspace, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	workspaces := make([]*Workspace, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, e.Name(), StateFile)); err != nil {
+			continue
+		}
+		w, err := Open(root, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+	sort.SliceStable(workspaces, func(i, j int) bool { return workspaces[i].State.Created.Before(workspaces[j].State.Created) })
+	return workspaces, nil
+}
+
+// Language returns the language name of a translation file, "" for the original story.
+// Files from the flat layout are recognized by their "<language>_" prefix.
+func Language(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if filepath.Base(filepath.Dir(file)) == TranslationsDir {
+		return name
+	}
+	for _, l := range utils.Languages() {
+		if strings.HasPrefix(name, l.Name+"_") {
+			return l.Name
+		}
+	}
+	return ""
+}
+
+func (w *Workspace) Path(elem ...string) string {
+	return filepath.Join(append([]string{w.Dir}, elem...)...)
+}
+
+// StoryFiles returns the story and its translations.
+func (w *Workspace) StoryFiles() []string {
+	files := make([]string, 0, 1+len(w.State.Translations))
+	if _, err := os.Stat(w.Path(StoryFile)); err == nil {
+		files = append(files, w.Path(StoryFile))
+	}
+	for _, language := range w.State.Translations {
+		files = append(files, w.TranslationFile(language))
+	}
+	return files
+}
+
+func (w *Workspace) TranslationFile(language string) string {
+	return w.Path(TranslationsDir, language+".json")
+}
+
+// Translations returns the translation files by language name.
+func (w *Workspace) Translations() map[string]string {
+	translations := make(map[string]string, len(w.State.Translations))
+	for _, language := range w.State.Translations {
+		translations[language] = w.TranslationFile(language)
+	}
+	return translations
+}
+
+// WriteRevision keeps a revision of the story without making it the current one.
+func (w *Workspace) WriteRevision(label string, s story.Story) (string, error) {
+	name := fmt.Sprintf("%02d_%s.json", len(w.State.Revisions)+1, utils.SanitizeFilename(label))
+	file := w.Path(RevisionsDir, name)
+	if err := write(file, s); err != nil {
+		return "", err
+	}
+	w.State.Revisions = append(w.State.Revisions, name)
+	return file, w.Save()
+}
+
+// SaveStory writes a revision and makes it the current story. It returns the story file.
+func (w *Workspace) SaveStory(stage string, s story.Story) (string, error) {
+	if _, err := w.WriteRevision(stage, s); err != nil {
+		return "", err
+	}
+	if err := write(w.Path(StoryFile), s); err != nil {
+		return "", err
+	}
+	w.State.Title, w.State.Stage = s.Title, stage
+	return w.Path(StoryFile), w.Save()
+}
+
+// SaveTranslation writes the story translated to the language and returns its file.
+func (w *Workspace) SaveTranslation(language string, s story.Story) (string, error) {
+	file := w.TranslationFile(language)
+	if err := write(file, s); err != nil {
+		return "", err
+	}
+	if !slices.Contains(w.State.Translations, language) {
+		w.State.Translations = append(w.State.Translations, language)
+		sort.Strings(w.State.Translations)
+	}
+	if w.State.Titles == nil {
+		w.State.Titles = make(map[string]string)
+	}
+	w.State.Titles[language] = s.Title
+	w.State.Stage = StageTranslated
+	return file, w.Save()
+}
+
+// SaveNarration writes a story that may be voiced, the original when language is "", and records its audio.
+func (w *Workspace) SaveNarration(language string, s story.Story) (string, error) {
+	var file string
+	var err error
+	if language == "" {
+		file, err = w.SaveStory(StageVoiced, s)
+	} else {
+		file, err = w.SaveTranslation(language, s)
+	}
+	if err != nil {
+		return "", err
+	}
+	if s.Narration != nil {
+		if w.State.Narrations == nil {
+			w.State.Narrations = make(map[string]string)
+		}
+		rel, _ := filepath.Rel(w.Dir, file)
+		w.State.Narrations[filepath.ToSlash(rel)] = s.Narration.File
+		w.State.Stage = StageVoiced
+	}
+	return file, w.Save()
+}
+
+// SaveTranscript writes what the narration chunks were transcribed to, next to the story file name.
+func (w *Workspace) SaveTranscript(language string, t story.Transcript) (string, error) {
+	name := strings.TrimSuffix(StoryFile, filepath.Ext(StoryFile))
+	if language != "" {
+		name = language
+	}
+	file := w.Path(TranscriptsDir, name+".json")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	return file, os.WriteFile(file, []byte(utils.ToJsonStr(t)), 0644)
+}
+
+// Save writes the state.
+func (w *Workspace) Save() error {
+	w.State.Updated = time.Now().UTC().Truncate(time.Second)
+	data, err := json.MarshalIndent(w.State, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.Path(StateFile), data, 0644)
+}
+
+func write(file string, s story.Story) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, []byte(s.ToJson()), 0644); err != nil {
+		return fmt.Errorf("failed to write to file: %v", err)
+	}
+	return nil
+}